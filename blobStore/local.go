@@ -0,0 +1,97 @@
+package blobStore
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+var ErrorInvalidKey = errors.New("Invalid blob key")
+
+// Local is a store.BlobStore keeping the blobs as files under Dir, a key being the relative path of its file.
+// The content type isn't stored : it's deduced from the extension of the key.
+type Local struct {
+	Dir string
+}
+
+func NewLocal(dir string) (*Local, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't create blob directory %s, %s", dir, err)
+	}
+	return &Local{Dir: dir}, nil
+}
+
+func (l *Local) Put(key, contentType string, data []byte) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return err
+	}
+	// The blob is written to a temporary file first so that a reader never gets a partial file.
+	tempFile, err := ioutil.TempFile(filepath.Dir(filePath), filepath.Base(filePath))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.Write(data)
+	tempFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), filePath)
+}
+
+func (l *Local) Get(key string) ([]byte, string, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, "", store.ErrorNotFoundBlob
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return data, contentType, nil
+}
+
+func (l *Local) Delete(key string) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// path refuses the keys that would point outside of Dir.
+func (l *Local) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "\\") || path.IsAbs(key) {
+		return "", ErrorInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrorInvalidKey
+		}
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
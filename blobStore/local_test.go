@@ -0,0 +1,62 @@
+package blobStore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/blobStore"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	local, err := blobStore.NewLocal(filepath.Join(dir, "photos"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Puts and gets a blob", func(t *testing.T) {
+		err := local.Put("playgrounds/1/abc.jpg", "image/jpeg", []byte("jpeg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, contentType, err := local.Get("playgrounds/1/abc.jpg")
+		if err != nil || string(data) != "jpeg" || contentType != "image/jpeg" {
+			t.Errorf("Got %q, %s, %v", data, contentType, err)
+		}
+		files, _ := ioutil.ReadDir(filepath.Join(dir, "photos", "playgrounds", "1"))
+		if len(files) != 1 {
+			t.Errorf("Temporary file should be removed, got %d files", len(files))
+		}
+	})
+	t.Run("Deletes a blob", func(t *testing.T) {
+		if err := local.Delete("playgrounds/1/abc.jpg"); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err := local.Get("playgrounds/1/abc.jpg")
+		if err != store.ErrorNotFoundBlob {
+			t.Errorf("Got %v, want %v", err, store.ErrorNotFoundBlob)
+		}
+		if err := local.Delete("playgrounds/1/abc.jpg"); err != nil {
+			t.Errorf("Deleting a missing blob should succeed, got %v", err)
+		}
+	})
+	t.Run("Refuses keys outside of the directory", func(t *testing.T) {
+		for _, key := range []string{"", "../secret.jpg", "playgrounds/../../secret.jpg", "/etc/passwd", "a//b.jpg", `a\..\b.jpg`} {
+			if err := local.Put(key, "image/jpeg", []byte("jpeg")); err != blobStore.ErrorInvalidKey {
+				t.Errorf("Put %q got %v", key, err)
+			}
+			if _, _, err := local.Get(key); err != blobStore.ErrorInvalidKey {
+				t.Errorf("Get %q got %v", key, err)
+			}
+		}
+	})
+}
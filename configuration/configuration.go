@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -20,6 +21,12 @@ type configVariables struct {
 	SESSION_SECRET           string
 	GOOGLE_MAPS_API_KEY      string
 	GOOGLE_GEOCODING_API_KEY string
+	GEOCODING_CACHE_FILE     string
+	GEOCODING_PROVIDERS      []string
+	GEOCODING_BAN_FILE       string
+	BANNED_WORDS_FILE        string
+	PHOTOS_DIR               string
+	MODERATORS               []string
 }
 
 type TLS struct {
@@ -59,9 +66,25 @@ func LoadEnvVariables() {
 		SESSION_SECRET:           os.Getenv("SESSION_SECRET"),
 		GOOGLE_MAPS_API_KEY:      os.Getenv("GOOGLE_MAPS_API_KEY"),
 		GOOGLE_GEOCODING_API_KEY: os.Getenv("GOOGLE_GEOCODING_API_KEY"),
+		GEOCODING_CACHE_FILE:     os.Getenv("GEOCODING_CACHE_FILE"),
+		GEOCODING_PROVIDERS:      getEnvAsList("GEOCODING_PROVIDERS"),
+		GEOCODING_BAN_FILE:       os.Getenv("GEOCODING_BAN_FILE"),
+		BANNED_WORDS_FILE:        os.Getenv("BANNED_WORDS_FILE"),
+		PHOTOS_DIR:               os.Getenv("PHOTOS_DIR"),
+		MODERATORS:               getEnvAsList("MODERATORS"),
 	}
 }
 
+func getEnvAsList(key string) []string {
+	values := []string{}
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getEnvAsBool(key string) bool {
 	valStr := os.Getenv(key)
 	if val, err := strconv.ParseBool(valStr); err == nil {
@@ -0,0 +1,361 @@
+package geolocationClient
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const ProviderBAN = "ban"
+
+// Size of a reverse geocoding grid cell in degrees, about 550m x 370m around Paris.
+const banCellSize = 0.005
+
+// Reverse geocoding doesn't answer further than this distance in metres.
+const banMaxReverseDistance = 500
+
+// Postings longer than this share of the addresses (« rue », « paris »...) are not used to find candidates.
+const banCommonTokenRatio = 0.1
+
+// Maximum number of words completing the last word of a suggestion query.
+const banMaxCompletions = 50
+
+var banRequiredColumns = []string{"numero", "nom_voie", "code_postal", "nom_commune", "lon", "lat"}
+
+// BAN geocodes addresses offline from a Base Adresse Nationale CSV extract, see https://adresse.data.gouv.fr/donnees-nationales
+// The whole extract is kept in memory : an inverted index over the words of the addresses answers forward queries
+// and a grid over the coordinates answers reverse queries.
+type BAN struct {
+	addresses []banAddress
+	postings  map[string][]int
+	words     []string
+	cells     map[gridKey][]int
+}
+
+type banAddress struct {
+	Number     string
+	Street     string
+	PostalCode string
+	City       string
+	Department string
+	Long       float64
+	Lat        float64
+	tokens     []string
+}
+
+type gridKey struct {
+	x, y int
+}
+
+type banCandidate struct {
+	index int
+	score float64
+}
+
+// NewBAN loads a BAN extract, gzipped if the file name ends with .gz.
+func NewBAN(path string) (*BAN, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't open BAN file %s, %s", path, err)
+	}
+	defer file.Close()
+
+	var input io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't decompress BAN file %s, %s", path, err)
+		}
+		defer gzipReader.Close()
+		input = gzipReader
+	}
+	return NewBANFromReader(input)
+}
+
+func NewBANFromReader(input io.Reader) (*BAN, error) {
+	reader := csv.NewReader(input)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read BAN header, %s", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range banRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("Couldn't read BAN file, missing column %q", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	ban := &BAN{
+		postings: make(map[string][]int),
+		cells:    make(map[gridKey][]int),
+	}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't read BAN line %d, %s", line, err)
+		}
+		long, errLong := strconv.ParseFloat(field(record, "lon"), 64)
+		lat, errLat := strconv.ParseFloat(field(record, "lat"), 64)
+		if errLong != nil || errLat != nil {
+			continue
+		}
+		address := banAddress{
+			Number:     strings.TrimSpace(field(record, "numero") + " " + field(record, "rep")),
+			Street:     field(record, "nom_voie"),
+			PostalCode: field(record, "code_postal"),
+			City:       field(record, "nom_commune"),
+			Long:       long,
+			Lat:        lat,
+		}
+		address.Department = departmentName(field(record, "code_insee"))
+		ban.add(address)
+	}
+
+	ban.words = make([]string, 0, len(ban.postings))
+	for word := range ban.postings {
+		ban.words = append(ban.words, word)
+	}
+	sort.Strings(ban.words)
+	return ban, nil
+}
+
+func (b *BAN) add(address banAddress) {
+	index := len(b.addresses)
+	address.tokens = tokenize(address.Number + " " + address.Street + " " + address.PostalCode + " " + address.City)
+	for _, token := range uniqueTokens(address.tokens) {
+		b.postings[token] = append(b.postings[token], index)
+	}
+	cell := banCellOf(address.Long, address.Lat)
+	b.cells[cell] = append(b.cells[cell], index)
+	b.addresses = append(b.addresses, address)
+}
+
+func (b *BAN) GetLongAndLat(ctx context.Context, address string) (float64, float64, error) {
+	location, err := b.Geocode(ctx, address)
+	if err != nil {
+		return 0, 0, err
+	}
+	return location.Long, location.Lat, nil
+}
+
+func (b *BAN) Geocode(ctx context.Context, address string) (store.Location, error) {
+	candidates := b.search(tokenize(address), false, 1)
+	if len(candidates) == 0 {
+		return store.Location{}, store.ErrorNotFoundLocation
+	}
+	return b.location(candidates[0]), nil
+}
+
+// ReverseGeocode returns the nearest address, its score decreases with the distance.
+func (b *BAN) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	center := banCellOf(long, lat)
+	nearest, nearestDistance := -1, math.Inf(1)
+	// A cell is narrower than banMaxReverseDistance along the longitude in France, hence the two columns on each side.
+	for x := center.x - 2; x <= center.x+2; x++ {
+		for y := center.y - 1; y <= center.y+1; y++ {
+			for _, index := range b.cells[gridKey{x, y}] {
+				address := b.addresses[index]
+				distance := store.Playground{Long: address.Long, Lat: address.Lat}.DistanceFrom(long, lat)
+				if distance < nearestDistance {
+					nearest, nearestDistance = index, distance
+				}
+			}
+		}
+	}
+	if nearest < 0 || nearestDistance > banMaxReverseDistance {
+		return store.Location{}, store.ErrorNotFoundLocation
+	}
+	return b.location(banCandidate{index: nearest, score: 1 - nearestDistance/banMaxReverseDistance}), nil
+}
+
+// SuggestAddresses completes the last word of the query.
+func (b *BAN) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	candidates := b.search(tokenize(query), true, limit)
+	locations := make([]store.Location, len(candidates))
+	for i, candidate := range candidates {
+		locations[i] = b.location(candidate)
+	}
+	return locations, nil
+}
+
+// search ranks the addresses by the Dice coefficient between their words and the query's.
+func (b *BAN) search(queryTokens []string, completeLastToken bool, limit int) []banCandidate {
+	if len(queryTokens) == 0 || len(b.addresses) == 0 {
+		return nil
+	}
+	lastTokenCompletions := []string{}
+	if completeLastToken {
+		lastTokenCompletions = b.completions(queryTokens[len(queryTokens)-1])
+	}
+
+	candidateIndexes := make(map[int]bool)
+	addPostings := func(tokens []string, commonTokensToo bool) {
+		for _, token := range tokens {
+			postings := b.postings[token]
+			if !commonTokensToo && float64(len(postings)) > banCommonTokenRatio*float64(len(b.addresses)) {
+				continue
+			}
+			for _, index := range postings {
+				candidateIndexes[index] = true
+			}
+		}
+	}
+	selectiveTokens := queryTokens
+	if completeLastToken {
+		selectiveTokens = queryTokens[:len(queryTokens)-1]
+		addPostings(lastTokenCompletions, false)
+	}
+	addPostings(selectiveTokens, false)
+	if len(candidateIndexes) == 0 {
+		addPostings(selectiveTokens, true)
+		addPostings(lastTokenCompletions, true)
+	}
+
+	candidates := make([]banCandidate, 0, len(candidateIndexes))
+	for index := range candidateIndexes {
+		score := diceCoefficient(queryTokens, b.addresses[index].tokens, completeLastToken)
+		candidates = append(candidates, banCandidate{index: index, score: score})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].index < candidates[j].index
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+func (b *BAN) completions(prefix string) []string {
+	completions := []string{}
+	for i := sort.SearchStrings(b.words, prefix); i < len(b.words) && strings.HasPrefix(b.words[i], prefix); i++ {
+		completions = append(completions, b.words[i])
+		if len(completions) == banMaxCompletions {
+			break
+		}
+	}
+	return completions
+}
+
+func (b *BAN) location(candidate banCandidate) store.Location {
+	address := b.addresses[candidate.index]
+	street := strings.TrimSpace(address.Number + " " + address.Street)
+	return store.Location{
+		Long:       address.Long,
+		Lat:        address.Lat,
+		Score:      math.Round(candidate.score*100) / 100,
+		Label:      street + " " + address.PostalCode + " " + address.City,
+		Address:    street,
+		PostalCode: address.PostalCode,
+		City:       address.City,
+		Department: address.Department,
+		Provider:   ProviderBAN,
+	}
+}
+
+func banCellOf(long, lat float64) gridKey {
+	return gridKey{
+		x: int(math.Floor(long / banCellSize)),
+		y: int(math.Floor(lat / banCellSize)),
+	}
+}
+
+// diceCoefficient is 1 when both lists have the same words and 0 when they have none in common.
+// With lastIsPrefix, the last query word matches any word it starts.
+func diceCoefficient(queryTokens, addressTokens []string, lastIsPrefix bool) float64 {
+	used := make([]bool, len(addressTokens))
+	matches := 0
+	for i, queryToken := range queryTokens {
+		isPrefix := lastIsPrefix && i == len(queryTokens)-1
+		for j, addressToken := range addressTokens {
+			if used[j] {
+				continue
+			}
+			if addressToken == queryToken || (isPrefix && strings.HasPrefix(addressToken, queryToken)) {
+				used[j] = true
+				matches++
+				break
+			}
+		}
+	}
+	return 2 * float64(matches) / float64(len(queryTokens)+len(addressTokens))
+}
+
+var stopWords = map[string]bool{
+	"de": true, "du": true, "des": true, "la": true, "le": true, "les": true, "l": true, "d": true, "et": true, "a": true, "au": true, "aux": true,
+}
+
+var streetAbbreviations = map[string]string{
+	"av":   "avenue",
+	"ave":  "avenue",
+	"bd":   "boulevard",
+	"bld":  "boulevard",
+	"boul": "boulevard",
+	"pl":   "place",
+	"r":    "rue",
+	"imp":  "impasse",
+	"che":  "chemin",
+	"all":  "allee",
+	"sq":   "square",
+	"st":   "saint",
+	"ste":  "sainte",
+}
+
+// tokenize splits an address into lower case words without accents, stop words nor abbreviations.
+func tokenize(text string) []string {
+	text = store.FoldAccents(strings.ToLower(text))
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if stopWords[word] {
+			continue
+		}
+		if fullWord, ok := streetAbbreviations[word]; ok {
+			word = fullWord
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	unique := tokens[:0:0]
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,167 @@
+package geolocationClient_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/geolocationClient"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const banExtract = `id;id_fantoir;numero;rep;nom_voie;code_postal;code_insee;nom_commune;code_insee_ancienne_commune;nom_ancienne_commune;x;y;lon;lat
+75119_3681_00040;75119_3681;40;;Avenue de Flandre;75019;75119;Paris 19e Arrondissement;;;;;2.372100;48.886500
+75119_3681_00042;75119_3681;42;;Avenue de Flandre;75019;75119;Paris 19e Arrondissement;;;;;2.372452;48.886835
+75119_3681_00042_bis;75119_3681;42;bis;Avenue de Flandre;75019;75119;Paris 19e Arrondissement;;;;;2.372500;48.886900
+75111_2149_00003;75111_2149;3;;Rue de l'Église;75015;75115;Paris 15e Arrondissement;;;;;2.288000;48.842000
+93066_0450_00012;93066_0450;12;;Boulevard Anatole France;93200;93066;Saint-Denis;;;;;2.358000;48.930000
+;;;;Ligne sans coordonnées;75019;75119;Paris;;;;;;
+`
+
+func newTestBAN(t *testing.T) *geolocationClient.BAN {
+	t.Helper()
+	ban, err := geolocationClient.NewBANFromReader(strings.NewReader(banExtract))
+	if err != nil {
+		t.Fatalf("Couldn't load BAN extract, %s", err)
+	}
+	return ban
+}
+
+func TestBAN(t *testing.T) {
+	ban := newTestBAN(t)
+	ctx := context.Background()
+
+	t.Run("Geocode finds the address", func(t *testing.T) {
+		got, err := ban.Geocode(ctx, "42 avenue de Flandre 75019 Paris 19e Arrondissement")
+		if err != nil {
+			t.Fatalf("Couldn't geocode, %s", err)
+		}
+
+		want := store.Location{
+			Long:       2.372452,
+			Lat:        48.886835,
+			Score:      1,
+			Label:      "42 Avenue de Flandre 75019 Paris 19e Arrondissement",
+			Address:    "42 Avenue de Flandre",
+			PostalCode: "75019",
+			City:       "Paris 19e Arrondissement",
+			Department: "Paris",
+			Provider:   geolocationClient.ProviderBAN,
+		}
+		if got != want {
+			t.Errorf("got : %v, want : %v", got, want)
+		}
+	})
+	t.Run("Geocode ignores case, accents and abbreviations", func(t *testing.T) {
+		cases := map[string]string{
+			"42 av. de flandre paris":           "42 Avenue de Flandre",
+			"42 bis avenue Flandre":             "42 bis Avenue de Flandre",
+			"3 RUE DE L'EGLISE 75015":           "3 Rue de l'Église",
+			"12 bd Anatole-France, Saint Denis": "12 Boulevard Anatole France",
+		}
+		for address, want := range cases {
+			got, err := ban.Geocode(ctx, address)
+			if err != nil {
+				t.Fatalf("Couldn't geocode %q, %s", address, err)
+			}
+			assertAddress(t, got.Address, want)
+			if got.Score < store.MinGeocodingScore {
+				t.Errorf("Score of %q should be high, got %f", address, got.Score)
+			}
+		}
+	})
+	t.Run("Geocode returns an error if nothing matches", func(t *testing.T) {
+		_, err := ban.Geocode(ctx, "Promenade des Anglais Nice")
+		if err != store.ErrorNotFoundLocation {
+			t.Errorf("got : %v, want : %v", err, store.ErrorNotFoundLocation)
+		}
+	})
+	t.Run("ReverseGeocode returns the nearest address", func(t *testing.T) {
+		got, err := ban.ReverseGeocode(ctx, 2.37246, 48.88684)
+		if err != nil {
+			t.Fatalf("Couldn't reverse geocode, %s", err)
+		}
+
+		assertAddress(t, got.Address, "42 Avenue de Flandre")
+		assertAddress(t, got.Department, "Paris")
+	})
+	t.Run("ReverseGeocode returns an error far from any address", func(t *testing.T) {
+		_, err := ban.ReverseGeocode(ctx, 2.33, 48.86)
+		if err != store.ErrorNotFoundLocation {
+			t.Errorf("got : %v, want : %v", err, store.ErrorNotFoundLocation)
+		}
+	})
+	t.Run("SuggestAddresses completes the last word", func(t *testing.T) {
+		got, err := ban.SuggestAddresses(ctx, "42 avenue de Fl", 5)
+		if err != nil {
+			t.Fatalf("Couldn't get suggestions, %s", err)
+		}
+
+		if len(got) != 3 {
+			t.Fatalf("got %d suggestions, want 3 : %v", len(got), got)
+		}
+		assertAddress(t, got[0].Address, "42 Avenue de Flandre")
+		for _, suggestion := range got {
+			if !strings.Contains(suggestion.Address, "Flandre") {
+				t.Errorf("Unexpected suggestion %q", suggestion.Address)
+			}
+		}
+	})
+	t.Run("Finds the department of any French commune", func(t *testing.T) {
+		extract := `id;numero;rep;nom_voie;code_postal;code_insee;nom_commune;lon;lat
+69381_0001_00001;1;;Place Bellecour;69002;69382;Lyon 2e Arrondissement;4.832000;45.757800
+2A004_0001_00001;1;;Cours Napoléon;20000;2A004;Ajaccio;8.738000;41.926000
+97411_0001_00001;1;;Rue de Paris;97400;97411;Saint-Denis;55.450000;-20.880000
+97801_0001_00001;1;;Rue de la République;97150;97801;Saint-Martin;-63.085000;18.067000
+`
+		ban, err := geolocationClient.NewBANFromReader(strings.NewReader(extract))
+		if err != nil {
+			t.Fatalf("Couldn't load BAN extract, %s", err)
+		}
+		cases := map[string]string{
+			"1 place Bellecour Lyon":              "Rhône",
+			"1 cours Napoléon Ajaccio":            "Corse-du-Sud",
+			"1 rue de Paris Saint-Denis":          "La Réunion",
+			"1 rue de la République Saint-Martin": "Saint-Martin",
+		}
+		for address, want := range cases {
+			got, err := ban.Geocode(ctx, address)
+			if err != nil {
+				t.Fatalf("Couldn't geocode %q, %s", address, err)
+			}
+			assertAddress(t, got.Department, want)
+		}
+	})
+	t.Run("Loads gzipped extracts", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "ban*.csv.gz")
+		if err != nil {
+			t.Fatalf("Couldn't create temp file, %s", err)
+		}
+		defer os.Remove(file.Name())
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		writer.Write([]byte(banExtract))
+		writer.Close()
+		file.Write(compressed.Bytes())
+		file.Close()
+
+		gzippedBAN, err := geolocationClient.NewBAN(file.Name())
+		if err != nil {
+			t.Fatalf("Couldn't load BAN extract, %s", err)
+		}
+		_, err = gzippedBAN.Geocode(ctx, "42 avenue de Flandre")
+		if err != nil {
+			t.Errorf("Couldn't geocode, %s", err)
+		}
+	})
+	t.Run("Rejects files without the expected columns", func(t *testing.T) {
+		_, err := geolocationClient.NewBANFromReader(strings.NewReader("id;numero;nom_voie\n1;42;Avenue de Flandre\n"))
+		if err == nil {
+			t.Errorf("Expected an error")
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package geolocationClient
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const (
+	DefaultCacheTTL         = 30 * 24 * time.Hour
+	DefaultCacheNegativeTTL = 24 * time.Hour
+	DefaultCacheSize        = 10000
+	DefaultCacheSaveEvery   = time.Minute
+)
+
+// Cache is a store.GeolocationClient keeping the answers of another client in memory.
+// Addresses that can't be found are cached too (for NegativeTTL) so that they don't hit the upstream API on every request.
+// When created with NewPersistentCache, the entries are reloaded from a file at startup and written to it by Save.
+// Address suggestions are not cached here : the server already caches them per query.
+type Cache struct {
+	Client      store.GeolocationClient
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	MaxSize     int
+
+	path    string
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	// dirty is set when entries were added since the last save.
+	dirty     bool
+	saveMutex sync.Mutex
+}
+
+type cacheEntry struct {
+	Key       string         `json:"key"`
+	Location  store.Location `json:"location"`
+	NotFound  bool           `json:"not_found"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+func NewCache(client store.GeolocationClient) *Cache {
+	return &Cache{
+		Client:      client,
+		TTL:         DefaultCacheTTL,
+		NegativeTTL: DefaultCacheNegativeTTL,
+		MaxSize:     DefaultCacheSize,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+	}
+}
+
+func NewPersistentCache(client store.GeolocationClient, path string) (*Cache, error) {
+	cache := NewCache(client)
+	cache.path = path
+	err := cache.load()
+	if err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+func (c *Cache) GetLongAndLat(ctx context.Context, address string) (float64, float64, error) {
+	location, err := c.Geocode(ctx, address)
+	if err != nil {
+		return 0, 0, err
+	}
+	return location.Long, location.Lat, nil
+}
+
+func (c *Cache) Geocode(ctx context.Context, address string) (store.Location, error) {
+	return c.get("search:"+normalizeAddress(address), func() (store.Location, error) {
+		return c.Client.Geocode(ctx, address)
+	})
+}
+
+func (c *Cache) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	// 5 decimals are about 1 metre, there is no point in distinguishing closer coordinates.
+	key := "reverse:" + strconv.FormatFloat(long, 'f', 5, 64) + "," + strconv.FormatFloat(lat, 'f', 5, 64)
+	return c.get(key, func() (store.Location, error) {
+		return c.Client.ReverseGeocode(ctx, long, lat)
+	})
+}
+
+func (c *Cache) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	return c.Client.SuggestAddresses(ctx, query, limit)
+}
+
+func (c *Cache) get(key string, fetch func() (store.Location, error)) (store.Location, error) {
+	c.mutex.Lock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		if time.Now().Before(entry.ExpiresAt) {
+			c.order.MoveToFront(element)
+			c.mutex.Unlock()
+			if entry.NotFound {
+				return store.Location{}, store.ErrorNotFoundLocation
+			}
+			return entry.Location, nil
+		}
+		c.remove(element)
+	}
+	c.mutex.Unlock()
+
+	location, err := fetch()
+	switch err {
+	case nil:
+		c.add(&cacheEntry{Key: key, Location: location, ExpiresAt: time.Now().Add(c.TTL)})
+	case store.ErrorNotFoundLocation:
+		c.add(&cacheEntry{Key: key, NotFound: true, ExpiresAt: time.Now().Add(c.NegativeTTL)})
+	}
+	return location, err
+}
+
+func (c *Cache) add(entry *cacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[entry.Key]; ok {
+		c.remove(element)
+	}
+	c.entries[entry.Key] = c.order.PushFront(entry)
+	for c.MaxSize > 0 && c.order.Len() > c.MaxSize {
+		c.remove(c.order.Back())
+	}
+	c.dirty = true
+}
+
+func (c *Cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).Key)
+}
+
+// SaveEvery saves the cache every interval until stop is called, stop saves it one last time.
+func (c *Cache) SaveEvery(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				err := c.Save()
+				if err != nil {
+					log.Printf("Couldn't save geocoding cache, %s", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+		err := c.Save()
+		if err != nil {
+			log.Printf("Couldn't save geocoding cache, %s", err)
+		}
+	}
+}
+
+// Save writes the entries to the file of a persistent cache if they have changed since the last save.
+// The entries are written from the least to the most recently used, so that load restores the same order.
+func (c *Cache) Save() error {
+	c.saveMutex.Lock()
+	defer c.saveMutex.Unlock()
+
+	c.mutex.Lock()
+	if c.path == "" || !c.dirty {
+		c.mutex.Unlock()
+		return nil
+	}
+	entries := make([]*cacheEntry, 0, c.order.Len())
+	for element := c.order.Back(); element != nil; element = element.Prev() {
+		entries = append(entries, element.Value.(*cacheEntry))
+	}
+	c.dirty = false
+	c.mutex.Unlock()
+
+	err := c.write(entries)
+	if err != nil {
+		c.mutex.Lock()
+		c.dirty = true
+		c.mutex.Unlock()
+	}
+	return err
+}
+
+func (c *Cache) write(entries []*cacheEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tempFile, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.Write(data)
+	tempFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), c.path)
+}
+
+func (c *Cache) load() error {
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Couldn't read geocoding cache %s, %s", c.path, err)
+	}
+	var entries []*cacheEntry
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return fmt.Errorf("Couldn't parse geocoding cache %s, %s", c.path, err)
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if now.Before(entry.ExpiresAt) {
+			c.entries[entry.Key] = c.order.PushFront(entry)
+		}
+	}
+	for c.MaxSize > 0 && c.order.Len() > c.MaxSize {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func normalizeAddress(address string) string {
+	address = strings.ToLower(address)
+	address = strings.NewReplacer(",", " ", ";", " ", ".", " ", "-", " ", "'", " ").Replace(address)
+	return strings.Join(strings.Fields(address), " ")
+}
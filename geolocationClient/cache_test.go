@@ -0,0 +1,214 @@
+package geolocationClient_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/geolocationClient"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+type countingClient struct {
+	calls map[string]int
+}
+
+func newCountingClient() *countingClient {
+	return &countingClient{calls: make(map[string]int)}
+}
+
+func (c *countingClient) GetLongAndLat(ctx context.Context, address string) (float64, float64, error) {
+	location, err := c.Geocode(ctx, address)
+	return location.Long, location.Lat, err
+}
+
+func (c *countingClient) Geocode(ctx context.Context, address string) (store.Location, error) {
+	c.calls[address]++
+	switch address {
+	case "nowhere":
+		return store.Location{}, store.ErrorNotFoundLocation
+	case "down":
+		return store.Location{}, errors.New("Upstream is down")
+	}
+	return store.Location{Long: 2.37, Lat: 48.89, Label: address}, nil
+}
+
+func (c *countingClient) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	c.calls["reverse"]++
+	return store.Location{Long: long, Lat: lat, Label: "42 Avenue de Flandre 75019 Paris"}, nil
+}
+
+func (c *countingClient) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	c.calls["suggestions"]++
+	return []store.Location{}, nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Calls the client once for addresses that only differ by case, spaces and punctuation", func(t *testing.T) {
+		client := newCountingClient()
+		cache := geolocationClient.NewCache(client)
+
+		for _, address := range []string{"42 avenue de Flandre, Paris", "42 AVENUE  de flandre paris", " 42 avenue de Flandre Paris."} {
+			long, lat, err := cache.GetLongAndLat(ctx, address)
+			if err != nil {
+				t.Fatalf("There shouldn't be an error, %s", err)
+			}
+			if long != 2.37 || lat != 48.89 {
+				t.Errorf("Wrong coordinates, got %f %f", long, lat)
+			}
+		}
+
+		if client.calls["42 avenue de Flandre, Paris"] != 1 || len(client.calls) != 1 {
+			t.Errorf("Client should be called once, got %v", client.calls)
+		}
+	})
+	t.Run("Caches reverse geocoding", func(t *testing.T) {
+		client := newCountingClient()
+		cache := geolocationClient.NewCache(client)
+
+		cache.ReverseGeocode(ctx, 2.372452, 48.886835)
+		cache.ReverseGeocode(ctx, 2.372452, 48.886835)
+
+		if client.calls["reverse"] != 1 {
+			t.Errorf("Client should be called once, got %v", client.calls)
+		}
+	})
+	t.Run("Caches addresses that don't exist but not upstream errors", func(t *testing.T) {
+		client := newCountingClient()
+		cache := geolocationClient.NewCache(client)
+
+		for i := 0; i < 2; i++ {
+			_, err := cache.Geocode(ctx, "nowhere")
+			if err != store.ErrorNotFoundLocation {
+				t.Errorf("got : %v, want : %v", err, store.ErrorNotFoundLocation)
+			}
+			_, err = cache.Geocode(ctx, "down")
+			if err == nil {
+				t.Errorf("There should be an error")
+			}
+		}
+
+		if client.calls["nowhere"] != 1 {
+			t.Errorf("Not found address should be cached, got %d calls", client.calls["nowhere"])
+		}
+		if client.calls["down"] != 2 {
+			t.Errorf("Upstream errors shouldn't be cached, got %d calls", client.calls["down"])
+		}
+	})
+	t.Run("Calls the client again once the entry expired", func(t *testing.T) {
+		client := newCountingClient()
+		cache := geolocationClient.NewCache(client)
+		cache.TTL = -time.Second
+
+		cache.Geocode(ctx, "Paris")
+		cache.Geocode(ctx, "Paris")
+
+		if client.calls["Paris"] != 2 {
+			t.Errorf("Expired entry should be fetched again, got %d calls", client.calls["Paris"])
+		}
+	})
+	t.Run("Evicts the least recently used entry when full", func(t *testing.T) {
+		client := newCountingClient()
+		cache := geolocationClient.NewCache(client)
+		cache.MaxSize = 2
+
+		cache.Geocode(ctx, "a")
+		cache.Geocode(ctx, "b")
+		cache.Geocode(ctx, "a")
+		cache.Geocode(ctx, "c")
+		cache.Geocode(ctx, "a")
+		cache.Geocode(ctx, "b")
+
+		if client.calls["a"] != 1 || client.calls["b"] != 2 {
+			t.Errorf("b should have been evicted, got %v", client.calls)
+		}
+	})
+	t.Run("Persists entries on disk", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "geocodingCache")
+		if err != nil {
+			t.Fatalf("Couldn't create temp dir, %s", err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "cache.json")
+
+		client := newCountingClient()
+		cache, err := geolocationClient.NewPersistentCache(client, path)
+		if err != nil {
+			t.Fatalf("Couldn't create cache, %s", err)
+		}
+		cache.Geocode(ctx, "Paris")
+		cache.Geocode(ctx, "nowhere")
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Cache shouldn't be written before Save, %v", err)
+		}
+		err = cache.Save()
+		if err != nil {
+			t.Fatalf("Couldn't save cache, %s", err)
+		}
+
+		cache, err = geolocationClient.NewPersistentCache(client, path)
+		if err != nil {
+			t.Fatalf("Couldn't reload cache, %s", err)
+		}
+		location, err := cache.Geocode(ctx, "Paris")
+		if err != nil || location.Label != "Paris" {
+			t.Errorf("Reloaded entry is wrong, got %v, %v", location, err)
+		}
+		cache.Geocode(ctx, "nowhere")
+
+		if client.calls["Paris"] != 1 || client.calls["nowhere"] != 1 {
+			t.Errorf("Entries should be reloaded from disk, got %v", client.calls)
+		}
+	})
+	t.Run("Saves periodically and when stopped", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "geocodingCache")
+		if err != nil {
+			t.Fatalf("Couldn't create temp dir, %s", err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "cache.json")
+
+		client := newCountingClient()
+		cache, err := geolocationClient.NewPersistentCache(client, path)
+		if err != nil {
+			t.Fatalf("Couldn't create cache, %s", err)
+		}
+		stop := cache.SaveEvery(10 * time.Millisecond)
+		cache.Geocode(ctx, "Paris")
+		time.Sleep(50 * time.Millisecond)
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Cache should have been saved, %s", err)
+		}
+		cache.Geocode(ctx, "Lyon")
+		stop()
+
+		cache, err = geolocationClient.NewPersistentCache(client, path)
+		if err != nil {
+			t.Fatalf("Couldn't reload cache, %s", err)
+		}
+		cache.Geocode(ctx, "Paris")
+		cache.Geocode(ctx, "Lyon")
+		if client.calls["Paris"] != 1 || client.calls["Lyon"] != 1 {
+			t.Errorf("Entries should be reloaded from disk, got %v", client.calls)
+		}
+	})
+	t.Run("Returns an error if the cache file is corrupted", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "geocodingCache")
+		if err != nil {
+			t.Fatalf("Couldn't create temp file, %s", err)
+		}
+		defer os.Remove(file.Name())
+		file.Write([]byte("This is a test"))
+		file.Close()
+
+		_, err = geolocationClient.NewPersistentCache(newCountingClient(), file.Name())
+		if err == nil {
+			t.Errorf("There should be an error")
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package geolocationClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+// ErrorSuggestionsNotSupported is returned by the providers which can't be used to autocomplete addresses, the chain skips them.
+var ErrorSuggestionsNotSupported = errors.New("Provider doesn't suggest addresses")
+
+// Chain asks its clients one after the other, in order of priority, until one of them finds the location.
+// An answer scoring below MinScore is kept as a fallback while the next clients are tried, the best scored answer is returned.
+type Chain struct {
+	Clients  []store.GeolocationClient
+	MinScore float64
+}
+
+func NewChain(clients ...store.GeolocationClient) *Chain {
+	return &Chain{Clients: clients, MinScore: store.MinGeocodingScore}
+}
+
+type ProviderOptions struct {
+	GoogleAPIKey string
+	BANFile      string
+}
+
+// NewProvider returns the client registered under name, see the Provider constants.
+func NewProvider(name string, options ProviderOptions) (store.GeolocationClient, error) {
+	switch strings.TrimSpace(name) {
+	case ProviderAPIGouvFR:
+		return NewAPIGouvFR(), nil
+	case ProviderGoogle:
+		if options.GoogleAPIKey == "" {
+			return nil, fmt.Errorf("Couldn't create %s provider, GOOGLE_GEOCODING_API_KEY is empty", ProviderGoogle)
+		}
+		return NewGoogle(options.GoogleAPIKey), nil
+	case ProviderNominatim:
+		return NewNominatim(), nil
+	case ProviderBAN:
+		if options.BANFile == "" {
+			return nil, fmt.Errorf("Couldn't create %s provider, GEOCODING_BAN_FILE is empty", ProviderBAN)
+		}
+		return NewBAN(options.BANFile)
+	}
+	return nil, fmt.Errorf("Couldn't create provider, unknown provider %q", name)
+}
+
+func (c *Chain) GetLongAndLat(ctx context.Context, address string) (float64, float64, error) {
+	location, err := c.Geocode(ctx, address)
+	if err != nil {
+		return 0, 0, err
+	}
+	return location.Long, location.Lat, nil
+}
+
+func (c *Chain) Geocode(ctx context.Context, address string) (store.Location, error) {
+	return c.first(ctx, func(client store.GeolocationClient) (store.Location, error) {
+		return client.Geocode(ctx, address)
+	})
+}
+
+func (c *Chain) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	return c.first(ctx, func(client store.GeolocationClient) (store.Location, error) {
+		return client.ReverseGeocode(ctx, long, lat)
+	})
+}
+
+// SuggestAddresses returns the suggestions of the first client having any.
+func (c *Chain) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	var lastErr error
+	for _, client := range c.Clients {
+		suggestions, err := client.SuggestAddresses(ctx, query, limit)
+		if err == ErrorSuggestionsNotSupported {
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if len(suggestions) > 0 {
+			return suggestions, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return []store.Location{}, nil
+}
+
+func (c *Chain) first(ctx context.Context, lookup func(store.GeolocationClient) (store.Location, error)) (store.Location, error) {
+	var best store.Location
+	found := false
+	lastErr := store.ErrorNotFoundLocation
+	for _, client := range c.Clients {
+		location, err := lookup(client)
+		if err != nil {
+			if err != store.ErrorNotFoundLocation {
+				log.Printf("Geocoding provider %T failed, %s", client, err)
+				lastErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if !found || location.Score > best.Score {
+			best = location
+			found = true
+		}
+		if location.Score >= c.MinScore {
+			break
+		}
+	}
+	if found {
+		return best, nil
+	}
+	return store.Location{}, lastErr
+}
@@ -0,0 +1,143 @@
+package geolocationClient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/geolocationClient"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+type stubProvider struct {
+	location store.Location
+	err      error
+	calls    int
+}
+
+func (s *stubProvider) GetLongAndLat(ctx context.Context, address string) (float64, float64, error) {
+	return s.location.Long, s.location.Lat, s.err
+}
+
+func (s *stubProvider) Geocode(ctx context.Context, address string) (store.Location, error) {
+	s.calls++
+	return s.location, s.err
+}
+
+func (s *stubProvider) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	s.calls++
+	return s.location, s.err
+}
+
+func (s *stubProvider) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []store.Location{s.location}, nil
+}
+
+func TestChain(t *testing.T) {
+	t.Run("Stops at the first provider finding the location", func(t *testing.T) {
+		first := &stubProvider{location: store.Location{Score: 0.9, Provider: "first"}}
+		second := &stubProvider{location: store.Location{Score: 1, Provider: "second"}}
+		chain := geolocationClient.NewChain(first, second)
+
+		got, err := chain.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != nil {
+			t.Fatalf("Couldn't geocode, %s", err)
+		}
+
+		assertAddress(t, got.Provider, "first")
+		if second.calls != 0 {
+			t.Errorf("Second provider shouldn't be called")
+		}
+	})
+	t.Run("Falls back when a provider fails or doesn't find the location", func(t *testing.T) {
+		failing := &stubProvider{err: errors.New("timeout")}
+		notFound := &stubProvider{err: store.ErrorNotFoundLocation}
+		last := &stubProvider{location: store.Location{Score: 0.9, Provider: "last"}}
+		chain := geolocationClient.NewChain(failing, notFound, last)
+
+		got, err := chain.ReverseGeocode(context.Background(), 2.37, 48.88)
+		if err != nil {
+			t.Fatalf("Couldn't geocode, %s", err)
+		}
+
+		assertAddress(t, got.Provider, "last")
+	})
+	t.Run("Keeps the best answer when every provider has a low score", func(t *testing.T) {
+		first := &stubProvider{location: store.Location{Score: 0.5, Provider: "first"}}
+		second := &stubProvider{location: store.Location{Score: 0.3, Provider: "second"}}
+		chain := geolocationClient.NewChain(first, second)
+
+		got, err := chain.Geocode(context.Background(), "Flandre")
+		if err != nil {
+			t.Fatalf("Couldn't geocode, %s", err)
+		}
+
+		assertAddress(t, got.Provider, "first")
+		if second.calls != 1 {
+			t.Errorf("Second provider should be called")
+		}
+	})
+	t.Run("Returns not found only if no provider failed", func(t *testing.T) {
+		chain := geolocationClient.NewChain(&stubProvider{err: store.ErrorNotFoundLocation}, &stubProvider{err: store.ErrorNotFoundLocation})
+		_, err := chain.Geocode(context.Background(), "nowhere")
+		if err != store.ErrorNotFoundLocation {
+			t.Errorf("got : %v, want : %v", err, store.ErrorNotFoundLocation)
+		}
+
+		upstreamErr := errors.New("timeout")
+		chain = geolocationClient.NewChain(&stubProvider{err: upstreamErr}, &stubProvider{err: store.ErrorNotFoundLocation})
+		_, err = chain.Geocode(context.Background(), "nowhere")
+		if err != upstreamErr {
+			t.Errorf("got : %v, want : %v", err, upstreamErr)
+		}
+	})
+	t.Run("SuggestAddresses falls back on errors", func(t *testing.T) {
+		chain := geolocationClient.NewChain(&stubProvider{err: errors.New("timeout")}, &stubProvider{location: store.Location{Provider: "second"}})
+
+		got, err := chain.SuggestAddresses(context.Background(), "42 avenue", 5)
+		if err != nil {
+			t.Fatalf("Couldn't get suggestions, %s", err)
+		}
+		if len(got) != 1 || got[0].Provider != "second" {
+			t.Errorf("got : %v", got)
+		}
+	})
+	t.Run("SuggestAddresses skips the providers which don't suggest addresses", func(t *testing.T) {
+		nominatim := geolocationClient.NewNominatim()
+		nominatim.BaseURL = "http://127.0.0.1:0"
+		chain := geolocationClient.NewChain(nominatim, &stubProvider{location: store.Location{Provider: "second"}})
+
+		got, err := chain.SuggestAddresses(context.Background(), "42 avenue", 5)
+		if err != nil {
+			t.Fatalf("Couldn't get suggestions, %s", err)
+		}
+		if len(got) != 1 || got[0].Provider != "second" {
+			t.Errorf("got : %v", got)
+		}
+
+		got, err = geolocationClient.NewChain(nominatim).SuggestAddresses(context.Background(), "42 avenue", 5)
+		if err != nil || len(got) != 0 {
+			t.Errorf("got : %v, %v", got, err)
+		}
+	})
+	t.Run("Providers are created by name", func(t *testing.T) {
+		for _, name := range []string{geolocationClient.ProviderAPIGouvFR, geolocationClient.ProviderNominatim} {
+			if _, err := geolocationClient.NewProvider(name, geolocationClient.ProviderOptions{}); err != nil {
+				t.Errorf("Couldn't create %s, %s", name, err)
+			}
+		}
+		if _, err := geolocationClient.NewProvider(geolocationClient.ProviderGoogle, geolocationClient.ProviderOptions{}); err == nil {
+			t.Errorf("Google provider needs an API key")
+		}
+		if _, err := geolocationClient.NewProvider(geolocationClient.ProviderBAN, geolocationClient.ProviderOptions{}); err == nil {
+			t.Errorf("BAN provider needs a file")
+		}
+		if _, err := geolocationClient.NewProvider("unknown", geolocationClient.ProviderOptions{}); err == nil {
+			t.Errorf("Unknown provider shouldn't be created")
+		}
+	})
+}
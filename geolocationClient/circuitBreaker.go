@@ -0,0 +1,62 @@
+package geolocationClient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("Geocoding API is unavailable, circuit breaker is open")
+
+// CircuitBreaker stops calling an upstream API after MaxFailures consecutive failures.
+// Once OpenDuration has elapsed, a single trial request is let through : a success closes the circuit, a failure opens it again.
+type CircuitBreaker struct {
+	MaxFailures  int
+	OpenDuration time.Duration
+
+	mutex       sync.Mutex
+	failures    int
+	openedAt    time.Time
+	trialIsSent bool
+}
+
+func NewCircuitBreaker(maxFailures int, openDuration time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{MaxFailures: maxFailures, OpenDuration: openDuration}
+}
+
+func (c *CircuitBreaker) Allow() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.failures < c.MaxFailures {
+		return nil
+	}
+	if time.Since(c.openedAt) < c.OpenDuration || c.trialIsSent {
+		return ErrCircuitOpen
+	}
+	c.trialIsSent = true
+	return nil
+}
+
+func (c *CircuitBreaker) Success() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.failures = 0
+	c.trialIsSent = false
+}
+
+// Release lets another trial request through without changing the state of the circuit.
+func (c *CircuitBreaker) Release() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.trialIsSent = false
+}
+
+func (c *CircuitBreaker) Failure() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.failures++
+	c.trialIsSent = false
+	if c.failures >= c.MaxFailures {
+		c.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,157 @@
+package geolocationClient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const ProviderGoogle = "google"
+
+// Google geocodes addresses with https://developers.google.com/maps/documentation/geocoding
+type Google struct {
+	BaseURL string
+	APIKey  string
+	Requester
+}
+
+func NewGoogle(APIKey string) *Google {
+	return &Google{APIKey: APIKey, Requester: NewRequester()}
+}
+
+func (g *Google) baseURL() string {
+	if g.BaseURL == "" {
+		return "https://maps.googleapis.com/maps/api/geocode"
+	}
+	return strings.TrimSuffix(g.BaseURL, "/")
+}
+
+// Google doesn't give a score, the precision of the location is used instead.
+var googleLocationTypeScores = map[string]float64{
+	"ROOFTOP":            1,
+	"RANGE_INTERPOLATED": 0.8,
+	"GEOMETRIC_CENTER":   0.6,
+	"APPROXIMATE":        0.4,
+}
+
+type googleResponse struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message"`
+	Results      []struct {
+		FormattedAddress  string `json:"formatted_address"`
+		AddressComponents []struct {
+			LongName string   `json:"long_name"`
+			Types    []string `json:"types"`
+		} `json:"address_components"`
+		Geometry struct {
+			Location struct {
+				Lat float64 `json:"lat"`
+				Lng float64 `json:"lng"`
+			} `json:"location"`
+			LocationType string `json:"location_type"`
+		} `json:"geometry"`
+	} `json:"results"`
+}
+
+func (g *Google) GetLongAndLat(ctx context.Context, address string) (float64, float64, error) {
+	location, err := g.Geocode(ctx, address)
+	if err != nil {
+		return 0, 0, err
+	}
+	return location.Long, location.Lat, nil
+}
+
+func (g *Google) Geocode(ctx context.Context, address string) (store.Location, error) {
+	query := url.Values{}
+	query.Set("address", strings.Join(strings.Fields(address), " "))
+
+	return g.getFirstLocation(ctx, query)
+}
+
+func (g *Google) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	query := url.Values{}
+	query.Set("latlng", strconv.FormatFloat(lat, 'f', -1, 64)+","+strconv.FormatFloat(long, 'f', -1, 64))
+	query.Set("result_type", "street_address")
+
+	return g.getFirstLocation(ctx, query)
+}
+
+// SuggestAddresses returns the geocoding candidates, Google's autocompletion is part of another API.
+func (g *Google) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	parameters := url.Values{}
+	parameters.Set("address", strings.Join(strings.Fields(query), " "))
+
+	locations, err := g.getLocations(ctx, parameters)
+	if err == store.ErrorNotFoundLocation {
+		return []store.Location{}, nil
+	}
+	if len(locations) > limit {
+		locations = locations[:limit]
+	}
+	return locations, err
+}
+
+func (g *Google) getFirstLocation(ctx context.Context, query url.Values) (store.Location, error) {
+	locations, err := g.getLocations(ctx, query)
+	if err != nil {
+		return store.Location{}, err
+	}
+	return locations[0], nil
+}
+
+func (g *Google) getLocations(ctx context.Context, query url.Values) ([]store.Location, error) {
+	query.Set("key", g.APIKey)
+	query.Set("region", "fr")
+	query.Set("language", "fr")
+
+	var response googleResponse
+	err := g.getJSON(ctx, g.baseURL()+"/json?"+query.Encode(), &response)
+	if err != nil {
+		return nil, err
+	}
+	switch response.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return nil, store.ErrorNotFoundLocation
+	default:
+		return nil, fmt.Errorf("Couldn't get info, Google answered %s %s", response.Status, response.ErrorMessage)
+	}
+	if len(response.Results) == 0 {
+		return nil, store.ErrorNotFoundLocation
+	}
+
+	locations := make([]store.Location, len(response.Results))
+	for i, result := range response.Results {
+		location := store.Location{
+			Long:     result.Geometry.Location.Lng,
+			Lat:      result.Geometry.Location.Lat,
+			Score:    googleLocationTypeScores[result.Geometry.LocationType],
+			Label:    result.FormattedAddress,
+			Provider: ProviderGoogle,
+		}
+		var streetNumber, route string
+		for _, component := range result.AddressComponents {
+			for _, componentType := range component.Types {
+				switch componentType {
+				case "street_number":
+					streetNumber = component.LongName
+				case "route":
+					route = component.LongName
+				case "postal_code":
+					location.PostalCode = component.LongName
+				case "locality":
+					location.City = component.LongName
+				case "administrative_area_level_2":
+					location.Department = component.LongName
+				}
+			}
+		}
+		location.Address = strings.TrimSpace(streetNumber + " " + route)
+		locations[i] = location
+	}
+	return locations, nil
+}
@@ -0,0 +1,88 @@
+package geolocationClient_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/geolocationClient"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const googleResult = `{"status":"OK","results":[{"formatted_address":"42 Avenue de Flandre, 75019 Paris, France",
+"address_components":[{"long_name":"42","types":["street_number"]},{"long_name":"Avenue de Flandre","types":["route"]},
+{"long_name":"Paris","types":["locality","political"]},{"long_name":"Département de Paris","types":["administrative_area_level_2","political"]},
+{"long_name":"75019","types":["postal_code"]}],
+"geometry":{"location":{"lat":48.886835,"lng":2.372452},"location_type":"ROOFTOP"}}]}`
+
+func newGoogleServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/json" || query.Get("key") != "secret" {
+			fmt.Fprint(w, `{"status":"REQUEST_DENIED","error_message":"The provided API key is invalid."}`)
+			return
+		}
+		if query.Get("address") == "42 avenue de Flandre Paris" || query.Get("latlng") == "48.886835,2.372452" {
+			fmt.Fprint(w, googleResult)
+			return
+		}
+		fmt.Fprint(w, `{"status":"ZERO_RESULTS","results":[]}`)
+	}))
+}
+
+func TestGoogle(t *testing.T) {
+	svr := newGoogleServer()
+	defer svr.Close()
+
+	t.Run("Geocode returns the location with its precision as score", func(t *testing.T) {
+		client := geolocationClient.Google{BaseURL: svr.URL, APIKey: "secret"}
+
+		got, err := client.Geocode(context.Background(), "42 avenue de Flandre   Paris")
+		if err != nil {
+			t.Fatalf("Couldn't get geolocation info, %s", err)
+		}
+
+		want := store.Location{
+			Long:       2.372452,
+			Lat:        48.886835,
+			Score:      1,
+			Label:      "42 Avenue de Flandre, 75019 Paris, France",
+			Address:    "42 Avenue de Flandre",
+			PostalCode: "75019",
+			City:       "Paris",
+			Department: "Département de Paris",
+			Provider:   geolocationClient.ProviderGoogle,
+		}
+		if got != want {
+			t.Errorf("got : %v, want : %v", got, want)
+		}
+	})
+	t.Run("ReverseGeocode sends the coordinates as lat,lng", func(t *testing.T) {
+		client := geolocationClient.Google{BaseURL: svr.URL, APIKey: "secret"}
+
+		got, err := client.ReverseGeocode(context.Background(), 2.372452, 48.886835)
+		if err != nil {
+			t.Fatalf("Couldn't get geolocation info, %s", err)
+		}
+
+		assertAddress(t, got.Address, "42 Avenue de Flandre")
+	})
+	t.Run("Returns an error if there is no result", func(t *testing.T) {
+		client := geolocationClient.Google{BaseURL: svr.URL, APIKey: "secret"}
+
+		_, err := client.Geocode(context.Background(), "nowhere")
+		if err != store.ErrorNotFoundLocation {
+			t.Errorf("got : %v, want : %v", err, store.ErrorNotFoundLocation)
+		}
+	})
+	t.Run("Returns an error if the key is refused", func(t *testing.T) {
+		client := geolocationClient.Google{BaseURL: svr.URL, APIKey: "wrong"}
+
+		_, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err == nil || err == store.ErrorNotFoundLocation {
+			t.Errorf("Expected an API error, got %v", err)
+		}
+	})
+}
@@ -1,58 +1,142 @@
 package geolocationClient
 
 import (
-	"encoding/json"
-	"fmt"
-	"net/http"
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
 )
 
-func (a *APIGouvFR) defaultify() {
-	if a.ApiBase == "" {
-		a.ApiBase = "https://api-adresse.data.gouv.fr/search/?q="
+const ProviderAPIGouvFR = "api-adresse"
+
+// APIGouvFR geocodes French addresses with https://adresse.data.gouv.fr/api-doc/adresse
+type APIGouvFR struct {
+	BaseURL string
+	Requester
+}
+
+func NewAPIGouvFR() *APIGouvFR {
+	return &APIGouvFR{Requester: NewRequester()}
+}
+
+// baseURL doesn't write the default in BaseURL, the client is shared by concurrent requests.
+func (a *APIGouvFR) baseURL() string {
+	if a.BaseURL == "" {
+		return "https://api-adresse.data.gouv.fr"
 	}
-	if a.ApiSuffix == "" {
-		a.ApiSuffix = "&limit=1"
+	return strings.TrimSuffix(a.BaseURL, "/")
+}
+
+func (a *APIGouvFR) GetLongAndLat(ctx context.Context, address string) (float64, float64, error) {
+	location, err := a.Geocode(ctx, address)
+	if err != nil {
+		return 0, 0, err
 	}
+	return location.Long, location.Lat, nil
 }
 
-func (a APIGouvFR) GetLongAndLat(address string) (float64, float64, error) {
-	a.defaultify()
+func (a *APIGouvFR) Geocode(ctx context.Context, address string) (store.Location, error) {
+	query := url.Values{}
+	query.Set("q", strings.Join(strings.Fields(address), " "))
+	query.Set("limit", "1")
 
-	formattedAddress := strings.Join(strings.Fields(address), "+")
+	return a.getFirstLocation(ctx, "/search/", query)
+}
 
-	var info GeolocationInfo
+func (a *APIGouvFR) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	query := url.Values{}
+	query.Set("lon", strconv.FormatFloat(long, 'f', -1, 64))
+	query.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+
+	return a.getFirstLocation(ctx, "/reverse/", query)
+}
+
+func (a *APIGouvFR) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	parameters := url.Values{}
+	parameters.Set("q", strings.Join(strings.Fields(query), " "))
+	parameters.Set("limit", strconv.Itoa(limit))
+	parameters.Set("autocomplete", "1")
 
-	resp, err := http.Get(fmt.Sprintf("%s%s%s", a.ApiBase, formattedAddress, a.ApiSuffix))
+	locations, err := a.getLocations(ctx, "/search/", parameters)
+	if err == store.ErrorNotFoundLocation {
+		return []store.Location{}, nil
+	}
+	return locations, err
+}
+
+func (a *APIGouvFR) getFirstLocation(ctx context.Context, path string, query url.Values) (store.Location, error) {
+	locations, err := a.getLocations(ctx, path, query)
 	if err != nil {
-		return 0, 0, fmt.Errorf("Couldn't get info, %s", err)
+		return store.Location{}, err
 	}
+	return locations[0], nil
+}
 
-	err = json.NewDecoder(resp.Body).Decode(&info)
+func (a *APIGouvFR) getLocations(ctx context.Context, path string, query url.Values) ([]store.Location, error) {
+	var info GeolocationInfo
+	err := a.getJSON(ctx, a.baseURL()+path+"?"+query.Encode(), &info)
 	if err != nil {
-		return 0, 0, fmt.Errorf("Couldn't parse response, %s", err)
+		return nil, err
 	}
 	if len(info.Features) == 0 {
-		return 0, 0, fmt.Errorf("Empty answer from the API, %s", err)
+		return nil, store.ErrorNotFoundLocation
 	}
 
-	long := info.Features[0].Geometry.Coordinates[0]
-	lat := info.Features[0].Geometry.Coordinates[1]
+	locations := make([]store.Location, len(info.Features))
+	for i, feature := range info.Features {
+		locations[i] = feature.toLocation()
+		locations[i].Provider = ProviderAPIGouvFR
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].Score > locations[j].Score
+	})
+	return locations, nil
+}
 
-	return long, lat, nil
+type GeolocationInfo struct {
+	Features []Feature `json:"features"`
 }
 
-type APIGouvFR struct {
-	ApiBase   string
-	ApiSuffix string
+type Feature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Label    string  `json:"label"`
+		Score    float64 `json:"score"`
+		Name     string  `json:"name"`
+		Postcode string  `json:"postcode"`
+		City     string  `json:"city"`
+		Context  string  `json:"context"`
+	} `json:"properties"`
 }
 
-type GeolocationInfo struct {
-	Features []struct {
-		Type     string `json:"type"`
-		Geometry struct {
-			Type        string    `json:"type"`
-			Coordinates []float64 `json:"coordinates"`
-		} `json:"geometry"`
-	} `json:"features"`
+func (f Feature) toLocation() store.Location {
+	location := store.Location{
+		Score:      f.Properties.Score,
+		Label:      f.Properties.Label,
+		Address:    f.Properties.Name,
+		PostalCode: f.Properties.Postcode,
+		City:       f.Properties.City,
+		Department: departmentFromContext(f.Properties.Context),
+	}
+	if len(f.Geometry.Coordinates) == 2 {
+		location.Long = f.Geometry.Coordinates[0]
+		location.Lat = f.Geometry.Coordinates[1]
+	}
+	return location
+}
+
+// The context looks like "75, Paris, Île-de-France" : department number, department name, region.
+func departmentFromContext(context string) string {
+	parts := strings.Split(context, ",")
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
 }
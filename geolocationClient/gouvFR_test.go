@@ -1,34 +1,68 @@
 package geolocationClient_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/yousseffarkhani/playground/backend2/geolocationClient"
+	"github.com/yousseffarkhani/playground/backend2/store"
 )
 
+const searchResult = `{"features":[{"type": "Feature","geometry":{"type":"Point","coordinates":[2.0,3.0]},
+	"properties":{"label":"42 Avenue de Flandre 75019 Paris","score":0.87,"name":"42 Avenue de Flandre","postcode":"75019","city":"Paris","context":"75, Paris, Île-de-France"}}]}`
+
 func setup() (string, func()) {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/42+avenue+de+Flandre+Paris&limit=1", stubGetSearch)
+	mux.HandleFunc("/search/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("autocomplete") == "1" {
+			stubGetSuggestions(w, r)
+			return
+		}
+		stubGetSearch(w, r)
+	})
+	mux.HandleFunc("/reverse/", stubGetReverse)
 	svr := httptest.NewServer(mux)
 	return svr.URL, svr.Close
 }
 
 func stubGetSearch(w http.ResponseWriter, r *http.Request) {
-	searchResult := `{"features":[{"type": "Feature","geometry":{"type":"Point","coordinates":[2.0,3.0]}}]}`
-	fmt.Fprintf(w, searchResult)
+	if r.URL.Query().Get("q") == "" || r.URL.Query().Get("limit") != "1" {
+		http.Error(w, "missing parameters", http.StatusBadRequest)
+		return
+	}
+	fmt.Fprint(w, searchResult)
+}
+
+func stubGetReverse(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("lon") != "2.37" || r.URL.Query().Get("lat") != "48.89" {
+		fmt.Fprint(w, `{"features":[]}`)
+		return
+	}
+	fmt.Fprint(w, searchResult)
+}
+
+func stubGetSuggestions(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("q") != "42 avenue de Fl" || r.URL.Query().Get("limit") != "5" {
+		fmt.Fprint(w, `{"features":[]}`)
+		return
+	}
+	searchResult := `{"features":[
+	{"type": "Feature","geometry":{"type":"Point","coordinates":[2.3,48.8]},"properties":{"label":"42 Avenue de Flandre 59170 Croix","score":0.5,"postcode":"59170","city":"Croix"}},
+	{"type": "Feature","geometry":{"type":"Point","coordinates":[2.37,48.88]},"properties":{"label":"42 Avenue de Flandre 75019 Paris","score":0.7,"postcode":"75019","city":"Paris"}}]}`
+	fmt.Fprint(w, searchResult)
 }
 
 func TestGouvFR(t *testing.T) {
 	t.Run("Get geolocation informations", func(t *testing.T) {
 		URL, closeServ := setup()
 		defer closeServ()
-		client := geolocationClient.APIGouvFR{ApiBase: URL + "/"}
+		client := geolocationClient.APIGouvFR{BaseURL: URL}
 
 		wantLong, wantLat := 2.0, 3.0
-		gotLong, gotLat, err := client.GetLongAndLat("42 avenue de Flandre Paris")
+		gotLong, gotLat, err := client.GetLongAndLat(context.Background(), "42 avenue de Flandre Paris")
 		if err != nil {
 			t.Fatalf("Couldn't get geolocation info, %s", err)
 		}
@@ -37,6 +71,101 @@ func TestGouvFR(t *testing.T) {
 			t.Errorf("Long and lat are not correct")
 		}
 	})
+	t.Run("Geocode returns score and normalized address", func(t *testing.T) {
+		URL, closeServ := setup()
+		defer closeServ()
+		client := geolocationClient.APIGouvFR{BaseURL: URL}
+
+		got, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != nil {
+			t.Fatalf("Couldn't get geolocation info, %s", err)
+		}
+
+		want := store.Location{
+			Long:       2.0,
+			Lat:        3.0,
+			Score:      0.87,
+			Label:      "42 Avenue de Flandre 75019 Paris",
+			Address:    "42 Avenue de Flandre",
+			PostalCode: "75019",
+			City:       "Paris",
+			Department: "Paris",
+			Provider:   geolocationClient.ProviderAPIGouvFR,
+		}
+		if got != want {
+			t.Errorf("got : %v, want : %v", got, want)
+		}
+	})
+	t.Run("SuggestAddresses returns candidates ranked by score", func(t *testing.T) {
+		URL, closeServ := setup()
+		defer closeServ()
+		client := geolocationClient.APIGouvFR{BaseURL: URL}
+
+		got, err := client.SuggestAddresses(context.Background(), "42 avenue de Fl", 5)
+		if err != nil {
+			t.Fatalf("Couldn't get suggestions, %s", err)
+		}
+
+		if len(got) != 2 {
+			t.Fatalf("got %d suggestions, want 2", len(got))
+		}
+		assertAddress(t, got[0].Label, "42 Avenue de Flandre 75019 Paris")
+		assertAddress(t, got[1].Label, "42 Avenue de Flandre 59170 Croix")
+	})
+	t.Run("ReverseGeocode returns the address of the coordinates", func(t *testing.T) {
+		URL, closeServ := setup()
+		defer closeServ()
+		client := geolocationClient.APIGouvFR{BaseURL: URL}
+
+		got, err := client.ReverseGeocode(context.Background(), 2.37, 48.89)
+		if err != nil {
+			t.Fatalf("Couldn't get geolocation info, %s", err)
+		}
+
+		assertAddress(t, got.Address, "42 Avenue de Flandre")
+		assertAddress(t, got.PostalCode, "75019")
+		assertAddress(t, got.City, "Paris")
+		assertAddress(t, got.Department, "Paris")
+	})
+	t.Run("ReverseGeocode returns an error if there is no address at the coordinates", func(t *testing.T) {
+		URL, closeServ := setup()
+		defer closeServ()
+		client := geolocationClient.APIGouvFR{BaseURL: URL}
+
+		_, err := client.ReverseGeocode(context.Background(), 0, 0)
+		if err != store.ErrorNotFoundLocation {
+			t.Errorf("got : %v, want : %v", err, store.ErrorNotFoundLocation)
+		}
+	})
+	t.Run("Query parameters are escaped", func(t *testing.T) {
+		var gotQuery string
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotQuery = r.URL.Query().Get("q")
+			fmt.Fprint(w, searchResult)
+		}))
+		defer svr.Close()
+		client := geolocationClient.APIGouvFR{BaseURL: svr.URL}
+
+		_, err := client.Geocode(context.Background(), "12 rue de l'Église & place   d'Italie#2")
+		if err != nil {
+			t.Fatalf("Couldn't get geolocation info, %s", err)
+		}
+
+		assertAddress(t, gotQuery, "12 rue de l'Église & place d'Italie#2")
+	})
+	t.Run("SuggestAddresses returns no suggestion if nothing matches", func(t *testing.T) {
+		URL, closeServ := setup()
+		defer closeServ()
+		client := geolocationClient.APIGouvFR{BaseURL: URL}
+
+		got, err := client.SuggestAddresses(context.Background(), "zzzz", 5)
+		if err != nil {
+			t.Fatalf("Couldn't get suggestions, %s", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("got %d suggestions, want 0", len(got))
+		}
+	})
 }
 
 func assertAddress(t *testing.T, got, want string) {
@@ -0,0 +1,151 @@
+package geolocationClient
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const (
+	ProviderNominatim = "nominatim"
+	NominatimInterval = time.Second
+)
+
+// Nominatim geocodes addresses with OpenStreetMap data, see https://nominatim.org/release-docs/latest/api/Overview/
+// Its usage policy requires an identifying User-Agent and at most one request per second.
+type Nominatim struct {
+	BaseURL string
+	Requester
+}
+
+func NewNominatim() *Nominatim {
+	requester := NewRequester()
+	requester.UserAgent = "playground-backend"
+	requester.Limiter = NewRateLimiter(NominatimInterval)
+	return &Nominatim{Requester: requester}
+}
+
+func (n *Nominatim) baseURL() string {
+	if n.BaseURL == "" {
+		return "https://nominatim.openstreetmap.org"
+	}
+	return strings.TrimSuffix(n.BaseURL, "/")
+}
+
+type nominatimPlace struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+	PlaceRank   int    `json:"place_rank"`
+	Error       string `json:"error"`
+	Address     struct {
+		HouseNumber string `json:"house_number"`
+		Road        string `json:"road"`
+		Postcode    string `json:"postcode"`
+		City        string `json:"city"`
+		Town        string `json:"town"`
+		Village     string `json:"village"`
+		County      string `json:"county"`
+	} `json:"address"`
+}
+
+func (n *Nominatim) GetLongAndLat(ctx context.Context, address string) (float64, float64, error) {
+	location, err := n.Geocode(ctx, address)
+	if err != nil {
+		return 0, 0, err
+	}
+	return location.Long, location.Lat, nil
+}
+
+func (n *Nominatim) Geocode(ctx context.Context, address string) (store.Location, error) {
+	locations, err := n.search(ctx, address, 1)
+	if err != nil {
+		return store.Location{}, err
+	}
+	return locations[0], nil
+}
+
+func (n *Nominatim) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	query := url.Values{}
+	query.Set("lon", strconv.FormatFloat(long, 'f', -1, 64))
+	query.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	query.Set("format", "jsonv2")
+	query.Set("addressdetails", "1")
+
+	var place nominatimPlace
+	err := n.getJSON(ctx, n.baseURL()+"/reverse?"+query.Encode(), &place)
+	if err != nil {
+		return store.Location{}, err
+	}
+	if place.Error != "" {
+		return store.Location{}, store.ErrorNotFoundLocation
+	}
+	return place.toLocation(), nil
+}
+
+// SuggestAddresses never calls Nominatim, its usage policy forbids autocompletion.
+func (n *Nominatim) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	return nil, ErrorSuggestionsNotSupported
+}
+
+func (n *Nominatim) search(ctx context.Context, address string, limit int) ([]store.Location, error) {
+	query := url.Values{}
+	query.Set("q", strings.Join(strings.Fields(address), " "))
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("format", "jsonv2")
+	query.Set("addressdetails", "1")
+	query.Set("countrycodes", "fr")
+
+	var places []nominatimPlace
+	err := n.getJSON(ctx, n.baseURL()+"/search?"+query.Encode(), &places)
+	if err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, store.ErrorNotFoundLocation
+	}
+	locations := make([]store.Location, len(places))
+	for i, place := range places {
+		locations[i] = place.toLocation()
+	}
+	return locations, nil
+}
+
+func (p nominatimPlace) toLocation() store.Location {
+	location := store.Location{
+		Score:      nominatimScore(p.PlaceRank),
+		Label:      p.DisplayName,
+		Address:    strings.TrimSpace(p.Address.HouseNumber + " " + p.Address.Road),
+		PostalCode: p.Address.Postcode,
+		City:       firstNonEmpty(p.Address.City, p.Address.Town, p.Address.Village),
+		Department: p.Address.County,
+		Provider:   ProviderNominatim,
+	}
+	location.Long, _ = strconv.ParseFloat(p.Lon, 64)
+	location.Lat, _ = strconv.ParseFloat(p.Lat, 64)
+	return location
+}
+
+// Nominatim doesn't give a score, the place rank tells whether a building (30), a street (26) or a wider area was found.
+func nominatimScore(placeRank int) float64 {
+	switch {
+	case placeRank >= 30:
+		return 1
+	case placeRank >= 26:
+		return 0.7
+	}
+	return 0.4
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
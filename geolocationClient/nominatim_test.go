@@ -0,0 +1,106 @@
+package geolocationClient_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/geolocationClient"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const nominatimPlace = `{"lat":"48.886835","lon":"2.372452","display_name":"42, Avenue de Flandre, Paris, Île-de-France, 75019, France","place_rank":30,
+"address":{"house_number":"42","road":"Avenue de Flandre","city":"Paris","county":"Paris","postcode":"75019"}}`
+
+func newNominatimServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.UserAgent() == "" || r.UserAgent() == "Go-http-client/1.1" {
+			http.Error(w, "Access blocked", http.StatusForbidden)
+			return
+		}
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/search":
+			if query.Get("q") != "42 avenue de Flandre Paris" {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+			fmt.Fprint(w, "["+nominatimPlace+"]")
+		case "/reverse":
+			if query.Get("lon") != "2.372452" || query.Get("lat") != "48.886835" {
+				fmt.Fprint(w, `{"error":"Unable to geocode"}`)
+				return
+			}
+			fmt.Fprint(w, nominatimPlace)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestNominatim(t *testing.T) {
+	svr := newNominatimServer()
+	defer svr.Close()
+	client := geolocationClient.NewNominatim()
+	client.BaseURL = svr.URL
+	client.Limiter = geolocationClient.NewRateLimiter(20 * time.Millisecond)
+
+	t.Run("Waits between requests", func(t *testing.T) {
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		}
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Errorf("3 requests took %s, want at least 40ms", elapsed)
+		}
+	})
+
+	t.Run("Geocode returns the location", func(t *testing.T) {
+		got, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != nil {
+			t.Fatalf("Couldn't get geolocation info, %s", err)
+		}
+
+		want := store.Location{
+			Long:       2.372452,
+			Lat:        48.886835,
+			Score:      1,
+			Label:      "42, Avenue de Flandre, Paris, Île-de-France, 75019, France",
+			Address:    "42 Avenue de Flandre",
+			PostalCode: "75019",
+			City:       "Paris",
+			Department: "Paris",
+			Provider:   geolocationClient.ProviderNominatim,
+		}
+		if got != want {
+			t.Errorf("got : %v, want : %v", got, want)
+		}
+	})
+	t.Run("ReverseGeocode returns the address of the coordinates", func(t *testing.T) {
+		got, err := client.ReverseGeocode(context.Background(), 2.372452, 48.886835)
+		if err != nil {
+			t.Fatalf("Couldn't get geolocation info, %s", err)
+		}
+
+		assertAddress(t, got.Address, "42 Avenue de Flandre")
+	})
+	t.Run("SuggestAddresses doesn't call Nominatim", func(t *testing.T) {
+		_, err := client.SuggestAddresses(context.Background(), "42 avenue de Flandre Paris", 5)
+		if err != geolocationClient.ErrorSuggestionsNotSupported {
+			t.Errorf("got : %v, want : %v", err, geolocationClient.ErrorSuggestionsNotSupported)
+		}
+	})
+	t.Run("Returns an error if there is no result", func(t *testing.T) {
+		_, err := client.Geocode(context.Background(), "nowhere")
+		if err != store.ErrorNotFoundLocation {
+			t.Errorf("got : %v, want : %v", err, store.ErrorNotFoundLocation)
+		}
+		_, err = client.ReverseGeocode(context.Background(), 0, 0)
+		if err != store.ErrorNotFoundLocation {
+			t.Errorf("got : %v, want : %v", err, store.ErrorNotFoundLocation)
+		}
+	})
+}
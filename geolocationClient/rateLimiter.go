@@ -0,0 +1,36 @@
+package geolocationClient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces out the requests sent to an API by at least Interval.
+type RateLimiter struct {
+	Interval time.Duration
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+func NewRateLimiter(interval time.Duration) *RateLimiter {
+	return &RateLimiter{Interval: interval}
+}
+
+// Wait blocks until the request can be sent. The slot of a request cancelled while waiting isn't given back.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mutex.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.Interval)
+	l.mutex.Unlock()
+
+	if wait := slot.Sub(now); wait > 0 {
+		return sleep(ctx, wait)
+	}
+	return nil
+}
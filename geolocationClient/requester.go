@@ -0,0 +1,185 @@
+package geolocationClient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout    = 5 * time.Second
+	DefaultMaxRetries = 2
+	DefaultBackoff    = 200 * time.Millisecond
+	MaxRetryAfter     = 10 * time.Second
+)
+
+// Requester sends the GET requests of the geocoding providers.
+// Network errors, 429 and 5xx answers are retried MaxRetries times, waiting Backoff, then twice as long, and so on.
+// An answer with a Retry-After header is retried after that delay instead, or not at all if the delay would outlast
+// the context deadline or MaxRetryAfter.
+// Every attempt, retries included, waits for the Limiter if there is one.
+type Requester struct {
+	HTTPClient *http.Client
+	MaxRetries int
+	Backoff    time.Duration
+	Breaker    *CircuitBreaker
+	Limiter    *RateLimiter
+	UserAgent  string
+}
+
+func NewRequester() Requester {
+	return Requester{
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+		MaxRetries: DefaultMaxRetries,
+		Backoff:    DefaultBackoff,
+		Breaker:    NewCircuitBreaker(5, 30*time.Second),
+	}
+}
+
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (s StatusError) Error() string {
+	return fmt.Sprintf("Geocoding API answered with status %d", s.StatusCode)
+}
+
+func (r Requester) getJSON(ctx context.Context, URL string, target interface{}) error {
+	if r.Breaker != nil {
+		if err := r.Breaker.Allow(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt <= r.MaxRetries; attempt++ {
+		if attempt > 0 {
+			wait := r.Backoff * time.Duration(1<<uint(attempt-1))
+			if statusError, ok := err.(StatusError); ok && statusError.RetryAfter > 0 {
+				if !canWait(ctx, statusError.RetryAfter) {
+					break
+				}
+				wait = statusError.RetryAfter
+			}
+			err = sleep(ctx, wait)
+			if err != nil {
+				break
+			}
+		}
+		if r.Limiter != nil {
+			err = r.Limiter.Wait(ctx)
+			if err != nil {
+				break
+			}
+		}
+		var retry bool
+		retry, err = r.get(ctx, URL, target)
+		if !retry {
+			break
+		}
+	}
+
+	if r.Breaker != nil {
+		switch {
+		case err == nil:
+			r.Breaker.Success()
+		case isUpstreamFailure(ctx, err):
+			r.Breaker.Failure()
+		default:
+			// A cancelled request or a rejected one doesn't tell whether the API is healthy.
+			r.Breaker.Release()
+		}
+	}
+	return err
+}
+
+func (r Requester) get(ctx context.Context, URL string, target interface{}) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, URL, nil)
+	if err != nil {
+		return false, fmt.Errorf("Couldn't create request, %s", err)
+	}
+	req = req.WithContext(ctx)
+	if r.UserAgent != "" {
+		req.Header.Set("User-Agent", r.UserAgent)
+	}
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("Couldn't get info, %s", err)
+	}
+	defer func() {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return retry, StatusError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(target)
+	if err != nil {
+		return false, fmt.Errorf("Couldn't parse response, %s", err)
+	}
+	return false, nil
+}
+
+// isUpstreamFailure tells whether the error means that the API is unhealthy, as opposed to a bad request or a cancelled one.
+func isUpstreamFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if statusError, ok := err.(StatusError); ok {
+		return statusError.StatusCode == http.StatusTooManyRequests || statusError.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// parseRetryAfter reads a Retry-After header, either a number of seconds or an HTTP date. It returns 0 if the header is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(header, 10, 32); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	date, err := http.ParseTime(header)
+	if err != nil || !date.After(now) {
+		return 0
+	}
+	return date.Sub(now)
+}
+
+func canWait(ctx context.Context, duration time.Duration) bool {
+	if duration > MaxRetryAfter {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > duration
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,246 @@
+package geolocationClient_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/geolocationClient"
+)
+
+// newFlakyServer answers with the given statuses, one per request, then with a valid result.
+func newFlakyServer(statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1))
+		if call <= len(statuses) {
+			w.WriteHeader(statuses[call-1])
+			return
+		}
+		fmt.Fprint(w, searchResult)
+	}))
+	return svr, &calls
+}
+
+// newThrottlingServer answers the first request with a 429 and the given Retry-After header, then with a valid result.
+func newThrottlingServer(retryAfter string) (*httptest.Server, *int32) {
+	var calls int32
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, searchResult)
+	}))
+	return svr, &calls
+}
+
+func newTestClient(URL string, breaker *geolocationClient.CircuitBreaker) geolocationClient.APIGouvFR {
+	return geolocationClient.APIGouvFR{
+		BaseURL: URL,
+		Requester: geolocationClient.Requester{
+			HTTPClient: &http.Client{Timeout: time.Second},
+			MaxRetries: 2,
+			Backoff:    time.Millisecond,
+			Breaker:    breaker,
+		},
+	}
+}
+
+func TestRequester(t *testing.T) {
+	t.Run("Retries server errors", func(t *testing.T) {
+		svr, calls := newFlakyServer(http.StatusInternalServerError, http.StatusTooManyRequests)
+		defer svr.Close()
+		client := newTestClient(svr.URL, nil)
+
+		_, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != nil {
+			t.Fatalf("Request should have succeeded after retries, %s", err)
+		}
+		assertCalls(t, calls, 3)
+	})
+	t.Run("Gives up after MaxRetries", func(t *testing.T) {
+		svr, calls := newFlakyServer(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		defer svr.Close()
+		client := newTestClient(svr.URL, nil)
+
+		_, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		want := geolocationClient.StatusError{StatusCode: http.StatusBadGateway}
+		if err != want {
+			t.Errorf("got : %v, want : %v", err, want)
+		}
+		assertCalls(t, calls, 3)
+	})
+	t.Run("Doesn't retry client errors", func(t *testing.T) {
+		svr, calls := newFlakyServer(http.StatusBadRequest)
+		defer svr.Close()
+		client := newTestClient(svr.URL, nil)
+
+		_, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		want := geolocationClient.StatusError{StatusCode: http.StatusBadRequest}
+		if err != want {
+			t.Errorf("got : %v, want : %v", err, want)
+		}
+		assertCalls(t, calls, 1)
+	})
+	t.Run("Times out slow answers", func(t *testing.T) {
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			fmt.Fprint(w, searchResult)
+		}))
+		defer svr.Close()
+		client := newTestClient(svr.URL, nil)
+		client.HTTPClient.Timeout = 20 * time.Millisecond
+		client.MaxRetries = 0
+
+		_, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err == nil {
+			t.Errorf("Request should have timed out")
+		}
+	})
+	t.Run("Stops when the context is cancelled", func(t *testing.T) {
+		svr, calls := newFlakyServer(http.StatusInternalServerError, http.StatusInternalServerError)
+		defer svr.Close()
+		client := newTestClient(svr.URL, nil)
+		client.Backoff = time.Second
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.Geocode(ctx, "42 avenue de Flandre Paris")
+		if err != context.DeadlineExceeded {
+			t.Errorf("got : %v, want : %v", err, context.DeadlineExceeded)
+		}
+		assertCalls(t, calls, 1)
+	})
+	t.Run("Waits for Retry-After before retrying", func(t *testing.T) {
+		svr, calls := newThrottlingServer("1")
+		defer svr.Close()
+		client := newTestClient(svr.URL, nil)
+
+		start := time.Now()
+		_, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != nil {
+			t.Fatalf("Request should have succeeded after Retry-After, %s", err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("Retried after %s, want at least 1s", elapsed)
+		}
+		assertCalls(t, calls, 2)
+	})
+	t.Run("Gives up if Retry-After outlasts the deadline", func(t *testing.T) {
+		for _, retryAfter := range []string{"120", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)} {
+			svr, calls := newThrottlingServer(retryAfter)
+			breaker := geolocationClient.NewCircuitBreaker(1, time.Minute)
+			client := newTestClient(svr.URL, breaker)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+			start := time.Now()
+			_, err := client.Geocode(ctx, "42 avenue de Flandre Paris")
+			statusError, ok := err.(geolocationClient.StatusError)
+			if !ok || statusError.StatusCode != http.StatusTooManyRequests || statusError.RetryAfter < time.Minute {
+				t.Errorf("got : %#v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Gave up after %s", elapsed)
+			}
+			assertCalls(t, calls, 1)
+			if breaker.Allow() != geolocationClient.ErrCircuitOpen {
+				t.Errorf("Throttled request should count as a failure")
+			}
+			cancel()
+			svr.Close()
+		}
+	})
+	t.Run("Circuit breaker fails fast once open and recovers", func(t *testing.T) {
+		svr, calls := newFlakyServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+		defer svr.Close()
+		breaker := geolocationClient.NewCircuitBreaker(2, 50*time.Millisecond)
+		client := newTestClient(svr.URL, breaker)
+		client.MaxRetries = 0
+
+		for i := 0; i < 2; i++ {
+			client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		}
+		_, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != geolocationClient.ErrCircuitOpen {
+			t.Errorf("got : %v, want : %v", err, geolocationClient.ErrCircuitOpen)
+		}
+		assertCalls(t, calls, 2)
+
+		time.Sleep(60 * time.Millisecond)
+		_, err = client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != nil {
+			t.Fatalf("Trial request should have succeeded, %s", err)
+		}
+		_, err = client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != nil {
+			t.Errorf("Circuit should be closed, %s", err)
+		}
+		assertCalls(t, calls, 4)
+	})
+	t.Run("Circuit breaker ignores cancelled requests", func(t *testing.T) {
+		svr, _ := newFlakyServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+		defer svr.Close()
+		breaker := geolocationClient.NewCircuitBreaker(2, time.Minute)
+		client := newTestClient(svr.URL, breaker)
+		client.MaxRetries = 0
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		client.Geocode(cancelled, "42 avenue de Flandre Paris")
+		client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		_, err := client.Geocode(context.Background(), "42 avenue de Flandre Paris")
+		if err != geolocationClient.ErrCircuitOpen {
+			t.Errorf("got : %v, want : %v", err, geolocationClient.ErrCircuitOpen)
+		}
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("Lets a single trial request through once the open duration has elapsed", func(t *testing.T) {
+		breaker := geolocationClient.NewCircuitBreaker(1, 10*time.Millisecond)
+		breaker.Failure()
+
+		if breaker.Allow() != geolocationClient.ErrCircuitOpen {
+			t.Errorf("Circuit should be open")
+		}
+		time.Sleep(15 * time.Millisecond)
+		if breaker.Allow() != nil {
+			t.Errorf("Trial request should be allowed")
+		}
+		if breaker.Allow() != geolocationClient.ErrCircuitOpen {
+			t.Errorf("Only one trial request should be allowed")
+		}
+		breaker.Failure()
+		if breaker.Allow() != geolocationClient.ErrCircuitOpen {
+			t.Errorf("Circuit should be open again after a failed trial")
+		}
+	})
+	t.Run("Lets another trial request through once the trial is released", func(t *testing.T) {
+		breaker := geolocationClient.NewCircuitBreaker(1, 10*time.Millisecond)
+		breaker.Failure()
+		time.Sleep(15 * time.Millisecond)
+
+		if breaker.Allow() != nil {
+			t.Errorf("Trial request should be allowed")
+		}
+		breaker.Release()
+		if breaker.Allow() != nil {
+			t.Errorf("Another trial request should be allowed")
+		}
+	})
+}
+
+func assertCalls(t *testing.T, calls *int32, want int) {
+	t.Helper()
+	got := int(atomic.LoadInt32(calls))
+	if got != want {
+		t.Errorf("got %d calls, want %d", got, want)
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/yousseffarkhani/playground/backend2/authentication"
+	"github.com/yousseffarkhani/playground/backend2/blobStore"
 
 	"github.com/yousseffarkhani/playground/backend2/configuration"
 	"github.com/yousseffarkhani/playground/backend2/views"
@@ -20,7 +24,8 @@ import (
 )
 
 const (
-	dbFileName = "playgroundsOpenData.json"
+	dbFileName       = "playgroundsOpenData.json"
+	defaultPhotosDir = "photos"
 )
 
 func init() {
@@ -33,13 +38,85 @@ func main() {
 	if err != nil {
 		log.Fatalf("Problem opening %s %v", dbFileName, err)
 	}
-	geolocationClient := &geolocationClient.APIGouvFR{}
+	geocoder, err := newGeolocationClient()
+	if err != nil {
+		log.Fatalf("Problem creating geolocation client %v", err)
+	}
+	onShutdown(geocoder.SaveEvery(geolocationClient.DefaultCacheSaveEvery))
 	views := views.Initialize()
 	middlewares := middleware.Initialize()
-	svr := server.New(database, geolocationClient, views, middlewares)
+	svr := server.New(database, geocoder, views, middlewares)
+	if configuration.Variables.BANNED_WORDS_FILE != "" {
+		bannedWords, err := store.LoadBannedWords(configuration.Variables.BANNED_WORDS_FILE)
+		if err != nil {
+			log.Fatalf("Problem loading banned words %v", err)
+		}
+		svr.UseSpamFilter(store.NewSpamFilter(bannedWords))
+	}
+	photosDir := configuration.Variables.PHOTOS_DIR
+	if photosDir == "" {
+		photosDir = defaultPhotosDir
+	}
+	photos, err := blobStore.NewLocal(photosDir)
+	if err != nil {
+		log.Fatalf("Problem opening photo storage %v", err)
+	}
+	svr.UsePhotoStorage(photos)
 	listenAndServe(svr)
 }
 
+func newGeolocationClient() (*geolocationClient.Cache, error) {
+	client, err := newGeocoderChain()
+	if err != nil {
+		return nil, err
+	}
+	if configuration.Variables.GEOCODING_CACHE_FILE == "" {
+		return geolocationClient.NewCache(client), nil
+	}
+	return geolocationClient.NewPersistentCache(client, configuration.Variables.GEOCODING_CACHE_FILE)
+}
+
+// Providers are asked in the order of GEOCODING_PROVIDERS, by default api-adresse, then Google if it has a key, then Nominatim,
+// then the local BAN extract if GEOCODING_BAN_FILE is set. Set GEOCODING_PROVIDERS=ban to geocode without any network.
+func newGeocoderChain() (*geolocationClient.Chain, error) {
+	options := geolocationClient.ProviderOptions{
+		GoogleAPIKey: configuration.Variables.GOOGLE_GEOCODING_API_KEY,
+		BANFile:      configuration.Variables.GEOCODING_BAN_FILE,
+	}
+	providerNames := configuration.Variables.GEOCODING_PROVIDERS
+	if len(providerNames) == 0 {
+		providerNames = []string{geolocationClient.ProviderAPIGouvFR}
+		if options.GoogleAPIKey != "" {
+			providerNames = append(providerNames, geolocationClient.ProviderGoogle)
+		}
+		providerNames = append(providerNames, geolocationClient.ProviderNominatim)
+		if options.BANFile != "" {
+			providerNames = append(providerNames, geolocationClient.ProviderBAN)
+		}
+	}
+	providers := make([]store.GeolocationClient, len(providerNames))
+	for i, name := range providerNames {
+		provider, err := geolocationClient.NewProvider(name, options)
+		if err != nil {
+			return nil, err
+		}
+		providers[i] = provider
+	}
+	log.Println("Geocoding providers :", strings.Join(providerNames, ", "))
+	return geolocationClient.NewChain(providers...), nil
+}
+
+// onShutdown calls cleanup before exiting when the server is interrupted or terminated.
+func onShutdown(cleanup func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cleanup()
+		os.Exit(0)
+	}()
+}
+
 func listenAndServe(svr *server.PlaygroundServer) {
 	var port string
 	if configuration.Variables.ProductionMode {
@@ -9,6 +9,7 @@ import (
 	"github.com/yousseffarkhani/playground/backend2/server"
 
 	"github.com/yousseffarkhani/playground/backend2/authentication"
+	"github.com/yousseffarkhani/playground/backend2/configuration"
 )
 
 type MW func(http.Handler) http.Handler
@@ -22,6 +23,7 @@ func Initialize() map[string]server.Middleware {
 	middlewares["isLogged"] = use(isLogged)
 	middlewares["refresh"] = use(isLogged, refreshJWT)
 	middlewares["authorized"] = use(isLogged, refreshJWT, isAuthorized)
+	middlewares["moderator"] = use(isLogged, refreshJWT, isAuthorized, isModerator(configuration.Variables.MODERATORS))
 	return middlewares
 }
 
@@ -83,3 +85,22 @@ func isAuthorized(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// isModerator only lets through the users listed in moderators, it expects isAuthorized to run first.
+func isModerator(moderators []string) MW {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value("claims").(*authentication.Claims)
+			if ok {
+				for _, moderator := range moderators {
+					if claims.Username == moderator {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+			log.Println("Access denied : not a moderator")
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+}
@@ -156,3 +156,77 @@ func TestIsAuthorized(t *testing.T) {
 		}
 	})
 }
+
+func TestIsModerator(t *testing.T) {
+	mockHandler := &mockHandler{}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("Problem setting cookie jar, %s", err)
+	}
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Jar: jar,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/", func(w http.ResponseWriter, r *http.Request) {
+		authentication.SetJwtCookie(w, r.URL.Path[len("/login/"):])
+	})
+	mux.Handle("/moderation", use(isLogged, refreshJWT, isAuthorized, isModerator([]string{"moderator"}))(mockHandler))
+
+	svr := httptest.NewServer(mux)
+	defer svr.Close()
+
+	t.Run("Redirects to login if no JWT", func(t *testing.T) {
+		resp, err := client.Get(svr.URL + "/moderation")
+		if err != nil {
+			t.Fatalf("Couldn't get a response, %s", err)
+		}
+
+		if resp.StatusCode != http.StatusFound {
+			t.Errorf("Got %d, want %d", resp.StatusCode, http.StatusFound)
+		}
+		if mockHandler.called {
+			t.Error("Handler shouldn't be called")
+		}
+	})
+
+	t.Run("Forbidden if the user isn't a moderator", func(t *testing.T) {
+		_, err = client.Get(svr.URL + "/login/test")
+		if err != nil {
+			t.Fatalf("Couldn't get a response, %s", err)
+		}
+
+		resp, err := client.Get(svr.URL + "/moderation")
+		if err != nil {
+			t.Fatalf("Couldn't get a response, %s", err)
+		}
+
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Got %d, want %d", resp.StatusCode, http.StatusForbidden)
+		}
+		if mockHandler.called {
+			t.Error("Handler shouldn't be called")
+		}
+	})
+
+	t.Run("Handler called if the user is a moderator", func(t *testing.T) {
+		_, err = client.Get(svr.URL + "/login/moderator")
+		if err != nil {
+			t.Fatalf("Couldn't get a response, %s", err)
+		}
+
+		resp, err := client.Get(svr.URL + "/moderation")
+		if err != nil {
+			t.Fatalf("Couldn't get a response, %s", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Got %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if !mockHandler.called {
+			t.Error("Handler should be called")
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/authentication"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+// RateLimit allows Requests in any period of Window, there is no limit if Requests is 0.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimits are counted per user and per IP address, replies count as comments and photos as submissions.
+type RateLimits struct {
+	CommentsPerUser    RateLimit
+	CommentsPerIP      RateLimit
+	SubmissionsPerUser RateLimit
+	SubmissionsPerIP   RateLimit
+}
+
+var DefaultRateLimits = RateLimits{
+	CommentsPerUser:    RateLimit{Requests: 5, Window: time.Minute},
+	CommentsPerIP:      RateLimit{Requests: 20, Window: time.Minute},
+	SubmissionsPerUser: RateLimit{Requests: 5, Window: time.Hour},
+	SubmissionsPerIP:   RateLimit{Requests: 20, Window: time.Hour},
+}
+
+// rateLimiter keeps the time of the requests of each key over the last window.
+type rateLimiter struct {
+	mutex     sync.Mutex
+	limit     RateLimit
+	requests  map[string][]time.Time
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	return &rateLimiter{limit: limit, requests: make(map[string][]time.Time)}
+}
+
+// wait returns how long key has to wait before its next request, 0 if it can be done now.
+func (l *rateLimiter) wait(key string, now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.limit.Requests <= 0 {
+		return 0
+	}
+	requests := l.recentRequests(key, now)
+	if len(requests) < l.limit.Requests {
+		return 0
+	}
+	return requests[len(requests)-l.limit.Requests].Add(l.limit.Window).Sub(now)
+}
+
+func (l *rateLimiter) add(key string, now time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.limit.Requests <= 0 {
+		return
+	}
+	l.requests[key] = append(l.recentRequests(key, now), now)
+	if now.Sub(l.lastSweep) > l.limit.Window {
+		for key := range l.requests {
+			if len(l.recentRequests(key, now)) == 0 {
+				delete(l.requests, key)
+			}
+		}
+		l.lastSweep = now
+	}
+}
+
+func (l *rateLimiter) recentRequests(key string, now time.Time) []time.Time {
+	requests := l.requests[key]
+	for len(requests) > 0 && now.Sub(requests[0]) >= l.limit.Window {
+		requests = requests[1:]
+	}
+	l.requests[key] = requests
+	return requests
+}
+
+type rateLimits struct {
+	user *rateLimiter
+	ip   *rateLimiter
+}
+
+func newRateLimits(user, ip RateLimit) rateLimits {
+	return rateLimits{user: newRateLimiter(user), ip: newRateLimiter(ip)}
+}
+
+// rateLimited answers 429 with a Retry-After header once the user or its IP address made too many requests.
+func (p *PlaygroundServer) rateLimited(limits *rateLimits, next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		username := requestUsername(r)
+		ip := clientIP(r)
+		wait := limits.ip.wait(ip, now)
+		if username != "" {
+			if userWait := limits.user.wait(username, now); userWait > wait {
+				wait = userWait
+			}
+		}
+		if wait > 0 {
+			seconds := int(math.Ceil(wait.Seconds()))
+			log.Printf("Rate limit reached by %q (%s), retry in %d s", username, ip, seconds)
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, fmt.Sprintf("Too many requests, retry in %d seconds", seconds), http.StatusTooManyRequests)
+			return
+		}
+		limits.ip.add(ip, now)
+		if username != "" {
+			limits.user.add(username, now)
+		}
+		next(w, r)
+	}
+}
+
+// UseRateLimits replaces DefaultRateLimits and forgets the requests already made.
+func (p *PlaygroundServer) UseRateLimits(limits RateLimits) {
+	p.commentLimits = newRateLimits(limits.CommentsPerUser, limits.CommentsPerIP)
+	p.submissionLimits = newRateLimits(limits.SubmissionsPerUser, limits.SubmissionsPerIP)
+}
+
+// UseSpamFilter replaces the default filter, which has no banned words.
+func (p *PlaygroundServer) UseSpamFilter(filter *store.SpamFilter) {
+	p.spamFilter = filter
+}
+
+func requestUsername(r *http.Request) string {
+	if claims, ok := r.Context().Value("claims").(*authentication.Claims); ok {
+		return claims.Username
+	}
+	return ""
+}
+
+// visibleComments leaves out of the playgrounds the shadowed comments the user of the request shouldn't see.
+func visibleComments(r *http.Request, playgrounds store.Playgrounds) store.Playgrounds {
+	username := requestUsername(r)
+	visible := make(store.Playgrounds, len(playgrounds))
+	for i, playground := range playgrounds {
+		playground.Comments = playground.Comments.VisibleTo(username)
+		visible[i] = playground
+	}
+	return visible
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
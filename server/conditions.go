@@ -0,0 +1,82 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/authentication"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+type PlaygroundCondition struct {
+	Summary store.ConditionSummary `json:"summary"`
+	Reports store.ConditionReports `json:"reports"`
+}
+
+func (p *PlaygroundServer) getConditions(w http.ResponseWriter, r *http.Request) {
+	playground, err := p.findPlaygroundFromRequestParameter(w, r)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	condition := PlaygroundCondition{
+		Summary: playground.ConditionReports.Summary(now),
+		Reports: playground.ConditionReports.Since(now.Add(-store.ConditionMaxAge)),
+	}
+	err = encodeToJson(w, condition)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// addConditionReport reads a score from 1 to 5 for each criterion sent, criteria left empty are not scored.
+func (p *PlaygroundServer) addConditionReport(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	err = r.ParseForm()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	report := store.ConditionReport{
+		Author:           claims.Username,
+		TimeOfSubmission: time.Now(),
+		Scores:           make(map[string]int),
+	}
+	for _, criterion := range store.ConditionCriteria {
+		value := r.FormValue(criterion.Name)
+		if value == "" {
+			continue
+		}
+		score, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, store.ErrorInvalidConditionScore.Error(), http.StatusBadRequest)
+			return
+		}
+		report.Scores[criterion.Name] = score
+	}
+
+	err = p.database.MainPlaygroundStore.AddConditionReport(ID, report)
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+	case store.ErrorNotFoundPlayground:
+		w.WriteHeader(http.StatusNotFound)
+	case store.ErrorInvalidConditionScore, store.ErrorEmptyConditionReport:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Problème à l'ajout du rapport d'état, %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const openAtLayout = "2006-01-02T15:04"
+
+// filterAndSortFromRequest applies the city, department, postal_code, type, coating, open, open_now, open_at, q and sort parameters.
+// Filters with several values (repeated or separated by commas) match any of them.
+func filterAndSortFromRequest(r *http.Request, playgrounds store.Playgrounds) (store.Playgrounds, error) {
+	filter, err := extractFilterFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	playgrounds = playgrounds.Filter(filter)
+
+	sortKey := r.URL.Query().Get("sort")
+	if sortKey == "" {
+		sortKey = store.SortByName
+	}
+	var long, lat float64
+	if sortKey == store.SortByDistance {
+		long, lat, err = extractCoordinatesFromRequest(r)
+		if err != nil {
+			return nil, fmt.Errorf("sort=%s needs coordinates, %s", store.SortByDistance, err)
+		}
+	}
+	playgrounds, err = playgrounds.Sort(sortKey, long, lat)
+	if err != nil {
+		return nil, fmt.Errorf("sort parameter should be one of %s, %s, %s, %s, got %q", store.SortByName, store.SortByDistance, store.SortByNewest, store.SortByRating, sortKey)
+	}
+	return playgrounds, nil
+}
+
+func extractFilterFromRequest(r *http.Request) (store.PlaygroundFilter, error) {
+	queryStrings := r.URL.Query()
+	filter := store.PlaygroundFilter{
+		Cities:      extractListFromRequest(r, "city"),
+		Departments: extractListFromRequest(r, "department"),
+		PostalCodes: extractListFromRequest(r, "postal_code"),
+		Types:       extractListFromRequest(r, "type"),
+		Coatings:    extractListFromRequest(r, "coating"),
+		Query:       queryStrings.Get("q"),
+	}
+	if openParameter := queryStrings.Get("open"); openParameter != "" {
+		open, err := strconv.ParseBool(openParameter)
+		if err != nil {
+			return store.PlaygroundFilter{}, fmt.Errorf("open parameter should be true or false, got %q", openParameter)
+		}
+		filter.Open = &open
+	}
+	openAt, err := extractOpenAtFromRequest(r)
+	if err != nil {
+		return store.PlaygroundFilter{}, err
+	}
+	filter.OpenAt = openAt
+	return filter, nil
+}
+
+// extractOpenAtFromRequest returns the current time for open_now=true, or the time given by open_at,
+// either in RFC 3339 or as a Paris local time (2006-01-02T15:04). It returns a zero time if there is no such parameter.
+// There is no filter on closed playgrounds, open_now=false is rejected rather than ignored.
+func extractOpenAtFromRequest(r *http.Request) (time.Time, error) {
+	queryStrings := r.URL.Query()
+	if openAtParameter := queryStrings.Get("open_at"); openAtParameter != "" {
+		if openAt, err := time.Parse(time.RFC3339, openAtParameter); err == nil {
+			return openAt, nil
+		}
+		openAt, err := time.ParseInLocation(openAtLayout, openAtParameter, store.ParisLocation)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("open_at parameter should be a date like %s, got %q", openAtLayout, openAtParameter)
+		}
+		return openAt, nil
+	}
+	if openNowParameter := queryStrings.Get("open_now"); openNowParameter != "" {
+		openNow, err := strconv.ParseBool(openNowParameter)
+		if err != nil || !openNow {
+			return time.Time{}, fmt.Errorf("open_now parameter can only be true, got %q", openNowParameter)
+		}
+		return time.Now(), nil
+	}
+	return time.Time{}, nil
+}
+
+func extractListFromRequest(r *http.Request, parameter string) []string {
+	values := []string{}
+	for _, parameterValue := range r.URL.Query()[parameter] {
+		for _, value := range strings.Split(parameterValue, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+// The whole dataset is geocoded again, which takes a while with a remote provider.
+const geocodingReportTimeout = 30 * time.Minute
+
+// geocodingJob runs CheckGeocoding in the background and keeps the last report.
+type geocodingJob struct {
+	mutex   sync.Mutex
+	running bool
+	report  *store.GeocodingReport
+	queued  int
+}
+
+type GeocodingJobStatus struct {
+	Running bool                   `json:"running"`
+	Report  *store.GeocodingReport `json:"report"`
+	Queued  int                    `json:"queued"`
+}
+
+func (g *geocodingJob) status() GeocodingJobStatus {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return GeocodingJobStatus{Running: g.running, Report: g.report, Queued: g.queued}
+}
+
+// start returns false if a report is already being computed.
+func (g *geocodingJob) start(run func() (store.GeocodingReport, int)) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.running {
+		return false
+	}
+	g.running = true
+	go func() {
+		report, queued := run()
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		g.running = false
+		g.report = &report
+		g.queued = queued
+	}()
+	return true
+}
+
+func (p *PlaygroundServer) getGeocodingReport(w http.ResponseWriter, r *http.Request) {
+	encodeToJson(w, p.geocodingJob.status())
+}
+
+// startGeocodingReport re-geocodes every playground, max_distance (metres) sets the tolerated distance
+// and queue=true queues the suggested corrections for moderators.
+func (p *PlaygroundServer) startGeocodingReport(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	maxDistance := float64(store.DefaultMaxGeocodingDistance)
+	if value := r.Form.Get("max_distance"); value != "" {
+		var err error
+		maxDistance, err = strconv.ParseFloat(value, 64)
+		if err != nil || maxDistance <= 0 {
+			http.Error(w, "max_distance should be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	queue, _ := strconv.ParseBool(r.Form.Get("queue"))
+
+	playgrounds := p.database.MainPlaygroundStore.AllPlaygrounds()
+	checkedPlaygrounds := make(store.Playgrounds, len(playgrounds))
+	copy(checkedPlaygrounds, playgrounds)
+	started := p.geocodingJob.start(func() (store.GeocodingReport, int) {
+		ctx, cancel := context.WithTimeout(context.Background(), geocodingReportTimeout)
+		defer cancel()
+		report := checkedPlaygrounds.CheckGeocoding(ctx, p.apiClient, maxDistance)
+		log.Printf("Geocoding report : %d playgrounds checked, %d outliers", report.Checked, len(report.Outliers))
+		if !queue {
+			return report, 0
+		}
+		return report, p.database.Corrections.Queue(report.Outliers)
+	})
+	if !started {
+		http.Error(w, "Geocoding report is already running", http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (p *PlaygroundServer) getAllGeocodingCorrections(w http.ResponseWriter, r *http.Request) {
+	encodeToJson(w, p.database.Corrections.AllCorrections())
+}
+
+func (p *PlaygroundServer) applyGeocodingCorrection(w http.ResponseWriter, r *http.Request) {
+	ID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	err = p.database.ApplyCorrection(ID)
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+	case store.ErrorNotFoundCorrection, store.ErrorNotFoundPlayground:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (p *PlaygroundServer) deleteGeocodingCorrection(w http.ResponseWriter, r *http.Request) {
+	ID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p.database.Corrections.DeleteCorrection(ID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (p *PlaygroundServer) geocodingCorrectionsHandler(w http.ResponseWriter, r *http.Request) {
+	p.renderView(w, r, "geocodingCorrections", p.database.Corrections.AllCorrections())
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/yousseffarkhani/playground/backend2/authentication"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+type Moderation struct {
+	Queue        []store.ReportedComment  `json:"queue"`
+	Photos       []store.PendingPhoto     `json:"photos"`
+	AuditLog     []store.ModerationAction `json:"audit_log"`
+	ShadowBanned []string                 `json:"shadow_banned"`
+}
+
+func (p *PlaygroundServer) moderationHandler(w http.ResponseWriter, r *http.Request) {
+	p.renderView(w, r, "moderation", Moderation{
+		Queue:        p.database.ModerationQueue(),
+		Photos:       p.database.PhotoQueue(),
+		AuditLog:     p.database.Moderation.AuditLog(),
+		ShadowBanned: p.database.Moderation.ShadowBannedUsers(),
+	})
+}
+
+// reportComment takes a reason (spam, abuse, off_topic or other) and optional details.
+func (p *PlaygroundServer) reportComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	playgroundID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	commentID, err := extractIDFromRequest(r, "commentID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = p.database.ReportComment(store.CommentReport{
+		PlaygroundID:     playgroundID,
+		CommentID:        commentID,
+		Reporter:         claims.Username,
+		Reason:           r.FormValue("reason"),
+		Details:          r.FormValue("details"),
+		TimeOfSubmission: time.Now(),
+	})
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+	case store.ErrorNotFoundPlayground, store.ErrorNotFoundComment:
+		w.WriteHeader(http.StatusNotFound)
+	case store.ErrorAlreadyReported:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case store.ErrorInvalidReportReason:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Problème au signalement du commentaire, %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// getCommentHistory returns every version of a comment from the oldest, including the content of hidden comments.
+func (p *PlaygroundServer) getCommentHistory(w http.ResponseWriter, r *http.Request) {
+	playground, err := p.findPlaygroundFromRequestParameter(w, r)
+	if err != nil {
+		return
+	}
+	commentID, err := extractIDFromRequest(r, "commentID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	versions, err := playground.CommentHistory(commentID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	err = encodeToJson(w, versions)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (p *PlaygroundServer) getModerationQueue(w http.ResponseWriter, r *http.Request) {
+	err := encodeToJson(w, p.database.ModerationQueue())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (p *PlaygroundServer) getModerationLog(w http.ResponseWriter, r *http.Request) {
+	err := encodeToJson(w, p.database.Moderation.AuditLog())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// moderateComment takes an action (hide, unhide, delete or dismiss) and an optional reason kept in the audit log.
+// Unlike deleteComment, the moderator doesn't need to be the author of the comment.
+func (p *PlaygroundServer) moderateComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	playgroundID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	commentID, err := extractIDFromRequest(r, "commentID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = p.database.ModerateComment(playgroundID, commentID, claims.Username, r.FormValue("action"), r.FormValue("reason"))
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+	case store.ErrorNotFoundPlayground, store.ErrorNotFoundComment:
+		w.WriteHeader(http.StatusNotFound)
+	case store.ErrorUnknownModerationAction:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Problème à la modération du commentaire, %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (p *PlaygroundServer) getShadowBans(w http.ResponseWriter, r *http.Request) {
+	err := encodeToJson(w, p.database.Moderation.ShadowBannedUsers())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// shadowBanUser takes the username to ban and an optional reason kept in the audit log.
+func (p *PlaygroundServer) shadowBanUser(w http.ResponseWriter, r *http.Request) {
+	p.setShadowBan(w, r, r.FormValue("username"), true)
+}
+
+func (p *PlaygroundServer) liftShadowBan(w http.ResponseWriter, r *http.Request) {
+	p.setShadowBan(w, r, mux.Vars(r)["username"], false)
+}
+
+func (p *PlaygroundServer) setShadowBan(w http.ResponseWriter, r *http.Request, username string, banned bool) {
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err := p.database.ShadowBanUser(username, claims.Username, banned, r.FormValue("reason"))
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+	case store.ErrEmptyField:
+		http.Error(w, "username parameter is required", http.StatusBadRequest)
+	default:
+		log.Printf("Problème au bannissement de l'utilisateur, %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Page is sent instead of the bare list when the client asks for a limit, a page or a cursor.
+// Next is the URL of the following page, empty on the last one.
+type Page struct {
+	Items interface{} `json:"items"`
+	Next  string      `json:"next"`
+	Total int         `json:"total"`
+}
+
+// pageCursor points after the item with ID, Offset is only used if this item has been deleted meanwhile.
+type pageCursor struct {
+	ID     int `json:"id"`
+	Offset int `json:"offset"`
+}
+
+func isPaginated(r *http.Request) bool {
+	queryStrings := r.URL.Query()
+	for _, parameter := range []string{"limit", "page", "cursor"} {
+		if _, ok := queryStrings[parameter]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeListToJson sends list as is, or the requested page of list with Link headers if the request is paginated.
+// IDAt returns the ID of the item at index i, cursors rely on it to stay valid when items are added before them.
+func encodeListToJson(w http.ResponseWriter, r *http.Request, list interface{}, IDAt func(i int) int) {
+	if !isPaginated(r) {
+		err := encodeToJson(w, list)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	items := reflect.ValueOf(list)
+	start, end, links, err := paginate(r, items.Len(), IDAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	linkHeader := []string{}
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if URL, ok := links[rel]; ok {
+			linkHeader = append(linkHeader, fmt.Sprintf("<%s>; rel=%q", URL, rel))
+		}
+	}
+	if len(linkHeader) > 0 {
+		w.Header().Set("Link", strings.Join(linkHeader, ", "))
+	}
+	err = encodeToJson(w, Page{Items: items.Slice(start, end).Interface(), Next: links["next"], Total: items.Len()})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func paginate(r *http.Request, length int, IDAt func(int) int) (int, int, map[string]string, error) {
+	queryStrings := r.URL.Query()
+	limit := defaultPageSize
+	if limitParameter := queryStrings.Get("limit"); limitParameter != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParameter)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, nil, fmt.Errorf("limit parameter should be a number between 1 and %d, got %q", maxPageSize, limitParameter)
+		}
+	}
+	cursorParameter, pageParameter := queryStrings.Get("cursor"), queryStrings.Get("page")
+	if cursorParameter != "" && pageParameter != "" {
+		return 0, 0, nil, fmt.Errorf("cursor and page parameters can't be used together")
+	}
+
+	links := make(map[string]string)
+	start, page := 0, 0
+	switch {
+	case cursorParameter != "":
+		cursor, err := decodeCursor(cursorParameter)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		start = cursor.Offset
+		for i := 0; i < length; i++ {
+			if IDAt(i) == cursor.ID {
+				start = i + 1
+				break
+			}
+		}
+	case pageParameter != "":
+		var err error
+		page, err = strconv.Atoi(pageParameter)
+		if err != nil || page < 1 {
+			return 0, 0, nil, fmt.Errorf("page parameter should be a number greater than 0, got %q", pageParameter)
+		}
+		start = (page - 1) * limit
+		lastPage := (length + limit - 1) / limit
+		if lastPage == 0 {
+			lastPage = 1
+		}
+		links["first"] = pageURL(r, "page", "1")
+		links["last"] = pageURL(r, "page", strconv.Itoa(lastPage))
+		if page > 1 {
+			links["prev"] = pageURL(r, "page", strconv.Itoa(minInt(page-1, lastPage)))
+		}
+	}
+	start = minInt(start, length)
+	end := minInt(start+limit, length)
+
+	if end < length {
+		if page > 0 {
+			links["next"] = pageURL(r, "page", strconv.Itoa(page+1))
+		} else {
+			links["next"] = pageURL(r, "cursor", encodeCursor(pageCursor{ID: IDAt(end - 1), Offset: end}))
+		}
+	}
+	return start, end, links, nil
+}
+
+func pageURL(r *http.Request, parameter, value string) string {
+	queryStrings := r.URL.Query()
+	queryStrings.Set(parameter, value)
+	if _, ok := queryStrings["limit"]; !ok {
+		queryStrings.Set("limit", strconv.Itoa(defaultPageSize))
+	}
+	return r.URL.Path + "?" + queryStrings.Encode()
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursorParameter string) (pageCursor, error) {
+	var cursor pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursorParameter)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.Offset < 0 {
+		return pageCursor{}, fmt.Errorf("cursor parameter is invalid, got %q", cursorParameter)
+	}
+	return cursor, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/authentication"
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+// maxUploadSize leaves room for the multipart headers around a photo of store.MaxPhotoSize.
+const maxUploadSize = store.MaxPhotoSize + 1<<20
+
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// UsePhotoStorage enables the photo uploads, they are refused while there is no storage.
+func (p *PlaygroundServer) UsePhotoStorage(blobs store.BlobStore) {
+	p.photos = blobs
+}
+
+// uploadPhoto takes a multipart form with the image in the photo field.
+// The photo is encoded again without its metadata and waits for moderation before being shown.
+func (p *PlaygroundServer) uploadPhoto(w http.ResponseWriter, r *http.Request) {
+	if p.photos == nil {
+		http.Error(w, "Photo uploads are disabled", http.StatusServiceUnavailable)
+		return
+	}
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	playground, err := p.findPlaygroundFromRequestParameter(w, r)
+	if err != nil {
+		return
+	}
+	if r.ContentLength > maxUploadSize {
+		http.Error(w, store.ErrorPhotoTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	file, _, err := r.FormFile("photo")
+	if err != nil {
+		http.Error(w, "Missing photo", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		http.Error(w, store.ErrorPhotoTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	processed, err := store.ProcessPhoto(data)
+	switch err {
+	case nil:
+	case store.ErrorPhotoTooLarge:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case store.ErrorInvalidPhotoType:
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.database.Moderation.IsShadowBanned(claims.Username) {
+		log.Printf("Photo envoyée par %q ignorée, l'utilisateur est banni", claims.Username)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	name, err := randomName()
+	if err != nil {
+		log.Printf("Problème à la création du nom de la photo, %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	prefix := fmt.Sprintf("playgrounds/%d/%s", playground.ID, name)
+	extension := photoExtensions[processed.ContentType]
+	photo := store.Photo{
+		Author:           claims.Username,
+		TimeOfSubmission: time.Now(),
+		ContentType:      processed.ContentType,
+		Width:            processed.Width,
+		Height:           processed.Height,
+		Key:              prefix + extension,
+		ThumbnailKey:     prefix + "_thumbnail" + extension,
+	}
+	err = p.photos.Put(photo.Key, photo.ContentType, processed.Image)
+	if err == nil {
+		err = p.photos.Put(photo.ThumbnailKey, photo.ContentType, processed.Thumbnail)
+	}
+	if err == nil {
+		_, err = p.database.MainPlaygroundStore.AddPhoto(playground.ID, photo)
+	}
+	if err != nil {
+		log.Printf("Problème à l'enregistrement de la photo, %s", err)
+		p.deletePhotoFiles(photo)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (p *PlaygroundServer) getPhotos(w http.ResponseWriter, r *http.Request) {
+	playground, err := p.findPlaygroundFromRequestParameter(w, r)
+	if err != nil {
+		return
+	}
+	err = encodeToJson(w, playground.ApprovedPhotos())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (p *PlaygroundServer) getPhoto(w http.ResponseWriter, r *http.Request) {
+	p.servePhoto(w, r, false, false)
+}
+
+func (p *PlaygroundServer) getPhotoThumbnail(w http.ResponseWriter, r *http.Request) {
+	p.servePhoto(w, r, true, false)
+}
+
+// getPendingPhoto lets the moderators see a photo whatever its status.
+func (p *PlaygroundServer) getPendingPhoto(w http.ResponseWriter, r *http.Request) {
+	p.servePhoto(w, r, false, true)
+}
+
+// servePhoto only serves the approved photos, unless anyStatus is set.
+func (p *PlaygroundServer) servePhoto(w http.ResponseWriter, r *http.Request, thumbnail, anyStatus bool) {
+	playground, err := p.findPlaygroundFromRequestParameter(w, r)
+	if err != nil {
+		return
+	}
+	photoID, err := extractIDFromRequest(r, "photoID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	photo, err := playground.FindPhoto(photoID)
+	if err != nil || (!anyStatus && photo.Status != store.PhotoApproved) || p.photos == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := photo.Key
+	if thumbnail {
+		key = photo.ThumbnailKey
+	}
+	data, contentType, err := p.photos.Get(key)
+	switch err {
+	case nil:
+	case store.ErrorNotFoundBlob:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	default:
+		log.Printf("Problème à la lecture de la photo %s, %s", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if anyStatus {
+		w.Header().Set("Cache-Control", "private, no-store")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (p *PlaygroundServer) getPhotoQueue(w http.ResponseWriter, r *http.Request) {
+	err := encodeToJson(w, p.database.PhotoQueue())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// moderatePhoto takes an action (approve or reject) and an optional reason kept in the audit log.
+// The files of a rejected photo are deleted.
+func (p *PlaygroundServer) moderatePhoto(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	playgroundID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	photoID, err := extractIDFromRequest(r, "photoID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	photo, err := p.database.ModeratePhoto(playgroundID, photoID, claims.Username, r.FormValue("action"), r.FormValue("reason"))
+	switch err {
+	case nil:
+		if photo.Status == store.PhotoRejected {
+			p.deletePhotoFiles(photo)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	case store.ErrorNotFoundPlayground, store.ErrorNotFoundPhoto:
+		w.WriteHeader(http.StatusNotFound)
+	case store.ErrorUnknownModerationAction:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case store.ErrorPhotoRejected:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Problème à la modération de la photo, %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (p *PlaygroundServer) deletePhotoFiles(photo store.Photo) {
+	if p.photos == nil {
+		return
+	}
+	for _, key := range []string{photo.Key, photo.ThumbnailKey} {
+		if err := p.photos.Delete(key); err != nil {
+			log.Printf("Problème à la suppression de la photo %s, %s", key, err)
+		}
+	}
+}
+
+func randomName() (string, error) {
+	bytes := make([]byte, 16)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
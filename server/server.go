@@ -31,28 +31,68 @@ const (
 	URLSubmitPlayground     = URLPlaygrounds + "/submit"
 	URLSubmittedPlaygrounds = "/submittedPlaygrounds"
 	URLSubmittedPlayground  = URLSubmittedPlaygrounds + "/{ID}"
+	URLGeocodingCorrections = "/geocodingCorrections"
+	URLModeration           = "/moderation"
 	URLContact              = "/contact" // TODO
 
 	// APIs
 	APIPlaygrounds          = "/api/playgrounds"
 	APIPlayground           = APIPlaygrounds + "/{ID}"
+	APIPlaygroundFacets     = APIPlaygrounds + "/facets"
 	APINearestPlaygrounds   = "/api/nearestPlaygrounds"
+	APIReverseGeocode       = "/api/reverseGeocode"
+	APIAddressSuggestions   = "/api/addressSuggestions"
+	APISearch               = "/api/search"
 	APIComments             = APIPlayground + "/comments"
+	APIRating               = APIPlayground + "/rating"
+	APIConditions           = APIPlayground + "/conditions"
 	APIComment              = APIComments + "/{commentID}"
+	APICommentVote          = APIComment + "/vote"
+	APIReplies              = APIComment + "/replies"
+	APICommentReport        = APIComment + "/report"
+	APICommentHistory       = APIComment + "/history"
 	APISubmittedPlaygrounds = "/api/submittedPlaygrounds"
 	APISubmittedPlayground  = APISubmittedPlaygrounds + "/{ID}"
+	APIGeocodingReport      = "/api/geocodingReport"
+	APIGeocodingCorrections = "/api/geocodingCorrections"
+	APIGeocodingCorrection  = APIGeocodingCorrections + "/{ID}"
+	APIModerationReports    = "/api/moderation/reports"
+	APIModerationLog        = "/api/moderation/log"
+	APIModerationComment    = "/api/moderation/playgrounds/{ID}/comments/{commentID}"
+	APIShadowBans           = "/api/moderation/shadowBans"
+	APIShadowBan            = APIShadowBans + "/{username}"
+	APIPhotos               = APIPlayground + "/photos"
+	APIPhoto                = APIPhotos + "/{photoID}"
+	APIPhotoThumbnail       = APIPhoto + "/thumbnail"
+	APIModerationPhotos     = "/api/moderation/photos"
+	APIModerationPhoto      = "/api/moderation/playgrounds/{ID}/photos/{photoID}"
 	// Other
 	JsonContentType    = "application/json"
 	HtmlContentType    = "text/html; charset=utf-8"
 	GzipAcceptEncoding = "gzip"
 )
 
+const (
+	defaultNearestPlaygroundsLimit = 10
+	maxNearestPlaygroundsLimit     = 100
+	maxZoom                        = 22
+	defaultSearchLimit             = 20
+	maxSearchLimit                 = 100
+)
+
 type PlaygroundServer struct {
 	database  store.PlaygroundDatabase
 	apiClient store.GeolocationClient
 	http.Handler
-	views       map[string]View
-	middlewares map[string]Middleware
+	views        map[string]View
+	middlewares  map[string]Middleware
+	suggestions  *suggestionsCache
+	geocodingJob *geocodingJob
+	spamFilter   *store.SpamFilter
+	photos       store.BlobStore
+	// Rate limits of addComment and addReply, and of submitPlayground.
+	commentLimits    rateLimits
+	submissionLimits rateLimits
 }
 
 type Middleware interface {
@@ -67,9 +107,15 @@ func New(playgroundStore store.PlaygroundStore, client store.GeolocationClient,
 	svr := new(PlaygroundServer)
 	svr.database.MainPlaygroundStore = playgroundStore
 	svr.database.SubmittedPlaygroundStore = &store.SubmittedPlaygroundStore{}
+	svr.database.Corrections = &store.CorrectionStore{}
+	svr.database.Moderation = &store.ModerationStore{}
 	svr.apiClient = client
 	svr.views = views
 	svr.middlewares = middlewares
+	svr.suggestions = newSuggestionsCache()
+	svr.geocodingJob = &geocodingJob{}
+	svr.spamFilter = store.NewSpamFilter(nil)
+	svr.UseRateLimits(DefaultRateLimits)
 	router := newRouter(svr)
 	svr.Handler = router
 	return svr
@@ -86,6 +132,8 @@ func newRouter(svr *PlaygroundServer) *mux.Router {
 	router.Handle(URLPlayground, svr.middlewares["refresh"].ThenFunc(svr.playgroundHandler)).Methods(http.MethodGet)
 	router.Handle(URLSubmittedPlaygrounds, svr.middlewares["authorized"].ThenFunc(svr.submittedPlaygroundsHandler)).Methods(http.MethodGet)
 	router.Handle(URLSubmittedPlayground, svr.middlewares["authorized"].ThenFunc(svr.submittedPlaygroundHandler)).Methods(http.MethodGet)
+	router.Handle(URLGeocodingCorrections, svr.middlewares["moderator"].ThenFunc(svr.geocodingCorrectionsHandler)).Methods(http.MethodGet)
+	router.Handle(URLModeration, svr.middlewares["moderator"].ThenFunc(svr.moderationHandler)).Methods(http.MethodGet)
 	router.Handle(URLLogin, svr.middlewares["isLogged"].ThenFunc(svr.loginHandler)).Methods(http.MethodGet)
 	router.HandleFunc(URLLogout, logoutHandler).Methods(http.MethodGet)
 	router.PathPrefix("/static").Handler(http.StripPrefix("/static", http.FileServer(http.Dir("static"))))
@@ -99,27 +147,60 @@ func newRouter(svr *PlaygroundServer) *mux.Router {
 	// API
 	// Playground
 	// GET
-	router.HandleFunc(APIPlaygrounds, svr.getAllPlaygrounds).Methods(http.MethodGet)
-	router.HandleFunc(APIPlaygrounds+"/", svr.getAllPlaygrounds).Methods(http.MethodGet)
-	router.HandleFunc(APIPlayground, svr.getPlayground).Methods(http.MethodGet)
-	router.HandleFunc(APINearestPlaygrounds, svr.getNearestPlaygrounds).Methods(http.MethodGet)
+	router.Handle(APIPlaygrounds, svr.middlewares["isLogged"].ThenFunc(svr.getAllPlaygrounds)).Methods(http.MethodGet)
+	router.Handle(APIPlaygrounds+"/", svr.middlewares["isLogged"].ThenFunc(svr.getAllPlaygrounds)).Methods(http.MethodGet)
+	router.HandleFunc(APIPlaygroundFacets, svr.getPlaygroundFacets).Methods(http.MethodGet)
+	router.Handle(APIPlayground, svr.middlewares["isLogged"].ThenFunc(svr.getPlayground)).Methods(http.MethodGet)
+	router.Handle(APINearestPlaygrounds, svr.middlewares["isLogged"].ThenFunc(svr.getNearestPlaygrounds)).Methods(http.MethodGet)
 	router.HandleFunc(APISubmittedPlaygrounds, svr.getAllSubmittedPlaygrounds).Methods(http.MethodGet)
+	// Geolocation
+	router.HandleFunc(APIReverseGeocode, svr.reverseGeocode).Methods(http.MethodGet)
+	router.HandleFunc(APIAddressSuggestions, svr.getAddressSuggestions).Methods(http.MethodGet)
+	router.HandleFunc(APISearch, svr.search).Methods(http.MethodGet)
 	// POST
-	router.Handle(APISubmittedPlaygrounds, svr.middlewares["authorized"].ThenFunc(svr.submitPlayground)).Methods(http.MethodPost)
+	router.Handle(APISubmittedPlaygrounds, svr.middlewares["authorized"].ThenFunc(svr.rateLimited(&svr.submissionLimits, svr.submitPlayground))).Methods(http.MethodPost)
 	router.Handle(APIPlaygrounds, svr.middlewares["authorized"].ThenFunc(svr.addPlayground)).Methods(http.MethodPost)
 	router.Handle(APISubmittedPlayground, svr.middlewares["authorized"].ThenFunc(svr.deleteSubmittedPlayground)).Methods(http.MethodPost)
 
+	router.Handle(APIGeocodingReport, svr.middlewares["moderator"].ThenFunc(svr.getGeocodingReport)).Methods(http.MethodGet)
+	router.Handle(APIGeocodingReport, svr.middlewares["moderator"].ThenFunc(svr.startGeocodingReport)).Methods(http.MethodPost)
+	router.Handle(APIGeocodingCorrections, svr.middlewares["moderator"].ThenFunc(svr.getAllGeocodingCorrections)).Methods(http.MethodGet)
+	router.Handle(APIGeocodingCorrection, svr.middlewares["moderator"].ThenFunc(svr.applyGeocodingCorrection)).Methods(http.MethodPost)
+	router.Handle(APIGeocodingCorrection, svr.middlewares["moderator"].ThenFunc(svr.deleteGeocodingCorrection)).Methods(http.MethodDelete)
+	router.Handle(APIModerationReports, svr.middlewares["moderator"].ThenFunc(svr.getModerationQueue)).Methods(http.MethodGet)
+	router.Handle(APIModerationLog, svr.middlewares["moderator"].ThenFunc(svr.getModerationLog)).Methods(http.MethodGet)
+	router.Handle(APIModerationComment, svr.middlewares["moderator"].ThenFunc(svr.moderateComment)).Methods(http.MethodPost)
+	router.Handle(APICommentHistory, svr.middlewares["moderator"].ThenFunc(svr.getCommentHistory)).Methods(http.MethodGet)
+	router.Handle(APIShadowBans, svr.middlewares["moderator"].ThenFunc(svr.getShadowBans)).Methods(http.MethodGet)
+	router.Handle(APIShadowBans, svr.middlewares["moderator"].ThenFunc(svr.shadowBanUser)).Methods(http.MethodPost)
+	router.Handle(APIShadowBan, svr.middlewares["moderator"].ThenFunc(svr.liftShadowBan)).Methods(http.MethodDelete)
+	router.Handle(APIModerationPhotos, svr.middlewares["moderator"].ThenFunc(svr.getPhotoQueue)).Methods(http.MethodGet)
+	router.Handle(APIModerationPhoto, svr.middlewares["moderator"].ThenFunc(svr.getPendingPhoto)).Methods(http.MethodGet)
+	router.Handle(APIModerationPhoto, svr.middlewares["moderator"].ThenFunc(svr.moderatePhoto)).Methods(http.MethodPost)
+
 	// Comment
 	// GET
-	router.HandleFunc(APIComments, svr.getAllComments).Methods(http.MethodGet)
-	router.HandleFunc(APIComment, svr.getComment).Methods(http.MethodGet)
+	router.Handle(APIComments, svr.middlewares["isLogged"].ThenFunc(svr.getAllComments)).Methods(http.MethodGet)
+	router.HandleFunc(APIConditions, svr.getConditions).Methods(http.MethodGet)
+	router.Handle(APIComment, svr.middlewares["isLogged"].ThenFunc(svr.getComment)).Methods(http.MethodGet)
+	router.Handle(APIReplies, svr.middlewares["isLogged"].ThenFunc(svr.getReplies)).Methods(http.MethodGet)
+	router.HandleFunc(APIPhotos, svr.getPhotos).Methods(http.MethodGet)
+	router.HandleFunc(APIPhoto, svr.getPhoto).Methods(http.MethodGet)
+	router.HandleFunc(APIPhotoThumbnail, svr.getPhotoThumbnail).Methods(http.MethodGet)
 	// POST
-	router.Handle(APIComments, svr.middlewares["authorized"].ThenFunc(svr.addComment)).Methods(http.MethodPost)
+	router.Handle(APIComments, svr.middlewares["authorized"].ThenFunc(svr.rateLimited(&svr.commentLimits, svr.addComment))).Methods(http.MethodPost)
+	router.Handle(APIReplies, svr.middlewares["authorized"].ThenFunc(svr.rateLimited(&svr.commentLimits, svr.addReply))).Methods(http.MethodPost)
+	router.Handle(APICommentReport, svr.middlewares["authorized"].ThenFunc(svr.reportComment)).Methods(http.MethodPost)
+	router.Handle(APIPhotos, svr.middlewares["authorized"].ThenFunc(svr.rateLimited(&svr.submissionLimits, svr.uploadPhoto))).Methods(http.MethodPost)
+	router.Handle(APIRating, svr.middlewares["authorized"].ThenFunc(svr.ratePlayground)).Methods(http.MethodPost)
+	router.Handle(APIConditions, svr.middlewares["authorized"].ThenFunc(svr.addConditionReport)).Methods(http.MethodPost)
 	// DELETE
 	// TODO: Mettre en commun et créer un if method == PUT ou DELETE pour différencier les 2
 	router.Handle(APIComment, svr.middlewares["authorized"].ThenFunc(svr.deleteComment)).Methods(http.MethodDelete)
 	// PUT
 	router.Handle(APIComment, svr.middlewares["authorized"].ThenFunc(svr.modifyComment)).Methods(http.MethodPut)
+	router.Handle(APICommentVote, svr.middlewares["authorized"].ThenFunc(svr.voteComment)).Methods(http.MethodPost)
+	router.Handle(APICommentVote, svr.middlewares["authorized"].ThenFunc(svr.voteComment)).Methods(http.MethodDelete)
 
 	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, URLHome, http.StatusFound)
@@ -128,19 +209,47 @@ func newRouter(svr *PlaygroundServer) *mux.Router {
 	return router
 }
 
+// getAllComments returns the top level comments with their replies nested.
+// They are sorted with the sort parameter, top or new (default).
 func (p *PlaygroundServer) getAllComments(w http.ResponseWriter, r *http.Request) {
 	if playground, err := p.findPlaygroundFromRequestParameter(w, r); err == nil {
-		comments := playground.Comments
-		if len(comments) == 0 {
-			comments = store.Comments{}
+		threads, err := playground.Comments.VisibleTo(requestUsername(r)).Threads(extractCommentsSortFromRequest(r))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("sort parameter should be one of %s, %s", store.SortCommentsByTop, store.SortCommentsByNew), http.StatusBadRequest)
+			return
 		}
-		err = encodeToJson(w, comments)
+		encodeListToJson(w, r, threads, func(i int) int { return threads[i].ID })
+	}
+}
+
+func (p *PlaygroundServer) getReplies(w http.ResponseWriter, r *http.Request) {
+	if playground, err := p.findPlaygroundFromRequestParameter(w, r); err == nil {
+		commentID, err := extractIDFromRequest(r, "commentID")
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		threads, err := playground.Comments.VisibleTo(requestUsername(r)).Replies(commentID, extractCommentsSortFromRequest(r))
+		switch err {
+		case nil:
+		case store.ErrorNotFoundComment:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		default:
+			http.Error(w, fmt.Sprintf("sort parameter should be one of %s, %s", store.SortCommentsByTop, store.SortCommentsByNew), http.StatusBadRequest)
+			return
 		}
+		encodeListToJson(w, r, threads, func(i int) int { return threads[i].ID })
 	}
 }
 
+func extractCommentsSortFromRequest(r *http.Request) string {
+	if sortKey := r.URL.Query().Get("sort"); sortKey != "" {
+		return sortKey
+	}
+	return store.SortCommentsByNew
+}
+
 func (p *PlaygroundServer) getComment(w http.ResponseWriter, r *http.Request) {
 	if playground, err := p.findPlaygroundFromRequestParameter(w, r); err == nil {
 		commentID, err := extractIDFromRequest(r, "commentID")
@@ -148,7 +257,7 @@ func (p *PlaygroundServer) getComment(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		comment, err := playground.FindComment(commentID)
-		if err != nil {
+		if err != nil || (comment.Shadowed && comment.Author != requestUsername(r)) {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
@@ -180,6 +289,12 @@ func (p *PlaygroundServer) addComment(w http.ResponseWriter, r *http.Request) {
 			Content:          strings.TrimSpace(r.FormValue("comment")),
 			Author:           username,
 			TimeOfSubmission: time.Now(),
+			Shadowed:         p.database.Moderation.IsShadowBanned(username),
+		}
+		err = p.spamFilter.Check(username, newComment.Content, newComment.TimeOfSubmission)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
 		err = p.database.MainPlaygroundStore.AddComment(ID, newComment)
@@ -189,11 +304,108 @@ func (p *PlaygroundServer) addComment(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		p.spamFilter.Record(username, newComment.Content, newComment.TimeOfSubmission)
 		w.WriteHeader(http.StatusAccepted)
 	} else {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+func (p *PlaygroundServer) addReply(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	commentID, err := extractIDFromRequest(r, "commentID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	reply := store.Comment{
+		Content:          strings.TrimSpace(r.FormValue("comment")),
+		Author:           claims.Username,
+		TimeOfSubmission: time.Now(),
+		ParentID:         commentID,
+		Shadowed:         p.database.Moderation.IsShadowBanned(claims.Username),
+	}
+	playground, err := p.database.MainPlaygroundStore.Playground(ID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if parent, err := playground.FindComment(commentID); err != nil || parent.Deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	err = p.spamFilter.Check(claims.Username, reply.Content, reply.TimeOfSubmission)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = p.database.MainPlaygroundStore.AddComment(ID, reply)
+	switch err {
+	case nil:
+		p.spamFilter.Record(claims.Username, reply.Content, reply.TimeOfSubmission)
+		w.WriteHeader(http.StatusAccepted)
+	case store.ErrorNotFoundPlayground, store.ErrorNotFoundComment:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		log.Printf("Problème à l'ajout de la réponse, %s", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+type Rating struct {
+	Rating        int     `json:"rating"`
+	AverageRating float64 `json:"average_rating"`
+	RatingsCount  int     `json:"ratings_count"`
+}
+
+// ratePlayground sets the rating of the user, sending a new rating replaces the previous one.
+func (p *PlaygroundServer) ratePlayground(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rating, err := strconv.Atoi(r.FormValue("rating"))
+	if err != nil {
+		http.Error(w, store.ErrorInvalidRating.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playground, err := p.database.MainPlaygroundStore.RatePlayground(ID, claims.Username, rating)
+	switch err {
+	case nil:
+	case store.ErrorNotFoundPlayground:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case store.ErrorInvalidRating:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	default:
+		log.Printf("Problème à l'ajout de la note, %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = encodeToJson(w, Rating{Rating: rating, AverageRating: playground.AverageRating, RatingsCount: playground.RatingsCount})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func (p *PlaygroundServer) deleteComment(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value("claims").(*authentication.Claims)
 	if ok {
@@ -221,6 +433,54 @@ func (p *PlaygroundServer) deleteComment(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// voteComment takes vote=up or vote=down, a DELETE request removes the vote of the user.
+// It returns the comment with its updated score.
+func (p *PlaygroundServer) voteComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*authentication.Claims)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	playgroundID, err := extractIDFromRequest(r, "ID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	commentID, err := extractIDFromRequest(r, "commentID")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	vote := 0
+	if r.Method != http.MethodDelete {
+		switch r.FormValue("vote") {
+		case "up":
+			vote = 1
+		case "down":
+			vote = -1
+		default:
+			http.Error(w, "vote parameter should be up or down", http.StatusBadRequest)
+			return
+		}
+	}
+
+	comment, err := p.database.MainPlaygroundStore.VoteComment(playgroundID, commentID, claims.Username, vote)
+	switch err {
+	case nil:
+	case store.ErrorNotFoundPlayground, store.ErrorNotFoundComment:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	default:
+		log.Printf("Problème au vote sur le commentaire, %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = encodeToJson(w, comment)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func (p *PlaygroundServer) modifyComment(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value("claims").(*authentication.Claims)
 	if ok {
@@ -243,9 +503,14 @@ func (p *PlaygroundServer) modifyComment(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		updatedComment.Content = strings.TrimSpace(updatedComment.Content)
-		updatedComment.TimeOfSubmission = time.Now()
+		updatedComment.TimeOfEdit = time.Now()
 		updatedComment.ID = commentID
 		updatedComment.Author = claims.Username
+		err = p.spamFilter.CheckContent(updatedComment.Content)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		err = p.database.MainPlaygroundStore.UpdateComment(playgroundID, updatedComment)
 		if err != nil {
@@ -326,6 +591,12 @@ func (p *PlaygroundServer) playgroundHandler(w http.ResponseWriter, r *http.Requ
 	case store.ErrorNotFoundPlayground:
 		p.renderView(w, r, "404", nil)
 	case nil:
+		playground.Comments = playground.Comments.VisibleTo(requestUsername(r))
+		if comments, err := playground.Comments.Sort(r.URL.Query().Get("sort")); err == nil {
+			playground.Comments = comments
+		} else {
+			playground.Comments, _ = playground.Comments.Sort(store.SortCommentsByNew)
+		}
 		p.renderView(w, r, "playground", playground)
 	default:
 		p.renderView(w, r, "internal error", nil)
@@ -341,22 +612,78 @@ func logoutHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, URLHome, http.StatusFound)
 }
 
+type Viewport struct {
+	Playgrounds store.Playgrounds `json:"playgrounds"`
+	Clusters    store.Clusters    `json:"clusters"`
+}
+
 func (p *PlaygroundServer) getAllPlaygrounds(w http.ResponseWriter, r *http.Request) {
-	err := encodeToJson(w, p.database.MainPlaygroundStore.AllPlaygrounds())
+	if _, ok := r.URL.Query()["bbox"]; ok {
+		p.getPlaygroundsInViewport(w, r)
+		return
+	}
+	playgrounds, err := filterAndSortFromRequest(r, p.database.MainPlaygroundStore.AllPlaygrounds())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	playgrounds = visibleComments(r, playgrounds)
+	encodeListToJson(w, r, playgrounds, func(i int) int { return playgrounds[i].ID })
+}
+
+// getPlaygroundFacets takes the same filters as getAllPlaygrounds.
+func (p *PlaygroundServer) getPlaygroundFacets(w http.ResponseWriter, r *http.Request) {
+	filter, err := extractFilterFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = encodeToJson(w, p.database.MainPlaygroundStore.AllPlaygrounds().Facets(filter))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
-func (p *PlaygroundServer) getAllSubmittedPlaygrounds(w http.ResponseWriter, r *http.Request) {
-	err := encodeToJson(w, p.database.SubmittedPlaygroundStore.AllPlaygrounds())
+func (p *PlaygroundServer) getPlaygroundsInViewport(w http.ResponseWriter, r *http.Request) {
+	box, err := extractBoundingBoxFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	zoom := store.MaxClusteringZoom + 1
+	if zoomParameter := r.URL.Query().Get("zoom"); zoomParameter != "" {
+		zoom, err = strconv.Atoi(zoomParameter)
+		if err != nil || zoom < 0 || zoom > maxZoom {
+			http.Error(w, fmt.Sprintf("zoom parameter should be a number between 0 and %d, got %q", maxZoom, zoomParameter), http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter, err := extractFilterFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playgrounds, clusters := p.database.MainPlaygroundStore.PlaygroundsWithin(box).Filter(filter).Cluster(zoom)
+	err = encodeToJson(w, Viewport{Playgrounds: visibleComments(r, playgrounds), Clusters: clusters})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
+func (p *PlaygroundServer) getAllSubmittedPlaygrounds(w http.ResponseWriter, r *http.Request) {
+	playgrounds, err := filterAndSortFromRequest(r, p.database.SubmittedPlaygroundStore.AllPlaygrounds())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	encodeListToJson(w, r, playgrounds, func(i int) int { return playgrounds[i].ID })
+}
+
 func (p *PlaygroundServer) getPlayground(w http.ResponseWriter, r *http.Request) {
 	if playground, err := p.findPlaygroundFromRequestParameter(w, r); err == nil {
+		playground.Comments = playground.Comments.VisibleTo(requestUsername(r))
 		err = encodeToJson(w, playground)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -365,19 +692,63 @@ func (p *PlaygroundServer) getPlayground(w http.ResponseWriter, r *http.Request)
 }
 
 func (p *PlaygroundServer) getNearestPlaygrounds(w http.ResponseWriter, r *http.Request) {
-	address, err := extractAddressFromRequest(r)
+	radius, limit, err := extractRadiusAndLimitFromRequest(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	sortKey := r.URL.Query().Get("sort")
+	if sortKey == "" {
+		sortKey = store.SortByDistance
+	}
+	if sortKey != store.SortByDistance && sortKey != store.SortByRating {
+		http.Error(w, fmt.Sprintf("sort parameter should be one of %s, %s, got %q", store.SortByDistance, store.SortByRating, sortKey), http.StatusBadRequest)
+		return
+	}
+
+	var long, lat float64
+	if hasCoordinatesInRequest(r) {
+		long, lat, err = extractCoordinatesFromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		var address string
+		address, err = extractAddressFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		long, lat, err = p.apiClient.GetLongAndLat(r.Context(), address)
+		if err != nil {
+			log.Printf("Couldn't get longitude and lattitude, %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
 
-	nearestPlaygrounds, err := p.database.MainPlaygroundStore.AllPlaygrounds().FindNearestPlaygrounds(p.apiClient, address)
+	openAt, err := extractOpenAtFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var nearestPlaygrounds store.NearbyPlaygrounds
+	if openAt.IsZero() {
+		nearestPlaygrounds = p.database.MainPlaygroundStore.NearestPlaygrounds(long, lat, radius, limit)
+	} else {
+		nearestPlaygrounds = p.database.MainPlaygroundStore.NearestOpenPlaygrounds(long, lat, radius, limit, openAt)
+	}
+	nearestPlaygrounds, err = nearestPlaygrounds.Sort(sortKey)
 	if err != nil {
+		log.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	if len(nearestPlaygrounds) > 10 {
-		nearestPlaygrounds = nearestPlaygrounds[:10]
+	username := requestUsername(r)
+	for i := range nearestPlaygrounds {
+		nearestPlaygrounds[i].Comments = nearestPlaygrounds[i].Comments.VisibleTo(username)
 	}
 	err = encodeToJson(w, nearestPlaygrounds)
 	if err != nil {
@@ -386,6 +757,86 @@ func (p *PlaygroundServer) getNearestPlaygrounds(w http.ResponseWriter, r *http.
 	}
 }
 
+func (p *PlaygroundServer) reverseGeocode(w http.ResponseWriter, r *http.Request) {
+	long, lat, err := extractCoordinatesFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	location, err := p.apiClient.ReverseGeocode(r.Context(), long, lat)
+	switch err {
+	case nil:
+	case store.ErrorNotFoundLocation:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	default:
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = encodeToJson(w, location)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (p *PlaygroundServer) getAddressSuggestions(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q parameter is required", http.StatusBadRequest)
+		return
+	}
+	limit := defaultSuggestionsLimit
+	if limitParameter := r.URL.Query().Get("limit"); limitParameter != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParameter)
+		if err != nil || limit < 1 || limit > maxSuggestionsLimit {
+			http.Error(w, fmt.Sprintf("limit parameter should be a number between 1 and %d", maxSuggestionsLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestions := []store.Location{}
+	if len([]rune(query)) >= minSuggestionQueryLength {
+		var err error
+		suggestions, err = p.suggestions.suggest(r.Context(), p.apiClient, query, limit)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	err := encodeToJson(w, suggestions)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// search looks for q in the playgrounds and their comments, the last word of q is completed so it can be called as the user types.
+func (p *PlaygroundServer) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		http.Error(w, "q parameter is required", http.StatusBadRequest)
+		return
+	}
+	limit := defaultSearchLimit
+	if limitParameter := r.URL.Query().Get("limit"); limitParameter != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParameter)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit parameter should be a number between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results := p.database.MainPlaygroundStore.Search(query, limit)
+	err := encodeToJson(w, results)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func (p *PlaygroundServer) deleteSubmittedPlayground(w http.ResponseWriter, r *http.Request) {
 	ID, err := extractIDFromRequest(r, "ID")
 
@@ -444,6 +895,7 @@ func (p *PlaygroundServer) addPlayground(w http.ResponseWriter, r *http.Request)
 		Lat:              latitude,
 		Coating:          formValues["coating"],
 		Type:             formValues["type"],
+		OpeningHours:     formValues["opening_hours"],
 		Author:           submittedPlayground.Author,
 		TimeOfSubmission: submittedPlayground.TimeOfSubmission,
 	}
@@ -482,9 +934,25 @@ func (p *PlaygroundServer) submitPlayground(w http.ResponseWriter, r *http.Reque
 			PostalCode:       formValues["postal_code"],
 			City:             formValues["city"],
 			Department:       formValues["department"],
+			OpeningHours:     formValues["opening_hours"],
 			Author:           username,
 			TimeOfSubmission: time.Now(),
 		}
+		err := p.spamFilter.CheckContent(newPlayground.Name + "\n" + newPlayground.Address)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if p.database.Moderation.IsShadowBanned(username) {
+			log.Printf("Terrain soumis par %q ignoré, l'utilisateur est banni", username)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		err = newPlayground.Geolocate(r.Context(), p.apiClient)
+		if err != nil {
+			log.Println(err)
+		}
 
 		errorsMap := p.database.SubmitPlayground(newPlayground)
 		if len(errorsMap) > 0 {
@@ -510,6 +978,80 @@ func extractAddressFromRequest(r *http.Request) (string, error) {
 	return address[0], nil
 }
 
+func extractRadiusAndLimitFromRequest(r *http.Request) (float64, int, error) {
+	queryStrings := r.URL.Query()
+	var radius float64
+	if radiusParameter := queryStrings.Get("radius"); radiusParameter != "" {
+		var err error
+		radius, err = strconv.ParseFloat(radiusParameter, 64)
+		if err != nil || radius <= 0 {
+			return 0, 0, fmt.Errorf("radius parameter should be a positive number of metres, got %q", radiusParameter)
+		}
+	}
+	limit := defaultNearestPlaygroundsLimit
+	if limitParameter := queryStrings.Get("limit"); limitParameter != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParameter)
+		if err != nil || limit < 1 || limit > maxNearestPlaygroundsLimit {
+			return 0, 0, fmt.Errorf("limit parameter should be a number between 1 and %d, got %q", maxNearestPlaygroundsLimit, limitParameter)
+		}
+	}
+	return radius, limit, nil
+}
+
+func extractBoundingBoxFromRequest(r *http.Request) (store.BoundingBox, error) {
+	bboxParameter := r.URL.Query().Get("bbox")
+	values := strings.Split(bboxParameter, ",")
+	if len(values) != 4 {
+		return store.BoundingBox{}, fmt.Errorf("bbox parameter should be minLong,minLat,maxLong,maxLat, got %q", bboxParameter)
+	}
+	var coordinates [4]float64
+	for i, value := range values {
+		coordinate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return store.BoundingBox{}, fmt.Errorf("bbox parameter should only contain numbers, got %q", bboxParameter)
+		}
+		coordinates[i] = coordinate
+	}
+	box := store.BoundingBox{MinLong: coordinates[0], MinLat: coordinates[1], MaxLong: coordinates[2], MaxLat: coordinates[3]}
+	if box.MinLong < -180 || box.MaxLong > 180 || box.MinLat < -90 || box.MaxLat > 90 {
+		return store.BoundingBox{}, fmt.Errorf("bbox parameter is out of range, got %q", bboxParameter)
+	}
+	if box.MinLong > box.MaxLong || box.MinLat > box.MaxLat {
+		return store.BoundingBox{}, fmt.Errorf("bbox parameter minimums should be lower than maximums, got %q", bboxParameter)
+	}
+	return box, nil
+}
+
+func hasCoordinatesInRequest(r *http.Request) bool {
+	queryStrings := r.URL.Query()
+	_, hasLat := queryStrings["lat"]
+	_, hasLong := queryStrings["long"]
+	return hasLat || hasLong
+}
+
+func extractCoordinatesFromRequest(r *http.Request) (float64, float64, error) {
+	queryStrings := r.URL.Query()
+	if queryStrings.Get("lat") == "" || queryStrings.Get("long") == "" {
+		return 0, 0, fmt.Errorf("lat and long parameters are required")
+	}
+	lat, err := strconv.ParseFloat(queryStrings.Get("lat"), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("lat parameter should be a number, got %q", queryStrings.Get("lat"))
+	}
+	long, err := strconv.ParseFloat(queryStrings.Get("long"), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("long parameter should be a number, got %q", queryStrings.Get("long"))
+	}
+	if lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("lat parameter should be between -90 and 90, got %v", lat)
+	}
+	if long < -180 || long > 180 {
+		return 0, 0, fmt.Errorf("long parameter should be between -180 and 180, got %v", long)
+	}
+	return long, lat, nil
+}
+
 func extractIDFromRequest(r *http.Request, parameter string) (int, error) {
 	vars := mux.Vars(r)
 	ID, err := strconv.Atoi(vars[parameter])
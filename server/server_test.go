@@ -1,14 +1,20 @@
 package server_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
+	"image/png"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -16,6 +22,7 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/yousseffarkhani/playground/backend2/authentication"
 	"github.com/yousseffarkhani/playground/backend2/configuration"
+	"github.com/yousseffarkhani/playground/backend2/middleware"
 	"github.com/yousseffarkhani/playground/backend2/server"
 
 	"github.com/yousseffarkhani/playground/backend2/store"
@@ -66,6 +73,7 @@ var dummyMiddlewares = map[string]server.Middleware{
 	"isLogged":   &mockMiddleware{},
 	"refresh":    &mockMiddleware{},
 	"authorized": &mockMiddleware{},
+	"moderator":  &mockMiddleware{},
 }
 
 type mockPlaygroundStore struct {
@@ -90,12 +98,138 @@ func (m *mockPlaygroundStore) NewPlayground(newPlayground store.Playground) {
 func (m *mockPlaygroundStore) DeletePlayground(ID int) {
 }
 
-type mockGeolocationClient struct{}
+func (m *mockPlaygroundStore) UpdatePlayground(updatedPlayground store.Playground) error {
+	_, index, err := m.playgrounds.Find(updatedPlayground.ID)
+	if err != nil {
+		return err
+	}
+	m.playgrounds[index] = updatedPlayground
+	return nil
+}
+
+func (m *mockPlaygroundStore) NearestPlaygrounds(long, lat, radius float64, limit int) store.NearbyPlaygrounds {
+	return m.playgrounds.Nearby(long, lat, radius, limit)
+}
+
+func (m *mockPlaygroundStore) NearestOpenPlaygrounds(long, lat, radius float64, limit int, t time.Time) store.NearbyPlaygrounds {
+	return m.playgrounds.Nearby(long, lat, radius, 0).OpenAt(t, limit)
+}
+
+func (m *mockPlaygroundStore) PlaygroundsWithin(box store.BoundingBox) store.Playgrounds {
+	return m.playgrounds.Within(box)
+}
+
+func (m *mockPlaygroundStore) RatePlayground(playgroundID int, username string, rating int) (store.Playground, error) {
+	_, index, err := m.playgrounds.Find(playgroundID)
+	if err != nil {
+		return store.Playground{}, err
+	}
+	err = m.playgrounds[index].Rate(username, rating)
+	if err != nil {
+		return store.Playground{}, err
+	}
+	return m.playgrounds[index], nil
+}
+
+func (m *mockPlaygroundStore) AddConditionReport(playgroundID int, report store.ConditionReport) error {
+	_, index, err := m.playgrounds.Find(playgroundID)
+	if err != nil {
+		return err
+	}
+	return m.playgrounds[index].AddConditionReport(report)
+}
+
+func (m *mockPlaygroundStore) AddPhoto(playgroundID int, photo store.Photo) (store.Photo, error) {
+	_, index, err := m.playgrounds.Find(playgroundID)
+	if err != nil {
+		return store.Photo{}, err
+	}
+	return m.playgrounds[index].AddPhoto(photo)
+}
+
+func (m *mockPlaygroundStore) SetPhotoStatus(playgroundID, photoID int, status string) (store.Photo, error) {
+	_, index, err := m.playgrounds.Find(playgroundID)
+	if err != nil {
+		return store.Photo{}, err
+	}
+	return m.playgrounds[index].SetPhotoStatus(photoID, status)
+}
+
+func (m *mockPlaygroundStore) VoteComment(playgroundID, commentID int, username string, vote int) (store.Comment, error) {
+	_, index, err := m.playgrounds.Find(playgroundID)
+	if err != nil {
+		return store.Comment{}, err
+	}
+	return m.playgrounds[index].Vote(commentID, username, vote)
+}
+
+func (m *mockPlaygroundStore) RemoveComment(playgroundID, commentID int) error {
+	_, index, err := m.playgrounds.Find(playgroundID)
+	if err != nil {
+		return err
+	}
+	if _, err := m.playgrounds[index].FindComment(commentID); err != nil {
+		return err
+	}
+	return m.playgrounds[index].DeleteComment(commentID)
+}
+
+func (m *mockPlaygroundStore) HideComment(playgroundID, commentID int, hidden bool) error {
+	_, index, err := m.playgrounds.Find(playgroundID)
+	if err != nil {
+		return err
+	}
+	return m.playgrounds[index].HideComment(commentID, hidden)
+}
+
+func (m *mockPlaygroundStore) Search(query string, limit int) store.SearchResults {
+	return m.playgrounds.Search(query, limit)
+}
+
+type mockGeolocationClient struct {
+	suggestionsCalls int
+}
 
-func (m *mockGeolocationClient) GetLongAndLat(address string) (long, lat float64, err error) {
+func (m *mockGeolocationClient) GetLongAndLat(ctx context.Context, address string) (long, lat float64, err error) {
 	return 2.372452, 48.886835, nil
 }
 
+func (m *mockGeolocationClient) Geocode(ctx context.Context, address string) (store.Location, error) {
+	return store.Location{
+		Long:  2.372452,
+		Lat:   48.886835,
+		Score: 0.9,
+		Label: address,
+	}, nil
+}
+
+func (m *mockGeolocationClient) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	if long == 0 && lat == 0 {
+		return store.Location{}, store.ErrorNotFoundLocation
+	}
+	return store.Location{
+		Long:       long,
+		Lat:        lat,
+		Score:      0.9,
+		Address:    "42 Avenue de Flandre",
+		PostalCode: "75019",
+		City:       "Paris",
+		Department: "Paris",
+	}, nil
+}
+
+func (m *mockGeolocationClient) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	m.suggestionsCalls++
+	suggestions := []store.Location{
+		{Label: "42 Avenue de Flandre 75019 Paris", PostalCode: "75019", City: "Paris", Long: 2.372452, Lat: 48.886835, Score: 0.9},
+		{Label: "42 Avenue de Flandre 59170 Croix", PostalCode: "59170", City: "Croix", Long: 3.15, Lat: 50.67, Score: 0.5},
+	}
+	if limit < len(suggestions) {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
 func TestAPIs(t *testing.T) {
 	// Arrange
 	playground1.ID = 1
@@ -108,6 +242,7 @@ func TestAPIs(t *testing.T) {
 	client := &mockGeolocationClient{}
 
 	svr := server.New(str, client, nil, dummyMiddlewares)
+	svr.UseRateLimits(server.RateLimits{})
 
 	t.Run("Playground APIs : ", func(t *testing.T) {
 		t.Run(server.APIPlaygrounds, func(t *testing.T) {
@@ -143,6 +278,132 @@ func TestAPIs(t *testing.T) {
 				})
 			}
 		})
+		t.Run(server.APIPlaygrounds+"?bbox=", func(t *testing.T) {
+			t.Run("Returns only the playgrounds in the bounding box", func(t *testing.T) {
+				req := test.NewGetRequest(t, server.APIPlaygrounds+"?bbox=2.35,48.85,2.37,48.86&zoom=16")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusOK)
+				assertHeader(t, res, "Content-Type", server.JsonContentType)
+				var got server.Viewport
+				err := json.NewDecoder(res.Body).Decode(&got)
+				if err != nil {
+					t.Fatalf("Unable to parse response into viewport, '%v'", err)
+				}
+				test.AssertPlaygrounds(t, got.Playgrounds, store.Playgrounds{playground1})
+				if len(got.Clusters) != 0 {
+					t.Errorf("There shouldn't be clusters, got %v", got.Clusters)
+				}
+			})
+			t.Run("Returns clusters at low zoom", func(t *testing.T) {
+				req := test.NewGetRequest(t, server.APIPlaygrounds+"?bbox=-5,41,10,51&zoom=5")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				var got server.Viewport
+				err := json.NewDecoder(res.Body).Decode(&got)
+				if err != nil {
+					t.Fatalf("Unable to parse response into viewport, '%v'", err)
+				}
+				if len(got.Playgrounds) != 0 || len(got.Clusters) != 1 || got.Clusters[0].Count != 2 {
+					t.Errorf("Playgrounds should be clustered, got %v", got)
+				}
+			})
+			t.Run("Returns bad request if bbox or zoom are invalid", func(t *testing.T) {
+				cases := []string{
+					"?bbox=",
+					"?bbox=2.35,48.85,2.37",
+					"?bbox=a,48.85,2.37,48.86",
+					"?bbox=2.37,48.85,2.35,48.86",
+					"?bbox=-200,48.85,2.37,48.86",
+					"?bbox=2.35,48.85,2.37,48.86&zoom=a",
+					"?bbox=2.35,48.85,2.37,48.86&zoom=30",
+				}
+				for _, query := range cases {
+					req := test.NewGetRequest(t, server.APIPlaygrounds+query)
+					res := httptest.NewRecorder()
+
+					svr.ServeHTTP(res, req)
+
+					assertStatusCode(t, res, http.StatusBadRequest)
+				}
+			})
+		})
+		t.Run(server.APIPlaygrounds+" filters and sort", func(t *testing.T) {
+			cases := map[string]store.Playgrounds{
+				"?q=42+Flandre": {playground1},
+				"?q=flandre":    {playground1, playground2},
+				"?q=flandre&sort=distance&long=2.31&lat=48.85": {playground2, playground1},
+				"?city=Lyon":              {},
+				"?open=false&sort=newest": {playground1, playground2},
+			}
+			for query, want := range cases {
+				req := test.NewGetRequest(t, server.APIPlaygrounds+query)
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusOK)
+				got, err := store.NewPlaygroundsFromJSON(res.Body)
+				if err != nil {
+					t.Fatalf("Unable to parse response into slice, '%v'", err)
+				}
+				test.AssertPlaygrounds(t, got, want)
+			}
+			t.Run("Returns bad request if a parameter is invalid", func(t *testing.T) {
+				cases := []string{
+					"?open=maybe",
+					"?sort=size",
+					"?sort=distance",
+					"?sort=distance&long=2.31",
+				}
+				for _, query := range cases {
+					req := test.NewGetRequest(t, server.APIPlaygrounds+query)
+					res := httptest.NewRecorder()
+
+					svr.ServeHTTP(res, req)
+
+					assertStatusCode(t, res, http.StatusBadRequest)
+				}
+			})
+		})
+		t.Run(server.APIPlaygroundFacets, func(t *testing.T) {
+			t.Run("Counts the filtered playgrounds", func(t *testing.T) {
+				req := test.NewGetRequest(t, server.APIPlaygroundFacets+"?q=42+Flandre")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusOK)
+				assertHeader(t, res, "Content-Type", server.JsonContentType)
+				var got store.Facets
+				err := json.NewDecoder(res.Body).Decode(&got)
+				if err != nil {
+					t.Fatalf("Unable to parse response into facets, '%v'", err)
+				}
+				want := store.Facets{
+					Types:       []store.FacetValue{},
+					Coatings:    []store.FacetValue{},
+					Departments: []store.FacetValue{},
+					Cities:      []store.FacetValue{},
+					Open:        []store.FacetValue{{Value: "false", Count: 1}},
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Got %v, want %v", got, want)
+				}
+			})
+			t.Run("Returns bad request if a filter is invalid", func(t *testing.T) {
+				req := test.NewGetRequest(t, server.APIPlaygroundFacets+"?open=maybe")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusBadRequest)
+			})
+		})
 		t.Run(server.APIPlayground, func(t *testing.T) {
 			t.Run("GET", func(t *testing.T) {
 				// Act
@@ -196,6 +457,8 @@ func TestAPIs(t *testing.T) {
 							PostalCode: "75019",
 							City:       "Paris",
 							Department: "Paris",
+							Long:       2.372452,
+							Lat:        48.886835,
 						}
 						mockForm := fmt.Sprintf("name= %s &address= %s &postal_code= %s &city= %s &department= %s ", want.Name, want.Address, want.PostalCode, want.City, want.Department)
 						req := test.NewPostFormRequest(t, server.APISubmittedPlaygrounds, mockForm)
@@ -220,6 +483,46 @@ func TestAPIs(t *testing.T) {
 						}
 						test.AssertPlayground(t, got[0], want)
 					})
+					t.Run(" geocodes the submitted playground", func(t *testing.T) {
+						req := test.NewGetRequest(t, server.APISubmittedPlaygrounds)
+						res := httptest.NewRecorder()
+
+						svr.ServeHTTP(res, req)
+
+						got, err := store.NewPlaygroundsFromJSON(res.Body)
+						if err != nil {
+							t.Fatalf("Unable to parse response into slice, '%v'", err)
+						}
+						if len(got) == 0 {
+							t.Fatalf("Response is empty")
+						}
+						if got[0].Long != 2.372452 || got[0].Lat != 48.886835 {
+							t.Errorf("Playground should be geocoded, got long : %f, lat : %f", got[0].Long, got[0].Lat)
+						}
+						if got[0].GeocodingScore != 0.9 || got[0].LowConfidence {
+							t.Errorf("Geocoding score should be recorded, got %v", got[0])
+						}
+					})
+					t.Run(" can be filtered by moderators", func(t *testing.T) {
+						cases := map[string]int{
+							"?postal_code=75019,75020": 1,
+							"?city=Lyon":               0,
+						}
+						for query, want := range cases {
+							req := test.NewGetRequest(t, server.APISubmittedPlaygrounds+query)
+							res := httptest.NewRecorder()
+
+							svr.ServeHTTP(res, req)
+
+							got, err := store.NewPlaygroundsFromJSON(res.Body)
+							if err != nil {
+								t.Fatalf("Unable to parse response into slice, '%v'", err)
+							}
+							if len(got) != want {
+								t.Errorf("%s : got %d playgrounds, want %d", query, len(got), want)
+							}
+						}
+					})
 					t.Run(" returns bad request", func(t *testing.T) {
 						cases := map[string]store.Playground{
 							" if there is an empty form value": store.Playground{
@@ -300,6 +603,100 @@ func TestAPIs(t *testing.T) {
 
 						test.AssertPlaygrounds(t, got, playgrounds)
 					})
+					t.Run("Returns a list of playgrounds ordered by proximity to lat and long without calling the geocoder", func(t *testing.T) {
+						svr := server.New(str, nil, nil, dummyMiddlewares)
+						req := test.NewGetRequest(t, server.APINearestPlaygrounds+"?lat=48.8533&long=2.31565")
+						res := httptest.NewRecorder()
+
+						svr.ServeHTTP(res, req)
+
+						assertStatusCode(t, res, http.StatusOK)
+						assertHeader(t, res, "Content-Type", server.JsonContentType)
+
+						got, err := store.NewPlaygroundsFromJSON(res.Body)
+						if err != nil {
+							t.Fatalf("Unable to parse response into slice, '%v'", err)
+						}
+
+						test.AssertPlaygrounds(t, got, store.Playgrounds{playground2, playground1})
+					})
+					t.Run("Returns the distance in metres of each playground", func(t *testing.T) {
+						req := test.NewGetRequest(t, server.APINearestPlaygrounds+"?lat=48.8533&long=2.31565")
+						res := httptest.NewRecorder()
+
+						svr.ServeHTTP(res, req)
+
+						var got store.NearbyPlaygrounds
+						err := json.NewDecoder(res.Body).Decode(&got)
+						if err != nil {
+							t.Fatalf("Unable to parse response into slice, '%v'", err)
+						}
+						if len(got) != 2 || got[0].Distance != 0 || got[1].Distance < 3200 || got[1].Distance > 3300 {
+							t.Errorf("Got %v", got)
+						}
+					})
+					t.Run("Filters playgrounds by radius and limits the number of results", func(t *testing.T) {
+						cases := map[string]int{
+							"?lat=48.8533&long=2.31565&radius=1000": 1,
+							"?lat=48.8533&long=2.31565&limit=1":     1,
+							"?lat=48.8533&long=2.31565&radius=5000": 2,
+						}
+						for query, want := range cases {
+							req := test.NewGetRequest(t, server.APINearestPlaygrounds+query)
+							res := httptest.NewRecorder()
+
+							svr.ServeHTTP(res, req)
+
+							assertStatusCode(t, res, http.StatusOK)
+							got, err := store.NewPlaygroundsFromJSON(res.Body)
+							if err != nil {
+								t.Fatalf("Unable to parse response into slice, '%v'", err)
+							}
+							if len(got) != want {
+								t.Errorf("Got %d playgrounds for %q, want %d", len(got), query, want)
+							}
+						}
+					})
+					t.Run("Returns bad request if radius or limit are invalid", func(t *testing.T) {
+						cases := []string{
+							"?lat=48.8533&long=2.31565&radius=-1",
+							"?lat=48.8533&long=2.31565&radius=abc",
+							"?lat=48.8533&long=2.31565&limit=0",
+							"?lat=48.8533&long=2.31565&limit=1000",
+						}
+						for _, query := range cases {
+							req := test.NewGetRequest(t, server.APINearestPlaygrounds+query)
+							res := httptest.NewRecorder()
+
+							svr.ServeHTTP(res, req)
+
+							assertStatusCode(t, res, http.StatusBadRequest)
+						}
+					})
+					t.Run("Returns bad request if lat or long are missing, invalid or out of range", func(t *testing.T) {
+						cases := []string{
+							"?lat=48.8533",
+							"?long=2.31565",
+							"?lat=&long=2.31565",
+							"?lat=abc&long=2.31565",
+							"?lat=48.8533&long=abc",
+							"?lat=90.5&long=2.31565",
+							"?lat=-91&long=2.31565",
+							"?lat=48.8533&long=180.1",
+							"?lat=48.8533&long=-200",
+						}
+						for _, query := range cases {
+							req := test.NewGetRequest(t, server.APINearestPlaygrounds+query)
+							res := httptest.NewRecorder()
+
+							svr.ServeHTTP(res, req)
+
+							assertStatusCode(t, res, http.StatusBadRequest)
+							if strings.TrimSpace(res.Body.String()) == "" {
+								t.Errorf("Bad request for %q should explain the error", query)
+							}
+						}
+					})
 					t.Run("Returns bad request if no address parameter in query", func(t *testing.T) {
 						req := test.NewGetRequest(t, server.APINearestPlaygrounds+"?test=42 Avenue de Flandre Paris")
 						res := httptest.NewRecorder()
@@ -319,6 +716,191 @@ func TestAPIs(t *testing.T) {
 				})
 			})
 		})
+		t.Run(server.APIReverseGeocode, func(t *testing.T) {
+			t.Run("Returns the address found at the coordinates", func(t *testing.T) {
+				req := test.NewGetRequest(t, server.APIReverseGeocode+"?lat=48.886835&long=2.372452")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusOK)
+				assertHeader(t, res, "Content-Type", server.JsonContentType)
+
+				var got store.Location
+				err := json.NewDecoder(res.Body).Decode(&got)
+				if err != nil {
+					t.Fatalf("Unable to parse response into location, '%v'", err)
+				}
+				if got.Address != "42 Avenue de Flandre" || got.PostalCode != "75019" || got.City != "Paris" || got.Department != "Paris" {
+					t.Errorf("Got %v", got)
+				}
+			})
+			t.Run("Returns not found if there is no address at the coordinates", func(t *testing.T) {
+				req := test.NewGetRequest(t, server.APIReverseGeocode+"?lat=0&long=0")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusNotFound)
+			})
+			t.Run("Returns bad request if coordinates are missing or invalid", func(t *testing.T) {
+				cases := []string{
+					"",
+					"?lat=48.88",
+					"?lat=aa&long=2.37",
+					"?lat=91&long=2.37",
+					"?lat=48.88&long=-181",
+				}
+				for _, query := range cases {
+					req := test.NewGetRequest(t, server.APIReverseGeocode+query)
+					res := httptest.NewRecorder()
+
+					svr.ServeHTTP(res, req)
+
+					assertStatusCode(t, res, http.StatusBadRequest)
+				}
+			})
+		})
+		t.Run(server.APIAddressSuggestions, func(t *testing.T) {
+			t.Run("Returns ranked address candidates", func(t *testing.T) {
+				req := test.NewGetRequest(t, server.APIAddressSuggestions+"?q=42+avenue+de+Fl")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusOK)
+				assertHeader(t, res, "Content-Type", server.JsonContentType)
+
+				var got []store.Location
+				err := json.NewDecoder(res.Body).Decode(&got)
+				if err != nil {
+					t.Fatalf("Unable to parse response into slice, '%v'", err)
+				}
+				if len(got) != 2 || got[0].Label != "42 Avenue de Flandre 75019 Paris" {
+					t.Errorf("Got %v", got)
+				}
+			})
+			t.Run("Respects the limit parameter", func(t *testing.T) {
+				req := test.NewGetRequest(t, server.APIAddressSuggestions+"?q=42+avenue+de+Fl&limit=1")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				var got []store.Location
+				json.NewDecoder(res.Body).Decode(&got)
+				if len(got) != 1 {
+					t.Errorf("Got %d suggestions, want 1", len(got))
+				}
+			})
+			t.Run("Caches identical queries", func(t *testing.T) {
+				client.suggestionsCalls = 0
+				for _, query := range []string{"?q=10+rue+de+Rivoli", "?q=10+RUE+de++rivoli"} {
+					req := test.NewGetRequest(t, server.APIAddressSuggestions+query)
+					svr.ServeHTTP(httptest.NewRecorder(), req)
+				}
+				if client.suggestionsCalls != 1 {
+					t.Errorf("Geocoder should be called once, got %d calls", client.suggestionsCalls)
+				}
+			})
+			t.Run("Evicts the least recently used query once the cache is full", func(t *testing.T) {
+				suggest := func(query string) {
+					req := test.NewGetRequest(t, server.APIAddressSuggestions+"?q="+url.QueryEscape(query))
+					svr.ServeHTTP(httptest.NewRecorder(), req)
+				}
+				for i := 0; i < 1000; i++ {
+					suggest(fmt.Sprintf("%d rue de Crimée", i))
+				}
+				suggest("0 rue de Crimée")
+				client.suggestionsCalls = 0
+				suggest("rue de Flandre")
+				suggest("rue de Flandre")
+				suggest("0 rue de Crimée")
+				if client.suggestionsCalls != 1 {
+					t.Errorf("New query should be cached, got %d calls", client.suggestionsCalls)
+				}
+				suggest("1 rue de Crimée")
+				if client.suggestionsCalls != 2 {
+					t.Errorf("Least recently used query should be evicted, got %d calls", client.suggestionsCalls)
+				}
+			})
+			t.Run("Returns an empty list without calling the geocoder for short queries", func(t *testing.T) {
+				client.suggestionsCalls = 0
+				req := test.NewGetRequest(t, server.APIAddressSuggestions+"?q=42")
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusOK)
+				if strings.TrimSpace(res.Body.String()) != "[]" {
+					t.Errorf("Got %q, want an empty list", res.Body.String())
+				}
+				if client.suggestionsCalls != 0 {
+					t.Errorf("Geocoder shouldn't be called")
+				}
+			})
+			t.Run("Returns bad request if q is missing or limit is invalid", func(t *testing.T) {
+				cases := []string{"", "?q=", "?q=42+avenue&limit=0", "?q=42+avenue&limit=aa", "?q=42+avenue&limit=100"}
+				for _, query := range cases {
+					req := test.NewGetRequest(t, server.APIAddressSuggestions+query)
+					res := httptest.NewRecorder()
+
+					svr.ServeHTTP(res, req)
+
+					assertStatusCode(t, res, http.StatusBadRequest)
+				}
+			})
+		})
+		t.Run(server.APISearch, func(t *testing.T) {
+			search := func(t *testing.T, query string) store.SearchResults {
+				t.Helper()
+				req := test.NewGetRequest(t, server.APISearch+query)
+				res := httptest.NewRecorder()
+
+				svr.ServeHTTP(res, req)
+
+				assertStatusCode(t, res, http.StatusOK)
+				assertHeader(t, res, "Content-Type", server.JsonContentType)
+				var got store.SearchResults
+				err := json.NewDecoder(res.Body).Decode(&got)
+				if err != nil {
+					t.Fatalf("Unable to parse response into slice, '%v'", err)
+				}
+				return got
+			}
+			t.Run("Returns the matching playgrounds", func(t *testing.T) {
+				got := search(t, "?q=43+Flan")
+
+				if len(got) != 1 || got[0].PlaygroundID != 2 || got[0].Snippet != "<mark>43</mark> avenue de <mark>Flandre</mark>" {
+					t.Errorf("Got %v", got)
+				}
+			})
+			t.Run("Returns the matching comments with highlighted snippets", func(t *testing.T) {
+				got := search(t, "?q=great")
+
+				want := store.SearchResults{{PlaygroundID: 1, CommentID: 1, Name: "test1", Snippet: "<mark>Great</mark> Playground !", Score: got[0].Score}}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Got %v, want %v", got, want)
+				}
+			})
+			t.Run("Respects the limit parameter", func(t *testing.T) {
+				got := search(t, "?q=playground&limit=2")
+
+				if len(got) != 2 {
+					t.Errorf("Got %d results, want 2", len(got))
+				}
+			})
+			t.Run("Returns bad request if q is missing or limit is invalid", func(t *testing.T) {
+				cases := []string{"", "?q=+", "?q=test&limit=0", "?q=test&limit=aa", "?q=test&limit=101"}
+				for _, query := range cases {
+					req := test.NewGetRequest(t, server.APISearch+query)
+					res := httptest.NewRecorder()
+
+					svr.ServeHTTP(res, req)
+
+					assertStatusCode(t, res, http.StatusBadRequest)
+				}
+			})
+		})
 		t.Run("Comments APIs : ", func(t *testing.T) {
 			t.Run(server.APIComments, func(t *testing.T) {
 				cases := map[string]store.Comments{
@@ -649,15 +1231,982 @@ func setupRequestContext(req *http.Request) *http.Request {
 	return req.WithContext(ctx)
 }
 
-type mockView struct {
-	data   server.RenderingData
-	called bool
-}
+func TestPagination(t *testing.T) {
+	playgrounds := store.Playgrounds{}
+	for i := 1; i <= 5; i++ {
+		playgrounds = append(playgrounds, store.Playground{ID: i, Name: fmt.Sprintf("playground %d", i)})
+	}
+	for i := 1; i <= 3; i++ {
+		playgrounds[0].Comments = append(playgrounds[0].Comments, store.Comment{ID: i, Content: fmt.Sprintf("comment %d", i)})
+	}
+	str := &mockPlaygroundStore{playgrounds: playgrounds}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
 
-func (m *mockView) Render(w io.Writer, r *http.Request, data server.RenderingData) error {
-	m.called = true
-	m.data = data
-	return nil
+	getPage := func(t *testing.T, URL string) (*httptest.ResponseRecorder, store.Playgrounds, server.Page) {
+		t.Helper()
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, URL))
+		assertStatusCode(t, res, http.StatusOK)
+		var items store.Playgrounds
+		page := server.Page{Items: &items}
+		err := json.NewDecoder(res.Body).Decode(&page)
+		if err != nil {
+			t.Fatalf("Unable to parse response into page, '%v'", err)
+		}
+		return res, items, page
+	}
+
+	t.Run("Returns a bare list without pagination parameters", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, server.APIPlaygrounds))
+
+		got, err := store.NewPlaygroundsFromJSON(res.Body)
+		if err != nil {
+			t.Fatalf("Unable to parse response into slice, '%v'", err)
+		}
+		test.AssertPlaygrounds(t, got, playgrounds)
+		assertHeader(t, res, "Link", "")
+	})
+	t.Run("Follows cursors until the last page", func(t *testing.T) {
+		URL := server.APIPlaygrounds + "?limit=2"
+		got := store.Playgrounds{}
+		for pages := 0; URL != ""; pages++ {
+			if pages > 3 {
+				t.Fatalf("Too many pages")
+			}
+			res, items, page := getPage(t, URL)
+			if page.Total != 5 {
+				t.Errorf("got total %d, want 5", page.Total)
+			}
+			if page.Next != "" && !strings.Contains(res.Header().Get("Link"), "<"+page.Next+">; rel=\"next\"") {
+				t.Errorf("Link header should point to the next page, got %q", res.Header().Get("Link"))
+			}
+			got = append(got, items...)
+			URL = page.Next
+		}
+		test.AssertPlaygrounds(t, got, playgrounds)
+	})
+	t.Run("Cursors stay valid when a playground is added before them", func(t *testing.T) {
+		_, _, page := getPage(t, server.APIPlaygrounds+"?limit=2")
+		str.playgrounds = append(store.Playgrounds{{ID: 6, Name: "new"}}, str.playgrounds...)
+		defer func() { str.playgrounds = str.playgrounds[1:] }()
+
+		_, items, _ := getPage(t, page.Next)
+
+		if len(items) != 2 || items[0].ID != 3 {
+			t.Errorf("got %v, want playgrounds 3 and 4", items)
+		}
+	})
+	t.Run("Returns numbered pages with first, prev and last links", func(t *testing.T) {
+		res, items, page := getPage(t, server.APIPlaygrounds+"?limit=2&page=3")
+
+		if len(items) != 1 || items[0].ID != 5 || page.Next != "" {
+			t.Errorf("got %v, next %q, want the last playground", items, page.Next)
+		}
+		link := res.Header().Get("Link")
+		for _, want := range []string{`page=1>; rel="first"`, `page=2>; rel="prev"`, `page=3>; rel="last"`} {
+			if !strings.Contains(link, want) {
+				t.Errorf("Link header %q should contain %q", link, want)
+			}
+		}
+	})
+	t.Run("Keeps filters in the next page", func(t *testing.T) {
+		_, items, page := getPage(t, server.APIPlaygrounds+"?q=playground&sort=name&page=1&limit=4")
+
+		if len(items) != 4 || !strings.Contains(page.Next, "q=playground") || !strings.Contains(page.Next, "page=2") {
+			t.Errorf("got %d items, next %q", len(items), page.Next)
+		}
+	})
+	t.Run("Paginates submitted playgrounds and comments", func(t *testing.T) {
+		_, _, page := getPage(t, server.APISubmittedPlaygrounds+"?limit=10")
+		if page.Total != 0 {
+			t.Errorf("got total %d, want 0", page.Total)
+		}
+
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, server.APIPlaygrounds+"/1/comments?limit=2"))
+		var comments store.Comments
+		commentsPage := server.Page{Items: &comments}
+		json.NewDecoder(res.Body).Decode(&commentsPage)
+		if len(comments) != 2 || commentsPage.Total != 3 || commentsPage.Next == "" {
+			t.Errorf("got %v", commentsPage)
+		}
+	})
+	t.Run("Returns bad request if a parameter is invalid", func(t *testing.T) {
+		cases := []string{
+			"?limit=0",
+			"?limit=1000",
+			"?page=0",
+			"?cursor=abc",
+			"?cursor=eyJpZCI6MSwib2Zmc2V0IjoxfQ&page=2",
+		}
+		for _, query := range cases {
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, test.NewGetRequest(t, server.APIPlaygrounds+query))
+
+			assertStatusCode(t, res, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestRatings(t *testing.T) {
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{
+		{ID: 1, Name: "test1", Long: 2.36016, Lat: 48.8532},
+		{ID: 2, Name: "test2", Long: 2.31565, Lat: 48.8533},
+	}}
+	str.playgrounds[0].Rate("Clélia", 2)
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+
+	rate := func(t *testing.T, ID int, rating string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := test.NewPostFormRequest(t, fmt.Sprintf("/api/playgrounds/%d/rating", ID), "rating="+rating)
+		req = setupRequestContext(req)
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("Returns the rating and the updated average", func(t *testing.T) {
+		res := rate(t, 2, "4")
+
+		assertStatusCode(t, res, http.StatusOK)
+		var got server.Rating
+		err := json.NewDecoder(res.Body).Decode(&got)
+		if err != nil {
+			t.Fatalf("Unable to parse response into rating, '%v'", err)
+		}
+		want := server.Rating{Rating: 4, AverageRating: 4, RatingsCount: 1}
+		if got != want {
+			t.Errorf("Got %v, want %v", got, want)
+		}
+	})
+	t.Run("Replaces the previous rating of the user", func(t *testing.T) {
+		rate(t, 1, "3")
+		res := rate(t, 1, "5")
+
+		var got server.Rating
+		json.NewDecoder(res.Body).Decode(&got)
+		want := server.Rating{Rating: 5, AverageRating: 3.5, RatingsCount: 2}
+		if got != want {
+			t.Errorf("Got %v, want %v", got, want)
+		}
+	})
+	t.Run("Returns bad request if the rating is invalid", func(t *testing.T) {
+		for _, rating := range []string{"", "0", "6", "abc"} {
+			res := rate(t, 1, rating)
+
+			assertStatusCode(t, res, http.StatusBadRequest)
+		}
+	})
+	t.Run("Returns not found if the playground doesn't exist", func(t *testing.T) {
+		res := rate(t, 3, "4")
+
+		assertStatusCode(t, res, http.StatusNotFound)
+	})
+	t.Run("Sorts by rating", func(t *testing.T) {
+		cases := map[string]store.Playgrounds{
+			server.APIPlaygrounds + "?sort=rating":                                 {str.playgrounds[1], str.playgrounds[0]},
+			server.APINearestPlaygrounds + "?lat=48.8532&long=2.36016":             {str.playgrounds[0], str.playgrounds[1]},
+			server.APINearestPlaygrounds + "?lat=48.8532&long=2.36016&sort=rating": {str.playgrounds[1], str.playgrounds[0]},
+		}
+		for URL, want := range cases {
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, test.NewGetRequest(t, URL))
+
+			assertStatusCode(t, res, http.StatusOK)
+			got, err := store.NewPlaygroundsFromJSON(res.Body)
+			if err != nil {
+				t.Fatalf("Unable to parse response into slice, '%v'", err)
+			}
+			if len(got) != 2 || got[0].ID != want[0].ID || got[0].AverageRating != want[0].AverageRating {
+				t.Errorf("%s : got %v, want %v", URL, got, want)
+			}
+		}
+	})
+	t.Run("Returns bad request if the nearest sort is unknown", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, server.APINearestPlaygrounds+"?lat=48.8532&long=2.36016&sort=name"))
+
+		assertStatusCode(t, res, http.StatusBadRequest)
+	})
+}
+
+func TestConditions(t *testing.T) {
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{{ID: 1, Name: "test1"}}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+
+	report := func(t *testing.T, ID int, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := test.NewPostFormRequest(t, fmt.Sprintf("/api/playgrounds/%d/conditions", ID), body)
+		req = setupRequestContext(req)
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("Adds a report and returns the current condition", func(t *testing.T) {
+		res := report(t, 1, "surface=2&hoops=5&lighting=")
+		assertStatusCode(t, res, http.StatusAccepted)
+		report(t, 1, "surface=4")
+
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, "/api/playgrounds/1/conditions"))
+
+		assertStatusCode(t, res, http.StatusOK)
+		var got server.PlaygroundCondition
+		err := json.NewDecoder(res.Body).Decode(&got)
+		if err != nil {
+			t.Fatalf("Unable to parse response into condition, '%v'", err)
+		}
+		if len(got.Reports) != 2 || got.Reports[1].Author != "Youssef" || !reflect.DeepEqual(got.Reports[1].Scores, map[string]int{"surface": 2, "hoops": 5}) {
+			t.Errorf("Got reports %v", got.Reports)
+		}
+		if got.Summary.Reports != 2 || len(got.Summary.Criteria) != 2 || got.Summary.Criteria[0].Score != 3 || got.Summary.Criteria[1].Score != 5 {
+			t.Errorf("Got summary %v", got.Summary)
+		}
+	})
+	t.Run("Returns bad request if the report is invalid", func(t *testing.T) {
+		for _, body := range []string{"", "surface=", "surface=0", "surface=abc", "hoops=6"} {
+			res := report(t, 1, body)
+
+			assertStatusCode(t, res, http.StatusBadRequest)
+		}
+	})
+	t.Run("Returns not found if the playground doesn't exist", func(t *testing.T) {
+		res := report(t, 2, "surface=3")
+
+		assertStatusCode(t, res, http.StatusNotFound)
+	})
+}
+
+func TestCommentVotes(t *testing.T) {
+	now := time.Now()
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{{ID: 1, Name: "test1", Comments: store.Comments{
+		{ID: 1, Content: "Great Playground !", Author: "Clélia", TimeOfSubmission: now.Add(-time.Hour)},
+		{ID: 2, Content: "Bad Playground !", Author: "Thibaut", TimeOfSubmission: now},
+	}}}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+
+	vote := func(t *testing.T, req *http.Request) *httptest.ResponseRecorder {
+		t.Helper()
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(req))
+		return res
+	}
+	commentIDs := func(t *testing.T, URL string) []int {
+		t.Helper()
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, URL))
+		assertStatusCode(t, res, http.StatusOK)
+		var comments store.Comments
+		err := json.NewDecoder(res.Body).Decode(&comments)
+		if err != nil {
+			t.Fatalf("Unable to parse response into slice, '%v'", err)
+		}
+		IDs := []int{}
+		for _, comment := range comments {
+			IDs = append(IDs, comment.ID)
+		}
+		return IDs
+	}
+
+	t.Run("Upvotes a comment and returns its score", func(t *testing.T) {
+		res := vote(t, test.NewPostFormRequest(t, "/api/playgrounds/1/comments/1/vote", "vote=up"))
+
+		assertStatusCode(t, res, http.StatusOK)
+		var got store.Comment
+		err := json.NewDecoder(res.Body).Decode(&got)
+		if err != nil {
+			t.Fatalf("Unable to parse response into comment, '%v'", err)
+		}
+		if got.ID != 1 || got.Upvotes != 1 || got.Score != 1 {
+			t.Errorf("Got %v", got)
+		}
+	})
+	t.Run("Sorts comments by score or from the newest", func(t *testing.T) {
+		cases := map[string][]int{
+			"/api/playgrounds/1/comments":          {2, 1},
+			"/api/playgrounds/1/comments?sort=new": {2, 1},
+			"/api/playgrounds/1/comments?sort=top": {1, 2},
+		}
+		for URL, want := range cases {
+			if got := commentIDs(t, URL); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s : got %v, want %v", URL, got, want)
+			}
+		}
+	})
+	t.Run("Removes the vote", func(t *testing.T) {
+		res := vote(t, test.NewDeleteRequest(t, "/api/playgrounds/1/comments/1/vote"))
+
+		assertStatusCode(t, res, http.StatusOK)
+		if str.playgrounds[0].Comments[0].Score != 0 {
+			t.Errorf("Vote wasn't removed, got %v", str.playgrounds[0].Comments[0])
+		}
+	})
+	t.Run("Returns bad request or not found", func(t *testing.T) {
+		cases := map[*http.Request]int{
+			test.NewPostFormRequest(t, "/api/playgrounds/1/comments/1/vote", "vote=sideways"): http.StatusBadRequest,
+			test.NewPostFormRequest(t, "/api/playgrounds/1/comments/3/vote", "vote=up"):       http.StatusNotFound,
+			test.NewPostFormRequest(t, "/api/playgrounds/2/comments/1/vote", "vote=up"):       http.StatusNotFound,
+		}
+		for req, want := range cases {
+			assertStatusCode(t, vote(t, req), want)
+		}
+
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, "/api/playgrounds/1/comments?sort=old"))
+		assertStatusCode(t, res, http.StatusBadRequest)
+	})
+}
+
+func TestReplies(t *testing.T) {
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{{ID: 1, Name: "test1", Comments: store.Comments{
+		{ID: 1, Content: "Great Playground !", Author: "Youssef", TimeOfSubmission: time.Now().Add(-time.Hour)},
+	}}}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+	svr.UseRateLimits(server.RateLimits{})
+
+	reply := func(t *testing.T, URL, content string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := setupRequestContext(test.NewPostFormRequest(t, URL, "comment="+content))
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		return res
+	}
+	getThreads := func(t *testing.T, URL string) []store.CommentThread {
+		t.Helper()
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, URL))
+		assertStatusCode(t, res, http.StatusOK)
+		var threads []store.CommentThread
+		err := json.NewDecoder(res.Body).Decode(&threads)
+		if err != nil {
+			t.Fatalf("Unable to parse response into threads, '%v'", err)
+		}
+		return threads
+	}
+
+	t.Run("Adds a reply under the comment", func(t *testing.T) {
+		res := reply(t, "/api/playgrounds/1/comments/1/replies", "Agreed")
+		assertStatusCode(t, res, http.StatusAccepted)
+		reply(t, "/api/playgrounds/1/comments/2/replies", "Me too")
+
+		threads := getThreads(t, "/api/playgrounds/1/comments")
+		if len(threads) != 1 || len(threads[0].Replies) != 1 || threads[0].Replies[0].Content != "Agreed" ||
+			len(threads[0].Replies[0].Replies) != 1 || threads[0].Replies[0].Replies[0].Author != "Youssef" {
+			t.Errorf("Got %v", threads)
+		}
+
+		replies := getThreads(t, "/api/playgrounds/1/comments/2/replies")
+		if len(replies) != 1 || replies[0].ID != 3 || replies[0].ParentID != 2 {
+			t.Errorf("Got %v", replies)
+		}
+	})
+	t.Run("Keeps the thread when the parent is deleted", func(t *testing.T) {
+		req := setupRequestContext(test.NewDeleteRequest(t, "/api/playgrounds/1/comments/1"))
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		threads := getThreads(t, "/api/playgrounds/1/comments")
+		if len(threads) != 1 || !threads[0].Deleted || threads[0].Content != "[deleted]" || threads[0].Author != "" || len(threads[0].Replies) != 1 {
+			t.Errorf("Got %v", threads)
+		}
+	})
+	t.Run("Returns not found or bad request", func(t *testing.T) {
+		cases := map[string]int{
+			"/api/playgrounds/1/comments/1/replies": http.StatusNotFound,
+			"/api/playgrounds/1/comments/9/replies": http.StatusNotFound,
+			"/api/playgrounds/2/comments/2/replies": http.StatusNotFound,
+			"/api/playgrounds/1/comments/3/replies": http.StatusAccepted,
+		}
+		for URL, want := range cases {
+			assertStatusCode(t, reply(t, URL, "Hello"), want)
+		}
+		assertStatusCode(t, reply(t, "/api/playgrounds/1/comments/4/replies", "Too deep"), http.StatusBadRequest)
+		assertStatusCode(t, reply(t, "/api/playgrounds/1/comments/2/replies", "+"), http.StatusBadRequest)
+
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, "/api/playgrounds/1/comments/9/replies"))
+		assertStatusCode(t, res, http.StatusNotFound)
+	})
+}
+
+func TestModeration(t *testing.T) {
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{{ID: 1, Name: "test1", Comments: store.Comments{
+		{ID: 1, Content: "Achetez mes baskets", Author: "Thibaut", TimeOfSubmission: time.Now()},
+	}}}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+
+	post := func(t *testing.T, URL, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := setupRequestContext(test.NewPostFormRequest(t, URL, body))
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		return res
+	}
+	get := func(t *testing.T, URL string, data interface{}) {
+		t.Helper()
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewGetRequest(t, URL)))
+		assertStatusCode(t, res, http.StatusOK)
+		err := json.NewDecoder(res.Body).Decode(data)
+		if err != nil {
+			t.Fatalf("Unable to parse response, '%v'", err)
+		}
+	}
+
+	t.Run("Reports a comment", func(t *testing.T) {
+		res := post(t, "/api/playgrounds/1/comments/1/report", "reason=spam&details=pub")
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		cases := map[string]int{
+			"/api/playgrounds/1/comments/1/report": http.StatusConflict,
+			"/api/playgrounds/1/comments/9/report": http.StatusNotFound,
+			"/api/playgrounds/9/comments/1/report": http.StatusNotFound,
+		}
+		for URL, want := range cases {
+			assertStatusCode(t, post(t, URL, "reason=spam"), want)
+		}
+		assertStatusCode(t, post(t, "/api/playgrounds/1/comments/1/report", "reason=boring"), http.StatusBadRequest)
+
+		var queue []store.ReportedComment
+		get(t, "/api/moderation/reports", &queue)
+		if len(queue) != 1 || queue[0].Content != "Achetez mes baskets" || queue[0].Reports[0].Reporter != "Youssef" {
+			t.Errorf("Got %v", queue)
+		}
+	})
+	t.Run("Hides a reported comment", func(t *testing.T) {
+		res := post(t, "/api/moderation/playgrounds/1/comments/1", "action=hide&reason=publicité")
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		var comment store.Comment
+		get(t, "/api/playgrounds/1/comments/1", &comment)
+		if !comment.Hidden || comment.Content != store.HiddenCommentContent || comment.Author != "" {
+			t.Errorf("Got %v", comment)
+		}
+		var queue []store.ReportedComment
+		get(t, "/api/moderation/reports", &queue)
+		if len(queue) != 0 {
+			t.Errorf("Got %v", queue)
+		}
+	})
+	t.Run("Deletes a comment the moderator didn't write", func(t *testing.T) {
+		res := post(t, "/api/moderation/playgrounds/1/comments/1", "action=delete")
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, "/api/playgrounds/1/comments/1"))
+		assertStatusCode(t, res, http.StatusNotFound)
+	})
+	t.Run("Returns not found or bad request", func(t *testing.T) {
+		assertStatusCode(t, post(t, "/api/moderation/playgrounds/1/comments/1", "action=hide"), http.StatusNotFound)
+		assertStatusCode(t, post(t, "/api/moderation/playgrounds/9/comments/1", "action=hide"), http.StatusNotFound)
+		str.AddComment(1, store.Comment{Content: "Hello", Author: "Thibaut"})
+		assertStatusCode(t, post(t, "/api/moderation/playgrounds/1/comments/1", "action=ban"), http.StatusBadRequest)
+	})
+	t.Run("Lists the moderation actions", func(t *testing.T) {
+		var log []store.ModerationAction
+		get(t, "/api/moderation/log", &log)
+		if len(log) != 2 || log[0].Action != store.ModerationDelete || log[1].Reason != "publicité" || log[1].Moderator != "Youssef" {
+			t.Errorf("Got %v", log)
+		}
+	})
+}
+
+func TestAntiSpam(t *testing.T) {
+	configuration.LoadEnvVariables()
+	// The real isLogged middleware reads the JWT cookie of the GET requests.
+	middlewares := map[string]server.Middleware{}
+	for name, m := range dummyMiddlewares {
+		middlewares[name] = m
+	}
+	middlewares["isLogged"] = middleware.Initialize()["isLogged"]
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{{ID: 1, Name: "test1"}}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, middlewares)
+	svr.UseRateLimits(server.RateLimits{})
+	svr.UseSpamFilter(store.NewSpamFilter([]string{"viagra"}))
+
+	requestAs := func(req *http.Request, username string) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), "claims", &authentication.Claims{Username: username}))
+	}
+	withCookie := func(req *http.Request, username string) *http.Request {
+		res := httptest.NewRecorder()
+		authentication.SetJwtCookie(res, username)
+		for _, cookie := range res.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+	comment := func(t *testing.T, username, content string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := requestAs(test.NewPostFormRequest(t, "/api/playgrounds/1/comments", "comment="+content), username)
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("Rejects spam content", func(t *testing.T) {
+		assertStatusCode(t, comment(t, "Clélia", "viagra"), http.StatusBadRequest)
+		assertStatusCode(t, comment(t, "Clélia", "http://a.fr http://b.fr http://c.fr"), http.StatusBadRequest)
+	})
+	t.Run("Limits the comments per user", func(t *testing.T) {
+		svr.UseRateLimits(server.RateLimits{CommentsPerUser: server.RateLimit{Requests: 2, Window: time.Minute}})
+		assertStatusCode(t, comment(t, "Youssef", "Premier"), http.StatusAccepted)
+		assertStatusCode(t, comment(t, "Youssef", "Premier"), http.StatusBadRequest)
+
+		res := comment(t, "Youssef", "Troisième")
+		assertStatusCode(t, res, http.StatusTooManyRequests)
+		assertHeader(t, res, "Retry-After", "60")
+		assertStatusCode(t, comment(t, "Clélia", "Bonjour"), http.StatusAccepted)
+	})
+	t.Run("Limits the comments per IP address", func(t *testing.T) {
+		svr.UseRateLimits(server.RateLimits{CommentsPerIP: server.RateLimit{Requests: 2, Window: time.Minute}})
+		assertStatusCode(t, comment(t, "Thibaut", "Salut"), http.StatusAccepted)
+		assertStatusCode(t, comment(t, "Lucas", "Salut"), http.StatusAccepted)
+		assertStatusCode(t, comment(t, "Marie", "Salut"), http.StatusTooManyRequests)
+	})
+	t.Run("Limits the submissions per user", func(t *testing.T) {
+		svr.UseRateLimits(server.RateLimits{SubmissionsPerUser: server.RateLimit{Requests: 1, Window: time.Hour}})
+		submit := func(name string) *httptest.ResponseRecorder {
+			form := fmt.Sprintf("name=%s&address=%s&postal_code=75019&city=Paris&department=Paris", name, name)
+			req := requestAs(test.NewPostFormRequest(t, server.APISubmittedPlaygrounds, form), "Youssef")
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, req)
+			return res
+		}
+		assertStatusCode(t, submit("terrain1"), http.StatusAccepted)
+
+		res := submit("terrain2")
+		assertStatusCode(t, res, http.StatusTooManyRequests)
+		assertHeader(t, res, "Retry-After", "3600")
+	})
+	t.Run("Only shows the comments of a shadow banned user to them", func(t *testing.T) {
+		svr.UseRateLimits(server.RateLimits{})
+		req := setupRequestContext(test.NewPostFormRequest(t, server.APIShadowBans, "username=Thibaut&reason=spam"))
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		res = comment(t, "Thibaut", "Achetez mes baskets")
+		assertStatusCode(t, res, http.StatusAccepted)
+		ID := len(str.playgrounds[0].Comments)
+
+		countComments := func(req *http.Request) int {
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, req)
+			var threads []store.CommentThread
+			json.NewDecoder(res.Body).Decode(&threads)
+			return len(threads)
+		}
+		everyone := countComments(test.NewGetRequest(t, "/api/playgrounds/1/comments"))
+		author := countComments(withCookie(test.NewGetRequest(t, "/api/playgrounds/1/comments"), "Thibaut"))
+		if author != everyone+1 {
+			t.Errorf("Got %d comments for the author, %d for the others", author, everyone)
+		}
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, fmt.Sprintf("/api/playgrounds/1/comments/%d", ID)))
+		assertStatusCode(t, res, http.StatusNotFound)
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, withCookie(test.NewGetRequest(t, fmt.Sprintf("/api/playgrounds/1/comments/%d", ID)), "Thibaut"))
+		assertStatusCode(t, res, http.StatusOK)
+
+		req = setupRequestContext(test.NewDeleteRequest(t, server.APIShadowBans+"/Thibaut"))
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		assertStatusCode(t, res, http.StatusAccepted)
+		var users []string
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewGetRequest(t, server.APIShadowBans)))
+		json.NewDecoder(res.Body).Decode(&users)
+		if len(users) != 0 {
+			t.Errorf("Got %v", users)
+		}
+	})
+	t.Run("Hides the shadowed comments in the playground APIs", func(t *testing.T) {
+		str.playgrounds[0].Comments = store.Comments{
+			{ID: 1, Content: "Bonjour", Author: "Clélia"},
+			{ID: 2, Content: "Achetez mes baskets", Author: "Thibaut", Shadowed: true},
+		}
+		countPlaygroundComments := func(req *http.Request) int {
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, req)
+			var playground store.Playground
+			json.NewDecoder(res.Body).Decode(&playground)
+			return len(playground.Comments)
+		}
+		countAllComments := func(req *http.Request) int {
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, req)
+			var playgrounds store.Playgrounds
+			json.NewDecoder(res.Body).Decode(&playgrounds)
+			if len(playgrounds) != 1 {
+				t.Fatalf("Got %d playgrounds, want 1", len(playgrounds))
+			}
+			return len(playgrounds[0].Comments)
+		}
+
+		if got := countPlaygroundComments(test.NewGetRequest(t, "/api/playgrounds/1")); got != 1 {
+			t.Errorf("Got %d comments, want 1", got)
+		}
+		if got := countPlaygroundComments(withCookie(test.NewGetRequest(t, "/api/playgrounds/1"), "Thibaut")); got != 2 {
+			t.Errorf("Got %d comments for the author, want 2", got)
+		}
+		if got := countAllComments(test.NewGetRequest(t, server.APIPlaygrounds)); got != 1 {
+			t.Errorf("Got %d comments, want 1", got)
+		}
+		if got := countAllComments(withCookie(test.NewGetRequest(t, server.APIPlaygrounds), "Thibaut")); got != 2 {
+			t.Errorf("Got %d comments for the author, want 2", got)
+		}
+		if len(str.playgrounds[0].Comments) != 2 {
+			t.Errorf("The stored comments shouldn't change")
+		}
+	})
+}
+
+func TestCommentHistory(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{{ID: 1, Name: "test1", Comments: store.Comments{
+		{ID: 1, Content: "Super terrain", Author: "Youssef", TimeOfSubmission: created},
+	}}}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+
+	t.Run("Keeps the creation time when a comment is edited", func(t *testing.T) {
+		req := setupRequestContext(test.NewPutRequest(t, "/api/playgrounds/1/comments/1", `{"content": "Super **terrain**"}`))
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, req)
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, "/api/playgrounds/1/comments/1"))
+		var comment store.Comment
+		json.NewDecoder(res.Body).Decode(&comment)
+		if !comment.TimeOfSubmission.Equal(created) || !comment.TimeOfEdit.After(created) {
+			t.Errorf("Got %v", comment)
+		}
+	})
+	t.Run("Returns the versions of the comment", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewGetRequest(t, "/api/playgrounds/1/comments/1/history")))
+		assertStatusCode(t, res, http.StatusOK)
+
+		var versions []store.CommentVersion
+		err := json.NewDecoder(res.Body).Decode(&versions)
+		if err != nil {
+			t.Fatalf("Unable to parse response into versions, '%v'", err)
+		}
+		if len(versions) != 2 || versions[0].Content != "Super terrain" || versions[1].Content != "Super **terrain**" {
+			t.Errorf("Got %v", versions)
+		}
+
+		for URL, want := range map[string]int{
+			"/api/playgrounds/1/comments/2/history": http.StatusNotFound,
+			"/api/playgrounds/2/comments/1/history": http.StatusNotFound,
+		} {
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, setupRequestContext(test.NewGetRequest(t, URL)))
+			assertStatusCode(t, res, want)
+		}
+	})
+}
+
+type mockBlobStore struct {
+	blobs map[string][]byte
+}
+
+func (m *mockBlobStore) Put(key, contentType string, data []byte) error {
+	m.blobs[key] = data
+	return nil
+}
+
+func (m *mockBlobStore) Get(key string) ([]byte, string, error) {
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, "", store.ErrorNotFoundBlob
+	}
+	return data, "image/png", nil
+}
+
+func (m *mockBlobStore) Delete(key string) error {
+	delete(m.blobs, key)
+	return nil
+}
+
+func newPhotoRequest(t *testing.T, URL string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("photo", "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+	req, err := http.NewRequest(http.MethodPost, URL, &body)
+	if err != nil {
+		t.Fatalf("Couldn't create request, %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return setupRequestContext(req)
+}
+
+func TestPhotos(t *testing.T) {
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{{ID: 1, Name: "test1"}}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+	svr.UseRateLimits(server.RateLimits{})
+	var photo bytes.Buffer
+	png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 640, 480)))
+
+	upload := func(t *testing.T, URL string, data []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, newPhotoRequest(t, URL, data))
+		return res
+	}
+	get := func(t *testing.T, URL string) *httptest.ResponseRecorder {
+		t.Helper()
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewGetRequest(t, URL)))
+		return res
+	}
+
+	t.Run("Refuses uploads without storage", func(t *testing.T) {
+		assertStatusCode(t, upload(t, "/api/playgrounds/1/photos", photo.Bytes()), http.StatusServiceUnavailable)
+	})
+	blobs := &mockBlobStore{blobs: make(map[string][]byte)}
+	svr.UsePhotoStorage(blobs)
+	t.Run("Refuses invalid photos", func(t *testing.T) {
+		assertStatusCode(t, upload(t, "/api/playgrounds/1/photos", []byte("<svg onload=alert(1)>")), http.StatusUnsupportedMediaType)
+		assertStatusCode(t, upload(t, "/api/playgrounds/1/photos", make([]byte, store.MaxPhotoSize+1)), http.StatusRequestEntityTooLarge)
+		assertStatusCode(t, upload(t, "/api/playgrounds/9/photos", photo.Bytes()), http.StatusNotFound)
+		if len(blobs.blobs) != 0 {
+			t.Errorf("Got %v", blobs.blobs)
+		}
+	})
+	t.Run("Keeps an uploaded photo for moderation", func(t *testing.T) {
+		assertStatusCode(t, upload(t, "/api/playgrounds/1/photos", photo.Bytes()), http.StatusAccepted)
+
+		if len(blobs.blobs) != 2 {
+			t.Errorf("Photo and thumbnail should be stored, got %d blobs", len(blobs.blobs))
+		}
+		var photos []store.Photo
+		json.NewDecoder(get(t, "/api/playgrounds/1/photos").Body).Decode(&photos)
+		if len(photos) != 0 {
+			t.Errorf("Pending photo shouldn't be listed, got %v", photos)
+		}
+		assertStatusCode(t, get(t, "/api/playgrounds/1/photos/1"), http.StatusNotFound)
+
+		var queue []store.PendingPhoto
+		json.NewDecoder(get(t, "/api/moderation/photos").Body).Decode(&queue)
+		if len(queue) != 1 || queue[0].Author != "Youssef" || queue[0].Width != 640 || queue[0].Height != 480 {
+			t.Errorf("Got %v", queue)
+		}
+		assertStatusCode(t, get(t, "/api/moderation/playgrounds/1/photos/1"), http.StatusOK)
+	})
+	t.Run("Serves an approved photo and its thumbnail", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewPostFormRequest(t, "/api/moderation/playgrounds/1/photos/1", "action=approve")))
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		res = get(t, "/api/playgrounds/1/photos/1")
+		assertStatusCode(t, res, http.StatusOK)
+		assertHeader(t, res, "Content-Type", "image/png")
+		assertHeader(t, res, "X-Content-Type-Options", "nosniff")
+		res = get(t, "/api/playgrounds/1/photos/1/thumbnail")
+		assertStatusCode(t, res, http.StatusOK)
+		thumbnail, err := png.DecodeConfig(res.Body)
+		if err != nil || thumbnail.Width != store.ThumbnailSize || thumbnail.Height != 240 {
+			t.Errorf("Got %v, %v", thumbnail, err)
+		}
+		var photos []store.Photo
+		json.NewDecoder(get(t, "/api/playgrounds/1/photos").Body).Decode(&photos)
+		if len(photos) != 1 || photos[0].ID != 1 {
+			t.Errorf("Got %v", photos)
+		}
+	})
+	t.Run("Deletes the files of a rejected photo", func(t *testing.T) {
+		assertStatusCode(t, upload(t, "/api/playgrounds/1/photos", photo.Bytes()), http.StatusAccepted)
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewPostFormRequest(t, "/api/moderation/playgrounds/1/photos/2", "action=reject&reason=hors sujet")))
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		if len(blobs.blobs) != 2 {
+			t.Errorf("Got %d blobs", len(blobs.blobs))
+		}
+		assertStatusCode(t, get(t, "/api/playgrounds/1/photos/2"), http.StatusNotFound)
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewPostFormRequest(t, "/api/moderation/playgrounds/1/photos/2", "action=hide")))
+		assertStatusCode(t, res, http.StatusBadRequest)
+		res = httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewPostFormRequest(t, "/api/moderation/playgrounds/1/photos/2", "action=approve")))
+		assertStatusCode(t, res, http.StatusConflict)
+	})
+	t.Run("Ignores the photos of shadow banned users", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, setupRequestContext(test.NewPostFormRequest(t, "/api/moderation/shadowBans", "username=Youssef")))
+		assertStatusCode(t, res, http.StatusAccepted)
+
+		assertStatusCode(t, upload(t, "/api/playgrounds/1/photos", photo.Bytes()), http.StatusAccepted)
+		if len(blobs.blobs) != 2 {
+			t.Errorf("Got %d blobs", len(blobs.blobs))
+		}
+	})
+}
+
+func TestOpeningHours(t *testing.T) {
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{
+		{ID: 1, Name: "Jour", Long: 2.36, Lat: 48.85, OpeningHours: "Mo-Su 08:00-20:00"},
+		{ID: 2, Name: "Nuit", Long: 2.37, Lat: 48.85, OpeningHours: "Mo-Su 20:00-08:00"},
+		{ID: 3, Name: "Toujours", Long: 2.38, Lat: 48.85, OpeningHours: "24/7"},
+		{ID: 4, Name: "Inconnu", Long: 2.36, Lat: 48.85},
+	}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+	svr.UseRateLimits(server.RateLimits{})
+
+	get := func(t *testing.T, URL string) store.Playgrounds {
+		t.Helper()
+		res := httptest.NewRecorder()
+		svr.ServeHTTP(res, test.NewGetRequest(t, URL))
+		assertStatusCode(t, res, http.StatusOK)
+		got, err := store.NewPlaygroundsFromJSON(res.Body)
+		if err != nil {
+			t.Fatalf("Unable to parse response into slice, '%v'", err)
+		}
+		return got
+	}
+
+	t.Run("Filters the playgrounds open at a time", func(t *testing.T) {
+		cases := map[string][]int{
+			server.APIPlaygrounds + "?open_at=2024-03-04T21:00":                                    {2, 3},
+			server.APIPlaygrounds + "?open_at=2024-03-04T12:00:00Z":                                {1, 3},
+			server.APINearestPlaygrounds + "?lat=48.85&long=2.36&open_at=2024-03-04T12:00":         {1, 3},
+			server.APINearestPlaygrounds + "?lat=48.85&long=2.36&open_at=2024-03-04T21:00&limit=1": {2},
+		}
+		for URL, want := range cases {
+			got := get(t, URL)
+			gotIDs := []int{}
+			for _, playground := range got {
+				gotIDs = append(gotIDs, playground.ID)
+			}
+			sort.Ints(gotIDs)
+			if !reflect.DeepEqual(gotIDs, want) {
+				t.Errorf("%s : got %v, want %v", URL, gotIDs, want)
+			}
+		}
+	})
+	t.Run("Filters the playgrounds open now", func(t *testing.T) {
+		got := get(t, server.APIPlaygrounds+"?open_now=true")
+		if len(got) != 2 {
+			t.Errorf("Got %v", got)
+		}
+	})
+	t.Run("Returns bad request if a parameter is invalid", func(t *testing.T) {
+		for _, URL := range []string{
+			server.APIPlaygrounds + "?open_now=maybe",
+			server.APIPlaygrounds + "?open_now=false",
+			server.APIPlaygrounds + "?open_at=demain",
+			server.APINearestPlaygrounds + "?lat=48.85&long=2.36&open_at=2024-03-04",
+		} {
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, test.NewGetRequest(t, URL))
+			assertStatusCode(t, res, http.StatusBadRequest)
+		}
+	})
+	t.Run("Checks the opening hours of a submitted playground", func(t *testing.T) {
+		submit := func(name, openingHours string) *httptest.ResponseRecorder {
+			form := fmt.Sprintf("name=%s&address=%s&postal_code=75019&city=Paris&department=Paris&opening_hours=%s", name, name, url.QueryEscape(openingHours))
+			req := setupRequestContext(test.NewPostFormRequest(t, server.APISubmittedPlaygrounds, form))
+			res := httptest.NewRecorder()
+			svr.ServeHTTP(res, req)
+			return res
+		}
+		assertStatusCode(t, submit("terrain1", "tous les jours"), http.StatusBadRequest)
+		assertStatusCode(t, submit("terrain2", "Mo-Fr 08:00-20:00; PH off"), http.StatusAccepted)
+	})
+}
+
+func TestGeocodingReport(t *testing.T) {
+	nearPlayground := store.Playground{ID: 1, Name: "near", Address: "42 avenue de Flandre", PostalCode: "75019", City: "Paris", Long: 2.372452, Lat: 48.886835}
+	farPlayground := store.Playground{ID: 2, Name: "far", Address: "1 rue de Rivoli", PostalCode: "75001", City: "Paris", Long: 2.35, Lat: 48.85}
+	str := &mockPlaygroundStore{playgrounds: store.Playgrounds{nearPlayground, farPlayground}}
+	svr := server.New(str, &mockGeolocationClient{}, nil, dummyMiddlewares)
+
+	t.Run("Rejects an invalid max distance", func(t *testing.T) {
+		req := test.NewPostFormRequest(t, server.APIGeocodingReport, "max_distance=-1")
+		response := httptest.NewRecorder()
+
+		svr.ServeHTTP(response, req)
+
+		assertStatusCode(t, response, http.StatusBadRequest)
+	})
+	t.Run("Runs the report in the background and queues corrections", func(t *testing.T) {
+		req := test.NewPostFormRequest(t, server.APIGeocodingReport, "max_distance=200&queue=true")
+		response := httptest.NewRecorder()
+
+		svr.ServeHTTP(response, req)
+
+		assertStatusCode(t, response, http.StatusAccepted)
+		status := waitForGeocodingReport(t, svr)
+		if status.Report.Checked != 2 || len(status.Report.Outliers) != 1 || status.Report.Outliers[0].Name != "far" || status.Queued != 1 {
+			t.Fatalf("Wrong report, got %+v", status)
+		}
+
+		var corrections store.GeocodingCorrections
+		response = httptest.NewRecorder()
+		svr.ServeHTTP(response, test.NewGetRequest(t, server.APIGeocodingCorrections))
+		json.NewDecoder(response.Body).Decode(&corrections)
+		if len(corrections) != 1 || corrections[0].Outlier.PlaygroundID != 2 {
+			t.Fatalf("got %v, want a correction for playground 2", corrections)
+		}
+	})
+	t.Run("Applies a correction", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, strings.Replace(server.APIGeocodingCorrection, "{ID}", "1", 1), nil)
+		response := httptest.NewRecorder()
+
+		svr.ServeHTTP(response, req)
+
+		assertStatusCode(t, response, http.StatusAccepted)
+		got, _ := str.Playground(2)
+		if got.Long != 2.372452 || got.Lat != 48.886835 {
+			t.Errorf("Playground wasn't moved, got %v", got)
+		}
+	})
+	t.Run("Returns 404 for an unknown correction", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, strings.Replace(server.APIGeocodingCorrection, "{ID}", "1", 1), nil)
+		response := httptest.NewRecorder()
+
+		svr.ServeHTTP(response, req)
+
+		assertStatusCode(t, response, http.StatusNotFound)
+	})
+}
+
+func waitForGeocodingReport(t *testing.T, svr *server.PlaygroundServer) server.GeocodingJobStatus {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		var status server.GeocodingJobStatus
+		response := httptest.NewRecorder()
+		svr.ServeHTTP(response, test.NewGetRequest(t, server.APIGeocodingReport))
+		json.NewDecoder(response.Body).Decode(&status)
+		if !status.Running && status.Report != nil {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Geocoding report didn't finish")
+	return server.GeocodingJobStatus{}
+}
+
+type mockView struct {
+	data   server.RenderingData
+	called bool
+}
+
+func (m *mockView) Render(w io.Writer, r *http.Request, data server.RenderingData) error {
+	m.called = true
+	m.data = data
+	return nil
 }
 
 func TestViews(t *testing.T) {
@@ -761,10 +2310,12 @@ func TestMiddlewares(t *testing.T) {
 	mockIsLogged := &mockMiddleware{}
 	mockRefreshJWT := &mockMiddleware{}
 	mockIsAuthorized := &mockMiddleware{}
+	mockIsModerator := &mockMiddleware{}
 	middlewares := map[string]server.Middleware{
 		"isLogged":   mockIsLogged,
 		"refresh":    mockRefreshJWT,
 		"authorized": mockIsAuthorized,
+		"moderator":  mockIsModerator,
 	}
 	str := &mockPlaygroundStore{}
 
@@ -824,6 +2375,52 @@ func TestMiddlewares(t *testing.T) {
 			mockIsAuthorized.called = false
 		})
 	}
+	moderationRoutes := []struct {
+		method string
+		url    string
+	}{
+		{http.MethodGet, server.URLModeration},
+		{http.MethodGet, server.APIModerationReports},
+		{http.MethodGet, server.APIModerationLog},
+		{http.MethodPost, "/api/moderation/playgrounds/1/comments/1"},
+		{http.MethodGet, "/api/playgrounds/1/comments/1/history"},
+		{http.MethodGet, server.APIShadowBans},
+		{http.MethodPost, server.APIShadowBans},
+		{http.MethodDelete, server.APIShadowBans + "/spammer"},
+		{http.MethodGet, server.APIModerationPhotos},
+		{http.MethodGet, "/api/moderation/playgrounds/1/photos/1"},
+		{http.MethodPost, "/api/moderation/playgrounds/1/photos/1"},
+		{http.MethodGet, server.URLGeocodingCorrections},
+		{http.MethodGet, server.APIGeocodingReport},
+		{http.MethodPost, server.APIGeocodingReport},
+		{http.MethodGet, server.APIGeocodingCorrections},
+		{http.MethodPost, server.APIGeocodingCorrections + "/1"},
+		{http.MethodDelete, server.APIGeocodingCorrections + "/1"},
+	}
+	for _, route := range moderationRoutes {
+		t.Run(fmt.Sprintf("Moderator middleware is called on route %s %q", route.method, route.url), func(t *testing.T) {
+			var req *http.Request
+			switch route.method {
+			case http.MethodGet:
+				req = test.NewGetRequest(t, route.url)
+			case http.MethodDelete:
+				req = test.NewDeleteRequest(t, route.url)
+			default:
+				req = test.NewPostFormRequest(t, route.url, "")
+			}
+
+			svr.ServeHTTP(httptest.NewRecorder(), req)
+
+			if mockIsModerator.called != true {
+				t.Errorf("IsModerator middleware hasn't been called")
+			}
+			if mockIsAuthorized.called {
+				t.Errorf("Moderation routes should use the moderator middleware")
+			}
+			mockIsModerator.called = false
+			mockIsAuthorized.called = false
+		})
+	}
 }
 
 func assertStatusCode(t *testing.T, res *httptest.ResponseRecorder, want int) {
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+const (
+	minSuggestionQueryLength = 3
+	defaultSuggestionsLimit  = 5
+	maxSuggestionsLimit      = 10
+	suggestionsCacheTTL      = 10 * time.Minute
+	suggestionsCacheSize     = 1000
+)
+
+// suggestionsCache keeps the answers of the geocoder for the queries typed in the address inputs.
+// Users type the same beginnings of addresses over and over, so most keystrokes never reach the geocoder.
+// Once full, the least recently used query is evicted. The server doesn't debounce the keystrokes,
+// static/js/autocomplete.js waits for the user to stop typing before sending a query.
+type suggestionsCache struct {
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type suggestionsEntry struct {
+	key       string
+	locations []store.Location
+	expiresAt time.Time
+}
+
+func newSuggestionsCache() *suggestionsCache {
+	return &suggestionsCache{entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (s *suggestionsCache) suggest(ctx context.Context, client store.GeolocationClient, query string, limit int) ([]store.Location, error) {
+	key := suggestionsKey(query, limit)
+
+	s.mutex.Lock()
+	if element, ok := s.entries[key]; ok {
+		entry := element.Value.(*suggestionsEntry)
+		if time.Now().Before(entry.expiresAt) {
+			s.order.MoveToFront(element)
+			s.mutex.Unlock()
+			return entry.locations, nil
+		}
+		s.remove(element)
+	}
+	s.mutex.Unlock()
+
+	locations, err := client.SuggestAddresses(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+	s.entries[key] = s.order.PushFront(&suggestionsEntry{key: key, locations: locations, expiresAt: time.Now().Add(suggestionsCacheTTL)})
+	for s.order.Len() > suggestionsCacheSize {
+		s.remove(s.order.Back())
+	}
+	return locations, nil
+}
+
+func (s *suggestionsCache) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*suggestionsEntry).key)
+}
+
+func suggestionsKey(query string, limit int) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ") + "|" + strconv.Itoa(limit)
+}
@@ -18,7 +18,7 @@ func TestPostPlaygroundAndGet(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Problem opening file, %v", err)
 	}
-	client := geolocationClient.APIGouvFR{}
+	client := geolocationClient.NewAPIGouvFR()
 	middlewares := middleware.Initialize()
 	svr := server.New(database, client, nil, middlewares)
 
@@ -76,28 +76,28 @@ func TestPostPlaygroundAndGet(t *testing.T) {
 		}
 
 		want := store.Playgrounds{
-			// Distance : 3,37km Temps : 55 min A pied : 4,3km
-			store.Playground{
-				Name: "TEP JARDINS SAINT PAUL",
-				Long: 2.36016000,
-				Lat:  48.85320000,
-				ID:   4,
-			},
-			// Distance : 4,1km Temps : 49 min A pied : 3,8km
+			// Distance à vol d'oiseau : 3,55km
 			store.Playground{
 				Name: "LYCEE CONDORCET",
 				Long: 2.32743,
 				Lat:  48.87484,
 				ID:   2,
 			},
-			// Distance : 4,19km Temps : 59 min A pied : 4,5km
+			// Distance à vol d'oiseau : 3,85km
+			store.Playground{
+				Name: "TEP JARDINS SAINT PAUL",
+				Long: 2.36016000,
+				Lat:  48.85320000,
+				ID:   4,
+			},
+			// Distance à vol d'oiseau : 4,14km
 			store.Playground{
 				Name: "ETABLISSEMENT FENELON",
 				Long: 2.31718,
 				Lat:  48.87867,
 				ID:   1,
 			},
-			// Distance : 5,7km Temps : 1h24 A pied : 6,5km
+			// Distance à vol d'oiseau : 5,58km
 			store.Playground{
 				Name: "LYCEE VICTOR DURUY",
 				Long: 2.31565,
@@ -0,0 +1,41 @@
+// Suggests addresses from /api/addressSuggestions while the user types in an input.
+// onSelect is called with the chosen suggestion (label, postal_code, city, department, long, lat).
+function addressAutocomplete(input, onSelect) {
+	const datalist = document.createElement("datalist");
+	datalist.id = `${input.id}-suggestions`;
+	input.setAttribute("list", datalist.id);
+	input.setAttribute("autocomplete", "off");
+	input.after(datalist);
+
+	let suggestions = [];
+	// The queries are only debounced here, the server sends every query it gets to its cache or the geocoder.
+	let timeout;
+	let lastQuery = 0;
+	input.addEventListener("input", () => {
+		const selected = suggestions.find(suggestion => suggestion.label === input.value);
+		if (selected) {
+			onSelect(selected);
+			return;
+		}
+		clearTimeout(timeout);
+		timeout = setTimeout(() => {
+			if (input.value.trim().length < 3) {
+				return;
+			}
+			const query = ++lastQuery;
+			fetch("/api/addressSuggestions?q=" + encodeURIComponent(input.value)).then(res => res.json()).then(results => {
+				// The answer to an older query can arrive after the last one.
+				if (query !== lastQuery) {
+					return;
+				}
+				suggestions = results;
+				datalist.innerHTML = "";
+				results.forEach(result => {
+					const option = document.createElement("option");
+					option.value = result.label;
+					datalist.appendChild(option);
+				});
+			});
+		}, 300);
+	});
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"math"
+	"sort"
+)
+
+// Above this zoom level, playgrounds are always sent one by one.
+const MaxClusteringZoom = 14
+
+// Number of clusters per map tile width : a 256px tile is split into 64px cells.
+const clustersPerTile = 4
+
+type Cluster struct {
+	Count  int         `json:"count"`
+	Long   float64     `json:"long"`
+	Lat    float64     `json:"lat"`
+	Bounds BoundingBox `json:"bounds"`
+}
+
+type Clusters []Cluster
+
+// Cluster groups the playgrounds that would overlap on a map displayed at the given zoom level.
+// Playgrounds alone in their cell are returned as is, the others are aggregated into clusters.
+func (p Playgrounds) Cluster(zoom int) (Playgrounds, Clusters) {
+	if zoom > MaxClusteringZoom {
+		return p, Clusters{}
+	}
+	cellSize := 360 / math.Pow(2, float64(zoom)) / clustersPerTile
+
+	cells := make(map[gridCell]Playgrounds)
+	order := []gridCell{}
+	for _, playground := range p {
+		cell := gridCell{
+			x: int(math.Floor(playground.Long / cellSize)),
+			y: int(math.Floor(playground.Lat / cellSize)),
+		}
+		if _, ok := cells[cell]; !ok {
+			order = append(order, cell)
+		}
+		cells[cell] = append(cells[cell], playground)
+	}
+
+	playgrounds := Playgrounds{}
+	clusters := Clusters{}
+	for _, cell := range order {
+		cellPlaygrounds := cells[cell]
+		if len(cellPlaygrounds) == 1 {
+			playgrounds = append(playgrounds, cellPlaygrounds[0])
+			continue
+		}
+		clusters = append(clusters, newCluster(cellPlaygrounds))
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].Count > clusters[j].Count
+	})
+	return playgrounds, clusters
+}
+
+func newCluster(playgrounds Playgrounds) Cluster {
+	cluster := Cluster{
+		Count: len(playgrounds),
+		Bounds: BoundingBox{
+			MinLong: playgrounds[0].Long,
+			MinLat:  playgrounds[0].Lat,
+			MaxLong: playgrounds[0].Long,
+			MaxLat:  playgrounds[0].Lat,
+		},
+	}
+	for _, playground := range playgrounds {
+		cluster.Long += playground.Long
+		cluster.Lat += playground.Lat
+		cluster.Bounds.MinLong = math.Min(cluster.Bounds.MinLong, playground.Long)
+		cluster.Bounds.MinLat = math.Min(cluster.Bounds.MinLat, playground.Lat)
+		cluster.Bounds.MaxLong = math.Max(cluster.Bounds.MaxLong, playground.Long)
+		cluster.Bounds.MaxLat = math.Max(cluster.Bounds.MaxLat, playground.Lat)
+	}
+	cluster.Long /= float64(len(playgrounds))
+	cluster.Lat /= float64(len(playgrounds))
+	return cluster
+}
@@ -0,0 +1,46 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestClusters(t *testing.T) {
+	paris1 := store.Playground{Name: "TEP JARDINS SAINT PAUL", Long: 2.36016000, Lat: 48.85320000}
+	paris2 := store.Playground{Name: "LYCEE VICTOR DURUY", Long: 2.31565, Lat: 48.8533}
+	marseille := store.Playground{Name: "MARSEILLE", Long: 5.37, Lat: 43.29}
+	playgrounds := store.Playgrounds{paris1, paris2, marseille}
+
+	t.Run("Cluster GROUPS playgrounds close to each other at low zoom", func(t *testing.T) {
+		gotPlaygrounds, gotClusters := playgrounds.Cluster(6)
+
+		if len(gotPlaygrounds) != 1 || gotPlaygrounds[0].Name != marseille.Name {
+			t.Errorf("Marseille should be alone, got %v", gotPlaygrounds)
+		}
+		if len(gotClusters) != 1 {
+			t.Fatalf("got %d clusters, want 1", len(gotClusters))
+		}
+		want := store.Cluster{
+			Count: 2,
+			Long:  (paris1.Long + paris2.Long) / 2,
+			Lat:   (paris1.Lat + paris2.Lat) / 2,
+			Bounds: store.BoundingBox{
+				MinLong: paris2.Long,
+				MinLat:  paris1.Lat,
+				MaxLong: paris1.Long,
+				MaxLat:  paris2.Lat,
+			},
+		}
+		if gotClusters[0] != want {
+			t.Errorf("got : %v, want : %v", gotClusters[0], want)
+		}
+	})
+	t.Run("Cluster RETURNS every playground at high zoom", func(t *testing.T) {
+		gotPlaygrounds, gotClusters := playgrounds.Cluster(store.MaxClusteringZoom + 1)
+
+		if len(gotPlaygrounds) != 3 || len(gotClusters) != 0 {
+			t.Errorf("got %d playgrounds and %d clusters, want 3 and 0", len(gotPlaygrounds), len(gotClusters))
+		}
+	})
+}
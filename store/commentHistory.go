@@ -0,0 +1,40 @@
+package store
+
+import "time"
+
+// CommentVersion is the content of a comment from Time until the next version.
+type CommentVersion struct {
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+func (c Comment) Edited() bool {
+	return !c.TimeOfEdit.IsZero()
+}
+
+func (c Comment) version() CommentVersion {
+	if c.Edited() {
+		return CommentVersion{Content: c.Content, Time: c.TimeOfEdit}
+	}
+	return CommentVersion{Content: c.Content, Time: c.TimeOfSubmission}
+}
+
+// addCommentVersion keeps the current version of comment before it's edited.
+func (p *Playground) addCommentVersion(comment Comment) {
+	if p.CommentVersions == nil {
+		p.CommentVersions = make(map[int][]CommentVersion)
+	}
+	p.CommentVersions[comment.ID] = append(p.CommentVersions[comment.ID], comment.version())
+}
+
+// CommentHistory returns every version of the comment from the oldest, the last one being the current content.
+func (p Playground) CommentHistory(commentID int) ([]CommentVersion, error) {
+	comment, err := p.FindComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	previousVersions := p.CommentVersions[commentID]
+	versions := make([]CommentVersion, 0, len(previousVersions)+1)
+	versions = append(versions, previousVersions...)
+	return append(versions, comment.version()), nil
+}
@@ -0,0 +1,49 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestCommentHistory(t *testing.T) {
+	created := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	playground := store.Playground{}
+	playground.AddComment(store.Comment{Author: "Youssef", Content: "Super terrain", TimeOfSubmission: created})
+
+	t.Run("Keeps the creation time and the previous versions", func(t *testing.T) {
+		playground.UpdateComment(store.Comment{ID: 1, Author: "Youssef", Content: "Super terrain !", TimeOfEdit: created.Add(time.Hour)})
+		playground.UpdateComment(store.Comment{ID: 1, Author: "Youssef", Content: " Super terrain !  ", TimeOfEdit: created.Add(2 * time.Hour)})
+		playground.UpdateComment(store.Comment{ID: 1, Author: "Youssef", Content: "Terrain correct", TimeOfEdit: created.Add(3 * time.Hour)})
+
+		comment, _ := playground.FindComment(1)
+		if !comment.Edited() || !comment.TimeOfSubmission.Equal(created) || !comment.TimeOfEdit.Equal(created.Add(3*time.Hour)) {
+			t.Errorf("Got %v", comment)
+		}
+		got, err := playground.CommentHistory(1)
+		if err != nil {
+			t.Fatalf("Couldn't get history, %s", err)
+		}
+		want := []store.CommentVersion{
+			{Content: "Super terrain", Time: created},
+			{Content: "Super terrain !", Time: created.Add(time.Hour)},
+			{Content: "Terrain correct", Time: created.Add(3 * time.Hour)},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+	t.Run("Forgets the history of deleted comments", func(t *testing.T) {
+		playground.DeleteComment(1)
+		playground.AddComment(store.Comment{Author: "Youssef", Content: "Nouveau", TimeOfSubmission: created})
+
+		got, _ := playground.CommentHistory(1)
+		if len(got) != 1 || got[0].Content != "Nouveau" {
+			t.Errorf("Got %v", got)
+		}
+		_, err := playground.CommentHistory(2)
+		assertError(t, err, store.ErrorNotFoundComment)
+	})
+}
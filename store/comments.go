@@ -0,0 +1,221 @@
+package store
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrorInvalidVote     = errors.New("Vote should be 1, -1 or 0 to remove it")
+	ErrorMaxCommentDepth = errors.New("Replies can't be nested deeper")
+)
+
+const (
+	SortCommentsByTop = "top"
+	SortCommentsByNew = "new"
+)
+
+const (
+	// Top level comments have a depth of 0, their replies 1...
+	MaxCommentDepth       = 3
+	DeletedCommentContent = "[deleted]"
+)
+
+// CommentThread is a comment with its replies, sorted like the top level comments.
+type CommentThread struct {
+	Comment
+	Replies []CommentThread `json:"replies"`
+}
+
+// Vote sets the vote of username on the comment : 1 for an upvote, -1 for a downvote and 0 to remove the vote.
+func (p *Playground) Vote(commentID int, username string, vote int) (Comment, error) {
+	if vote < -1 || vote > 1 {
+		return Comment{}, ErrorInvalidVote
+	}
+	if username == "" {
+		return Comment{}, ErrEmptyField
+	}
+	index := -1
+	for i, comment := range p.Comments {
+		if comment.ID == commentID {
+			index = i
+		}
+	}
+	if index < 0 {
+		return Comment{}, ErrorNotFoundComment
+	}
+
+	if p.CommentVotes == nil {
+		p.CommentVotes = make(map[int]map[string]int)
+	}
+	votes, ok := p.CommentVotes[commentID]
+	if !ok {
+		votes = make(map[string]int)
+		p.CommentVotes[commentID] = votes
+	}
+	if vote == 0 {
+		delete(votes, username)
+	} else {
+		votes[username] = vote
+	}
+
+	comment := &p.Comments[index]
+	comment.Upvotes, comment.Downvotes = 0, 0
+	for _, vote := range votes {
+		if vote > 0 {
+			comment.Upvotes++
+		} else {
+			comment.Downvotes++
+		}
+	}
+	comment.Score = comment.Upvotes - comment.Downvotes
+	return *comment, nil
+}
+
+// VisibleTo leaves out the shadowed comments of other users than username.
+func (c Comments) VisibleTo(username string) Comments {
+	if c == nil {
+		return nil
+	}
+	comments := make(Comments, 0, len(c))
+	for _, comment := range c {
+		if !comment.Shadowed || (username != "" && comment.Author == username) {
+			comments = append(comments, comment)
+		}
+	}
+	return comments
+}
+
+// Sort returns a sorted copy of the comments, ties keep their current order.
+// Top sorts by score then from the newest, new only from the newest.
+func (c Comments) Sort(key string) (Comments, error) {
+	comments := make(Comments, len(c))
+	copy(comments, c)
+	switch key {
+	case SortCommentsByTop:
+		sort.SliceStable(comments, func(i, j int) bool {
+			if comments[i].Score != comments[j].Score {
+				return comments[i].Score > comments[j].Score
+			}
+			return comments[i].TimeOfSubmission.After(comments[j].TimeOfSubmission)
+		})
+	case SortCommentsByNew:
+		sort.SliceStable(comments, func(i, j int) bool {
+			return comments[i].TimeOfSubmission.After(comments[j].TimeOfSubmission)
+		})
+	default:
+		return nil, ErrorUnknownSort
+	}
+	return comments, nil
+}
+
+// replyDepth returns the depth of a reply to parentID, deleted comments can't be replied to.
+func (p Playground) replyDepth(parentID int) (int, error) {
+	parent, err := p.FindComment(parentID)
+	if err != nil || parent.Deleted {
+		return 0, ErrorNotFoundComment
+	}
+	depth := 1
+	for parent.ParentID != 0 && depth <= MaxCommentDepth {
+		parent, err = p.FindComment(parent.ParentID)
+		if err != nil {
+			break
+		}
+		depth++
+	}
+	return depth, nil
+}
+
+func (p Playground) hasReplies(commentID int) bool {
+	for _, comment := range p.Comments {
+		if comment.ParentID == commentID {
+			return true
+		}
+	}
+	return false
+}
+
+// ThreadedComment is a comment of a flattened thread, Depth being its number of parents.
+type ThreadedComment struct {
+	Comment
+	Depth int
+}
+
+func (c ThreadedComment) CanReply() bool {
+	return !c.Deleted && !c.Hidden && c.Depth < MaxCommentDepth
+}
+
+// Threads nests the replies under their parent, a reply whose parent is missing is shown at the top level.
+// An empty sortKey keeps the current order.
+func (c Comments) Threads(sortKey string) ([]CommentThread, error) {
+	return c.threads(0, sortKey)
+}
+
+// ThreadedComments returns the comments in reading order, each comment followed by its replies.
+// Hidden comments are masked.
+func (p Playground) ThreadedComments() []ThreadedComment {
+	threads, _ := p.Comments.Threads("")
+	comments := []ThreadedComment{}
+	var flatten func(threads []CommentThread, depth int)
+	flatten = func(threads []CommentThread, depth int) {
+		for _, thread := range threads {
+			comments = append(comments, ThreadedComment{Comment: thread.Comment.masked(), Depth: depth})
+			flatten(thread.Replies, depth+1)
+		}
+	}
+	flatten(threads, 0)
+	return comments
+}
+
+// Replies returns the threads of the replies to commentID.
+func (c Comments) Replies(commentID int, sortKey string) ([]CommentThread, error) {
+	for _, comment := range c {
+		if comment.ID == commentID {
+			return c.threads(commentID, sortKey)
+		}
+	}
+	return nil, ErrorNotFoundComment
+}
+
+func (c Comments) threads(parentID int, sortKey string) ([]CommentThread, error) {
+	IDs := make(map[int]bool, len(c))
+	for _, comment := range c {
+		IDs[comment.ID] = true
+	}
+	replies := make(map[int]Comments)
+	for _, comment := range c {
+		commentParentID := comment.ParentID
+		if !IDs[commentParentID] {
+			commentParentID = 0
+		}
+		replies[commentParentID] = append(replies[commentParentID], comment)
+	}
+
+	// visited protects from loops in inconsistent data.
+	visited := make(map[int]bool, len(c))
+	var build func(parentID int) ([]CommentThread, error)
+	build = func(parentID int) ([]CommentThread, error) {
+		comments := replies[parentID]
+		if sortKey != "" {
+			var err error
+			comments, err = comments.Sort(sortKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+		threads := make([]CommentThread, 0, len(comments))
+		for _, comment := range comments {
+			if visited[comment.ID] {
+				continue
+			}
+			visited[comment.ID] = true
+			commentReplies, err := build(comment.ID)
+			if err != nil {
+				return nil, err
+			}
+			threads = append(threads, CommentThread{Comment: comment, Replies: commentReplies})
+		}
+		return threads, nil
+	}
+	return build(parentID)
+}
@@ -0,0 +1,195 @@
+package store_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestCommentVotes(t *testing.T) {
+	playground := store.Playground{}
+	playground.AddComment(store.Comment{Author: "Youssef", Content: "Super terrain"})
+
+	t.Run("Counts one vote per user", func(t *testing.T) {
+		playground.Vote(1, "Youssef", 1)
+		playground.Vote(1, "Clélia", 1)
+		playground.Vote(1, "Thibaut", -1)
+		got, err := playground.Vote(1, "Clélia", 1)
+		if err != nil {
+			t.Fatalf("Couldn't vote, %s", err)
+		}
+
+		assertVotes(t, got, 2, 1, 1)
+		assertVotes(t, playground.Comments[0], 2, 1, 1)
+	})
+	t.Run("Changes and removes votes", func(t *testing.T) {
+		playground.Vote(1, "Youssef", -1)
+		got, _ := playground.Vote(1, "Clélia", 0)
+
+		assertVotes(t, got, 0, 2, -2)
+	})
+	t.Run("Returns an error for invalid votes", func(t *testing.T) {
+		_, err := playground.Vote(1, "Youssef", 2)
+		assertError(t, err, store.ErrorInvalidVote)
+
+		_, err = playground.Vote(2, "Youssef", 1)
+		assertError(t, err, store.ErrorNotFoundComment)
+
+		_, err = playground.Vote(1, "", 1)
+		assertError(t, err, store.ErrEmptyField)
+	})
+	t.Run("Doesn't reuse the ID of a deleted comment", func(t *testing.T) {
+		playground.AddComment(store.Comment{Author: "Clélia", Content: "Trop de monde"})
+		playground.AddComment(store.Comment{Author: "Thibaut", Content: "Pas de filets"})
+		playground.DeleteComment(2)
+
+		playground.AddComment(store.Comment{Author: "Thibaut", Content: "Filets changés"})
+
+		if last := playground.Comments[len(playground.Comments)-1]; last.ID != 4 {
+			t.Errorf("got ID %d, want 4", last.ID)
+		}
+	})
+}
+
+func TestSortComments(t *testing.T) {
+	now := time.Now()
+	comments := store.Comments{
+		{ID: 1, Score: 2, TimeOfSubmission: now.Add(-3 * time.Hour)},
+		{ID: 2, Score: 5, TimeOfSubmission: now.Add(-2 * time.Hour)},
+		{ID: 3, Score: 2, TimeOfSubmission: now.Add(-time.Hour)},
+		{ID: 4, Score: -1, TimeOfSubmission: now},
+	}
+	cases := map[string][]int{
+		store.SortCommentsByTop: {2, 3, 1, 4},
+		store.SortCommentsByNew: {4, 3, 2, 1},
+	}
+	for key, want := range cases {
+		t.Run("Sorts by "+key, func(t *testing.T) {
+			got, err := comments.Sort(key)
+			if err != nil {
+				t.Fatalf("Couldn't sort comments, %s", err)
+			}
+			for i, comment := range got {
+				if comment.ID != want[i] {
+					t.Fatalf("got %v, want IDs %v", got, want)
+				}
+			}
+		})
+	}
+	t.Run("Returns an error for an unknown sort", func(t *testing.T) {
+		_, err := comments.Sort("old")
+
+		assertError(t, err, store.ErrorUnknownSort)
+	})
+}
+
+func assertVotes(t *testing.T, comment store.Comment, upvotes, downvotes, score int) {
+	t.Helper()
+	if comment.Upvotes != upvotes || comment.Downvotes != downvotes || comment.Score != score {
+		t.Errorf("got %d upvotes, %d downvotes and score %d, want %d, %d and %d", comment.Upvotes, comment.Downvotes, comment.Score, upvotes, downvotes, score)
+	}
+}
+
+func TestCommentThreads(t *testing.T) {
+	now := time.Now()
+	newPlayground := func() store.Playground {
+		playground := store.Playground{}
+		contents := []struct {
+			content  string
+			parentID int
+		}{{"1", 0}, {"2", 0}, {"1.1", 1}, {"1.2", 1}, {"1.1.1", 3}}
+		for i, comment := range contents {
+			playground.AddComment(store.Comment{Author: "Youssef", Content: comment.content, ParentID: comment.parentID, TimeOfSubmission: now.Add(time.Duration(i) * time.Minute)})
+		}
+		return playground
+	}
+
+	t.Run("Nests replies under their parent", func(t *testing.T) {
+		playground := newPlayground()
+
+		got, err := playground.Comments.Threads(store.SortCommentsByNew)
+		if err != nil {
+			t.Fatalf("Couldn't build threads, %s", err)
+		}
+
+		want := "[2 1 [1.2 1.1 [1.1.1]]]"
+		if threadsString(got) != want {
+			t.Errorf("got %s, want %s", threadsString(got), want)
+		}
+	})
+	t.Run("Returns the replies of a comment", func(t *testing.T) {
+		playground := newPlayground()
+
+		got, _ := playground.Comments.Replies(1, store.SortCommentsByNew)
+		if threadsString(got) != "[1.2 1.1 [1.1.1]]" {
+			t.Errorf("got %s", threadsString(got))
+		}
+
+		_, err := playground.Comments.Replies(6, store.SortCommentsByNew)
+		assertError(t, err, store.ErrorNotFoundComment)
+	})
+	t.Run("Flattens threads in reading order", func(t *testing.T) {
+		playground := newPlayground()
+
+		got := playground.ThreadedComments()
+
+		want := []struct {
+			content string
+			depth   int
+		}{{"1", 0}, {"1.1", 1}, {"1.1.1", 2}, {"1.2", 1}, {"2", 0}}
+		for i, comment := range got {
+			if comment.Content != want[i].content || comment.Depth != want[i].depth {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+	})
+	t.Run("Refuses replies to missing comments and too deep replies", func(t *testing.T) {
+		playground := newPlayground()
+
+		err := playground.AddComment(store.Comment{Author: "Youssef", Content: "reply", ParentID: 10})
+		assertError(t, err, store.ErrorNotFoundComment)
+
+		err = playground.AddComment(store.Comment{Author: "Youssef", Content: "1.1.1.1", ParentID: 5})
+		if err != nil {
+			t.Fatalf("Couldn't add reply, %s", err)
+		}
+		err = playground.AddComment(store.Comment{Author: "Youssef", Content: "1.1.1.1.1", ParentID: 6})
+		assertError(t, err, store.ErrorMaxCommentDepth)
+	})
+	t.Run("Keeps deleted comments with replies", func(t *testing.T) {
+		playground := newPlayground()
+
+		playground.DeleteComment(3)
+
+		deleted, err := playground.FindComment(3)
+		if err != nil || !deleted.Deleted || deleted.Content != store.DeletedCommentContent || deleted.Author != "" {
+			t.Errorf("got %v, want a deleted comment", deleted)
+		}
+		err = playground.AddComment(store.Comment{Author: "Youssef", Content: "reply", ParentID: 3})
+		assertError(t, err, store.ErrorNotFoundComment)
+
+		playground.DeleteComment(5)
+
+		if _, err := playground.FindComment(3); err != store.ErrorNotFoundComment {
+			t.Errorf("Deleted comment should be removed with its last reply")
+		}
+		threads, _ := playground.Comments.Threads(store.SortCommentsByNew)
+		if threadsString(threads) != "[2 1 [1.2]]" {
+			t.Errorf("got %s", threadsString(threads))
+		}
+	})
+}
+
+// threadsString writes the contents of the threads, the replies of a comment being in brackets after it.
+func threadsString(threads []store.CommentThread) string {
+	contents := []string{}
+	for _, thread := range threads {
+		contents = append(contents, thread.Content)
+		if len(thread.Replies) > 0 {
+			contents = append(contents, threadsString(thread.Replies))
+		}
+	}
+	return "[" + strings.Join(contents, " ") + "]"
+}
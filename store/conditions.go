@@ -0,0 +1,160 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	ErrorInvalidConditionScore = errors.New("Condition scores should be between 1 and 5")
+	ErrorEmptyConditionReport  = errors.New("Condition report should score at least one criterion")
+)
+
+const (
+	ConditionSurface     = "surface"
+	ConditionHoops       = "hoops"
+	ConditionLighting    = "lighting"
+	ConditionCrowding    = "crowding"
+	ConditionCleanliness = "cleanliness"
+)
+
+const (
+	MinConditionScore = 1
+	MaxConditionScore = 5
+	// A report counts half as much as a new one after ConditionHalfLife, and not at all after ConditionMaxAge.
+	ConditionHalfLife = 3 * 24 * time.Hour
+	ConditionMaxAge   = 30 * 24 * time.Hour
+)
+
+// ConditionCriteria lists the criteria in display order, with their label.
+// Every score goes from 1 (bad) to 5 (good) : for crowding, 5 means the playground is empty.
+var ConditionCriteria = []struct {
+	Name  string
+	Label string
+}{
+	{ConditionSurface, "Revêtement"},
+	{ConditionHoops, "Paniers et filets"},
+	{ConditionLighting, "Éclairage"},
+	{ConditionCrowding, "Affluence"},
+	{ConditionCleanliness, "Propreté"},
+}
+
+type ConditionReport struct {
+	ID               int            `json:"id"`
+	Author           string         `json:"author"`
+	TimeOfSubmission time.Time      `json:"time_of_submission"`
+	Scores           map[string]int `json:"scores"`
+}
+
+type ConditionReports []ConditionReport
+
+type CriterionCondition struct {
+	Criterion string  `json:"criterion"`
+	Label     string  `json:"label"`
+	Score     float64 `json:"score"`
+	Reports   int     `json:"reports"`
+}
+
+// ConditionSummary only has the criteria scored by a recent report.
+type ConditionSummary struct {
+	Criteria   []CriterionCondition `json:"criteria"`
+	Reports    int                  `json:"reports"`
+	LastReport time.Time            `json:"last_report"`
+}
+
+func (r ConditionReport) validate() error {
+	if r.Author == "" {
+		return ErrEmptyField
+	}
+	if len(r.Scores) == 0 {
+		return ErrorEmptyConditionReport
+	}
+	for criterion, score := range r.Scores {
+		if !isConditionCriterion(criterion) || score < MinConditionScore || score > MaxConditionScore {
+			return ErrorInvalidConditionScore
+		}
+	}
+	return nil
+}
+
+func isConditionCriterion(name string) bool {
+	for _, criterion := range ConditionCriteria {
+		if criterion.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddConditionReport keeps the reports sorted from the newest and forgets the ones older than ConditionMaxAge.
+func (p *Playground) AddConditionReport(report ConditionReport) error {
+	err := report.validate()
+	if err != nil {
+		return err
+	}
+	report.ID = 1
+	for _, conditionReport := range p.ConditionReports {
+		if conditionReport.ID >= report.ID {
+			report.ID = conditionReport.ID + 1
+		}
+	}
+	reports := append(ConditionReports{report}, p.ConditionReports...)
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].TimeOfSubmission.After(reports[j].TimeOfSubmission)
+	})
+	p.ConditionReports = reports.Since(report.TimeOfSubmission.Add(-ConditionMaxAge))
+	return nil
+}
+
+// Since returns the reports submitted after date.
+func (r ConditionReports) Since(date time.Time) ConditionReports {
+	reports := ConditionReports{}
+	for _, report := range r {
+		if report.TimeOfSubmission.After(date) {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+func (p Playground) CurrentCondition() ConditionSummary {
+	return p.ConditionReports.Summary(time.Now())
+}
+
+// Summary averages the scores of each criterion, weighting the reports by their age at now.
+func (r ConditionReports) Summary(now time.Time) ConditionSummary {
+	summary := ConditionSummary{Criteria: []CriterionCondition{}}
+	recentReports := r.Since(now.Add(-ConditionMaxAge))
+	for _, criterion := range ConditionCriteria {
+		var weightedSum, weights float64
+		count := 0
+		for _, report := range recentReports {
+			score, ok := report.Scores[criterion.Name]
+			if !ok {
+				continue
+			}
+			weight := math.Pow(0.5, now.Sub(report.TimeOfSubmission).Hours()/ConditionHalfLife.Hours())
+			weightedSum += weight * float64(score)
+			weights += weight
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+		summary.Criteria = append(summary.Criteria, CriterionCondition{
+			Criterion: criterion.Name,
+			Label:     criterion.Label,
+			Score:     math.Round(weightedSum/weights*10) / 10,
+			Reports:   count,
+		})
+	}
+	summary.Reports = len(recentReports)
+	for _, report := range recentReports {
+		if report.TimeOfSubmission.After(summary.LastReport) {
+			summary.LastReport = report.TimeOfSubmission
+		}
+	}
+	return summary
+}
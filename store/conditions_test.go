@@ -0,0 +1,78 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestConditionReports(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Summary weights recent reports more", func(t *testing.T) {
+		reports := store.ConditionReports{
+			{Author: "Youssef", TimeOfSubmission: now, Scores: map[string]int{store.ConditionSurface: 5}},
+			{Author: "Clélia", TimeOfSubmission: now.Add(-store.ConditionHalfLife), Scores: map[string]int{store.ConditionSurface: 2, store.ConditionHoops: 1}},
+			{Author: "Thibaut", TimeOfSubmission: now.Add(-store.ConditionMaxAge - time.Hour), Scores: map[string]int{store.ConditionLighting: 1}},
+		}
+
+		got := reports.Summary(now)
+
+		want := store.ConditionSummary{
+			Criteria: []store.CriterionCondition{
+				{Criterion: store.ConditionSurface, Label: "Revêtement", Score: 4, Reports: 2},
+				{Criterion: store.ConditionHoops, Label: "Paniers et filets", Score: 1, Reports: 1},
+			},
+			Reports:    2,
+			LastReport: now,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+	t.Run("Summary is empty without recent reports", func(t *testing.T) {
+		got := store.ConditionReports{}.Summary(now)
+
+		if len(got.Criteria) != 0 || got.Reports != 0 {
+			t.Errorf("got %v, want an empty summary", got)
+		}
+	})
+	t.Run("AddConditionReport keeps the newest reports first and drops old ones", func(t *testing.T) {
+		playground := store.Playground{}
+		playground.AddConditionReport(store.ConditionReport{Author: "Youssef", TimeOfSubmission: now.Add(-store.ConditionMaxAge), Scores: map[string]int{store.ConditionSurface: 3}})
+		playground.AddConditionReport(store.ConditionReport{Author: "Clélia", TimeOfSubmission: now.Add(-time.Hour), Scores: map[string]int{store.ConditionSurface: 4}})
+		err := playground.AddConditionReport(store.ConditionReport{Author: "Thibaut", TimeOfSubmission: now, Scores: map[string]int{store.ConditionCrowding: 1}})
+		if err != nil {
+			t.Fatalf("Couldn't add report, %s", err)
+		}
+
+		if len(playground.ConditionReports) != 2 || playground.ConditionReports[0].ID != 3 || playground.ConditionReports[1].ID != 2 {
+			t.Errorf("got %v", playground.ConditionReports)
+		}
+	})
+	t.Run("AddConditionReport refuses invalid reports", func(t *testing.T) {
+		cases := map[string]struct {
+			report store.ConditionReport
+			want   error
+		}{
+			"no score":          {store.ConditionReport{Author: "Youssef"}, store.ErrorEmptyConditionReport},
+			"score too high":    {store.ConditionReport{Author: "Youssef", Scores: map[string]int{store.ConditionSurface: 6}}, store.ErrorInvalidConditionScore},
+			"unknown criterion": {store.ConditionReport{Author: "Youssef", Scores: map[string]int{"wind": 3}}, store.ErrorInvalidConditionScore},
+			"no author":         {store.ConditionReport{Scores: map[string]int{store.ConditionSurface: 3}}, store.ErrEmptyField},
+		}
+		for name, c := range cases {
+			t.Run(name, func(t *testing.T) {
+				playground := store.Playground{}
+
+				err := playground.AddConditionReport(c.report)
+
+				assertError(t, err, c.want)
+				if len(playground.ConditionReports) != 0 {
+					t.Errorf("Report shouldn't be added")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"sort"
+	"strconv"
+)
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type Facets struct {
+	Types       []FacetValue `json:"type"`
+	Coatings    []FacetValue `json:"coating"`
+	Departments []FacetValue `json:"department"`
+	Cities      []FacetValue `json:"city"`
+	Open        []FacetValue `json:"open"`
+}
+
+// Facets counts the playgrounds matching filter for each type, coating, department, city and open value.
+// The criterion of a facet is left out when counting it, so that the counts of the other values of a multi-valued filter stay visible.
+func (p Playgrounds) Facets(filter PlaygroundFilter) Facets {
+	withoutTypes, withoutCoatings, withoutDepartments, withoutCities, withoutOpen := filter, filter, filter, filter, filter
+	withoutTypes.Types = nil
+	withoutCoatings.Coatings = nil
+	withoutDepartments.Departments = nil
+	withoutCities.Cities = nil
+	withoutOpen.Open = nil
+
+	return Facets{
+		Types:       p.Filter(withoutTypes).countBy(func(playground Playground) string { return playground.Type }),
+		Coatings:    p.Filter(withoutCoatings).countBy(func(playground Playground) string { return playground.Coating }),
+		Departments: p.Filter(withoutDepartments).countBy(func(playground Playground) string { return playground.Department }),
+		Cities:      p.Filter(withoutCities).countBy(func(playground Playground) string { return playground.City }),
+		Open:        p.Filter(withoutOpen).countBy(func(playground Playground) string { return strconv.FormatBool(playground.Open) }),
+	}
+}
+
+// countBy groups values regardless of case and accents, like the filter does, keeping the first spelling found.
+// Empty values aren't counted, the most frequent values come first.
+func (p Playgrounds) countBy(value func(Playground) string) []FacetValue {
+	facetValues := []FacetValue{}
+	positions := make(map[string]int)
+	for _, playground := range p {
+		value := value(playground)
+		key := normalizeText(value)
+		if key == "" {
+			continue
+		}
+		position, ok := positions[key]
+		if !ok {
+			position = len(facetValues)
+			positions[key] = position
+			facetValues = append(facetValues, FacetValue{Value: value})
+		}
+		facetValues[position].Count++
+	}
+	sort.SliceStable(facetValues, func(i, j int) bool {
+		if facetValues[i].Count != facetValues[j].Count {
+			return facetValues[i].Count > facetValues[j].Count
+		}
+		return normalizeText(facetValues[i].Value) < normalizeText(facetValues[j].Value)
+	})
+	return facetValues
+}
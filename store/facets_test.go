@@ -0,0 +1,47 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestFacets(t *testing.T) {
+	playgrounds := store.Playgrounds{
+		{ID: 1, City: "Paris", Department: "Paris", Type: "Basket", Coating: "Bitume", Open: true},
+		{ID: 2, City: "paris", Department: "Paris", Type: "Basket", Coating: "Synthétique", Open: false},
+		{ID: 3, City: "Montreuil", Department: "Seine-Saint-Denis", Type: "Football", Coating: "synthetique", Open: true},
+		{ID: 4, City: "Montreuil", Department: "Seine-Saint-Denis", Type: "Basket", Open: true},
+	}
+
+	t.Run("Counts every value without filter", func(t *testing.T) {
+		got := playgrounds.Facets(store.PlaygroundFilter{})
+
+		want := store.Facets{
+			Types:       []store.FacetValue{{"Basket", 3}, {"Football", 1}},
+			Coatings:    []store.FacetValue{{"Synthétique", 2}, {"Bitume", 1}},
+			Departments: []store.FacetValue{{"Paris", 2}, {"Seine-Saint-Denis", 2}},
+			Cities:      []store.FacetValue{{"Montreuil", 2}, {"Paris", 2}},
+			Open:        []store.FacetValue{{"true", 3}, {"false", 1}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+	t.Run("Counts the playgrounds matching the other criteria", func(t *testing.T) {
+		open := true
+		got := playgrounds.Facets(store.PlaygroundFilter{Types: []string{"Basket"}, Open: &open})
+
+		want := store.Facets{
+			Types:       []store.FacetValue{{"Basket", 2}, {"Football", 1}},
+			Coatings:    []store.FacetValue{{"Bitume", 1}},
+			Departments: []store.FacetValue{{"Paris", 1}, {"Seine-Saint-Denis", 1}},
+			Cities:      []store.FacetValue{{"Montreuil", 1}, {"Paris", 1}},
+			Open:        []store.FacetValue{{"true", 2}, {"false", 1}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrorUnknownSort = errors.New("Unknown sort")
+
+const (
+	SortByName     = "name"
+	SortByDistance = "distance"
+	SortByNewest   = "newest"
+	SortByRating   = "rating"
+)
+
+// PlaygroundFilter keeps the playgrounds matching every non empty criterion.
+// A criterion with several values matches any of them, text is compared regardless of case and accents.
+type PlaygroundFilter struct {
+	Cities      []string
+	Departments []string
+	PostalCodes []string
+	Types       []string
+	Coatings    []string
+	Open        *bool
+	// When set, only the playgrounds with known opening hours open at that time are kept.
+	OpenAt time.Time
+	// Every word of Query has to appear in the name, address, postal code or city.
+	Query string
+}
+
+func (f PlaygroundFilter) Match(playground Playground) bool {
+	if !matchAny(f.Cities, playground.City) ||
+		!matchAny(f.Departments, playground.Department) ||
+		!matchAny(f.PostalCodes, playground.PostalCode) ||
+		!matchAny(f.Types, playground.Type) ||
+		!matchAny(f.Coatings, playground.Coating) {
+		return false
+	}
+	if f.Open != nil && *f.Open != playground.Open {
+		return false
+	}
+	if !f.OpenAt.IsZero() {
+		if open, _ := playground.IsOpenAt(f.OpenAt); !open {
+			return false
+		}
+	}
+	if query := normalizeText(f.Query); query != "" {
+		text := normalizeText(strings.Join([]string{playground.Name, playground.Address, playground.PostalCode, playground.City}, " "))
+		for _, word := range strings.Fields(query) {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	value = normalizeText(value)
+	for _, wanted := range values {
+		if normalizeText(wanted) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Playgrounds) Filter(filter PlaygroundFilter) Playgrounds {
+	playgrounds := Playgrounds{}
+	for _, playground := range p {
+		if filter.Match(playground) {
+			playgrounds = append(playgrounds, playground)
+		}
+	}
+	return playgrounds
+}
+
+// Sort returns a sorted copy of the playgrounds, long and lat are only used to sort by distance.
+// Ties keep their current order.
+func (p Playgrounds) Sort(key string, long, lat float64) (Playgrounds, error) {
+	playgrounds := make(Playgrounds, len(p))
+	copy(playgrounds, p)
+	switch key {
+	case SortByName:
+		sort.SliceStable(playgrounds, func(i, j int) bool {
+			return strings.ToLower(playgrounds[i].Name) < strings.ToLower(playgrounds[j].Name)
+		})
+	case SortByDistance:
+		return p.sortByProximity(long, lat), nil
+	case SortByNewest:
+		sort.SliceStable(playgrounds, func(i, j int) bool {
+			return playgrounds[i].TimeOfSubmission.After(playgrounds[j].TimeOfSubmission)
+		})
+	case SortByRating:
+		sort.SliceStable(playgrounds, func(i, j int) bool {
+			return isBetterRated(playgrounds[i], playgrounds[j])
+		})
+	default:
+		return nil, ErrorUnknownSort
+	}
+	return playgrounds, nil
+}
+
+// Sort returns a sorted copy of the nearby playgrounds, they are already sorted by distance.
+func (n NearbyPlaygrounds) Sort(key string) (NearbyPlaygrounds, error) {
+	nearbyPlaygrounds := make(NearbyPlaygrounds, len(n))
+	copy(nearbyPlaygrounds, n)
+	switch key {
+	case SortByDistance:
+	case SortByRating:
+		sort.SliceStable(nearbyPlaygrounds, func(i, j int) bool {
+			return isBetterRated(nearbyPlaygrounds[i].Playground, nearbyPlaygrounds[j].Playground)
+		})
+	default:
+		return nil, ErrorUnknownSort
+	}
+	return nearbyPlaygrounds, nil
+}
+
+// isBetterRated compares averages, then the number of ratings which makes an average more reliable.
+func isBetterRated(a, b Playground) bool {
+	if a.AverageRating != b.AverageRating {
+		return a.AverageRating > b.AverageRating
+	}
+	return a.RatingsCount > b.RatingsCount
+}
+
+// OpenAt keeps the first limit playgrounds open at t, a zero limit keeping all of them.
+func (n NearbyPlaygrounds) OpenAt(t time.Time, limit int) NearbyPlaygrounds {
+	openPlaygrounds := NearbyPlaygrounds{}
+	for _, nearbyPlayground := range n {
+		if limit > 0 && len(openPlaygrounds) == limit {
+			break
+		}
+		if open, _ := nearbyPlayground.IsOpenAt(t); open {
+			openPlaygrounds = append(openPlaygrounds, nearbyPlayground)
+		}
+	}
+	return openPlaygrounds
+}
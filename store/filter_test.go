@@ -0,0 +1,74 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestFilter(t *testing.T) {
+	open, covered := true, false
+	playgrounds := store.Playgrounds{
+		{ID: 1, Name: "Curial", Address: "1 rue Curial", PostalCode: "75019", City: "Paris", Department: "Paris", Type: "Basket", Coating: "Bitume", Open: true, Long: 2.372, Lat: 48.893},
+		{ID: 2, Name: "Duruy", Address: "Rue Éugène Sue", PostalCode: "75018", City: "Paris", Department: "Paris", Type: "Basket", Coating: "Synthétique (hors gazon)", Open: false, Long: 2.349, Lat: 48.891},
+		{ID: 3, Name: "Fénelon", Address: "12 avenue de la République", PostalCode: "93100", City: "Montreuil", Department: "Seine-Saint-Denis", Type: "Football", Coating: "Gazon synthétique", Open: true, Long: 2.44, Lat: 48.86},
+	}
+
+	cases := []struct {
+		name   string
+		filter store.PlaygroundFilter
+		want   []int
+	}{
+		{"no filter", store.PlaygroundFilter{}, []int{1, 2, 3}},
+		{"city ignoring case", store.PlaygroundFilter{Cities: []string{"montreuil"}}, []int{3}},
+		{"several postal codes", store.PlaygroundFilter{PostalCodes: []string{"75018", "93100"}}, []int{2, 3}},
+		{"department", store.PlaygroundFilter{Departments: []string{"Seine-Saint-Denis"}}, []int{3}},
+		{"coating ignoring accents", store.PlaygroundFilter{Coatings: []string{"synthetique (hors gazon)"}}, []int{2}},
+		{"uncovered", store.PlaygroundFilter{Open: &open}, []int{1, 3}},
+		{"covered", store.PlaygroundFilter{Open: &covered}, []int{2}},
+		{"combined", store.PlaygroundFilter{Types: []string{"Basket"}, Open: &open}, []int{1}},
+		{"text in name", store.PlaygroundFilter{Query: "fenelon"}, []int{3}},
+		{"every word of the text", store.PlaygroundFilter{Query: "rue eugene"}, []int{2}},
+		{"text in postal code", store.PlaygroundFilter{Query: "7501"}, []int{1, 2}},
+		{"nothing matches", store.PlaygroundFilter{Query: "Marseille"}, []int{}},
+	}
+	for _, c := range cases {
+		t.Run("Filters by "+c.name, func(t *testing.T) {
+			assertIDs(t, playgrounds.Filter(c.filter), c.want)
+		})
+	}
+
+	t.Run("Sort", func(t *testing.T) {
+		playgrounds[1].TimeOfSubmission = time.Now()
+		playgrounds[2].TimeOfSubmission = time.Now().Add(-time.Hour)
+
+		got, _ := playgrounds.Sort(store.SortByNewest, 0, 0)
+		assertIDs(t, got, []int{2, 3, 1})
+
+		got, _ = playgrounds.Sort(store.SortByDistance, 2.44, 48.86)
+		assertIDs(t, got, []int{3, 1, 2})
+
+		got, _ = got.Sort(store.SortByName, 0, 0)
+		assertIDs(t, got, []int{1, 2, 3})
+
+		_, err := playgrounds.Sort("size", 0, 0)
+		assertError(t, err, store.ErrorUnknownSort)
+	})
+}
+
+func assertIDs(t *testing.T, playgrounds store.Playgrounds, want []int) {
+	t.Helper()
+	got := make([]int, len(playgrounds))
+	for i, playground := range playgrounds {
+		got[i] = playground.ID
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got IDs %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got IDs %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrorNotFoundCorrection = errors.New("Correction doesn't exist")
+
+// Above this distance in metres, stored coordinates are reported as suspicious.
+const DefaultMaxGeocodingDistance = 200
+
+// Reasons why a playground is reported by CheckGeocoding.
+const (
+	OutlierDistance   = "distance"
+	OutlierPostalCode = "postal_code"
+	OutlierInseeCode  = "insee_code"
+	OutlierLowScore   = "low_score"
+	OutlierNotFound   = "not_found"
+)
+
+// INSEE codes of Paris, Lyon and Marseille and of their arrondissements, often typed instead of a postal code.
+// 75116 is left out as it is both the INSEE code of the 16th arrondissement and one of its postal codes.
+var inseeCodeRegexp = regexp.MustCompile(`^(75056|69123|13055|751(0[1-9]|1[0-57-9]|20)|6938[1-9]|132(0[1-9]|1[0-6]))$`)
+
+type GeocodingOutlier struct {
+	PlaygroundID int      `json:"playground_id"`
+	Name         string   `json:"name"`
+	Address      string   `json:"address"`
+	PostalCode   string   `json:"postal_code"`
+	City         string   `json:"city"`
+	Long         float64  `json:"long"`
+	Lat          float64  `json:"lat"`
+	Location     Location `json:"location"`
+	Distance     float64  `json:"distance"`
+	Reasons      []string `json:"reasons"`
+	Error        string   `json:"error,omitempty"`
+}
+
+type GeocodingReport struct {
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Checked    int                `json:"checked"`
+	Outliers   []GeocodingOutlier `json:"outliers"`
+}
+
+// CheckGeocoding geocodes every playground again and reports the ones whose stored location disagrees with the geocoder.
+// Outliers are sorted from the furthest to the nearest.
+func (p Playgrounds) CheckGeocoding(ctx context.Context, client GeolocationClient, maxDistance float64) GeocodingReport {
+	report := GeocodingReport{StartedAt: time.Now(), Outliers: []GeocodingOutlier{}}
+	for _, playground := range p {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Checked++
+		outlier, isOutlier := playground.checkGeocoding(ctx, client, maxDistance)
+		if isOutlier {
+			report.Outliers = append(report.Outliers, outlier)
+		}
+	}
+	sort.SliceStable(report.Outliers, func(i, j int) bool {
+		return report.Outliers[i].Distance > report.Outliers[j].Distance
+	})
+	report.FinishedAt = time.Now()
+	return report
+}
+
+func (p Playground) checkGeocoding(ctx context.Context, client GeolocationClient, maxDistance float64) (GeocodingOutlier, bool) {
+	outlier := GeocodingOutlier{
+		PlaygroundID: p.ID,
+		Name:         p.Name,
+		Address:      p.Address,
+		PostalCode:   p.PostalCode,
+		City:         p.City,
+		Long:         p.Long,
+		Lat:          p.Lat,
+		Reasons:      []string{},
+	}
+	if inseeCodeRegexp.MatchString(p.PostalCode) {
+		outlier.Reasons = append(outlier.Reasons, OutlierInseeCode)
+	}
+
+	// The postal code is left out when it is an INSEE code, it would mislead the geocoder.
+	address := fmt.Sprintf("%s %s %s", p.Address, p.PostalCode, p.City)
+	if len(outlier.Reasons) > 0 {
+		address = fmt.Sprintf("%s %s", p.Address, p.City)
+	}
+	location, err := client.Geocode(ctx, address)
+	if err != nil {
+		outlier.Reasons = append(outlier.Reasons, OutlierNotFound)
+		if err != ErrorNotFoundLocation {
+			outlier.Error = err.Error()
+		}
+		return outlier, true
+	}
+
+	outlier.Location = location
+	outlier.Distance = p.DistanceFrom(location.Long, location.Lat)
+	if location.Score < MinGeocodingScore {
+		outlier.Reasons = append(outlier.Reasons, OutlierLowScore)
+	}
+	if outlier.Distance > maxDistance {
+		outlier.Reasons = append(outlier.Reasons, OutlierDistance)
+	}
+	if location.PostalCode != "" && location.PostalCode != p.PostalCode {
+		outlier.Reasons = append(outlier.Reasons, OutlierPostalCode)
+	}
+	return outlier, len(outlier.Reasons) > 0
+}
+
+// HasSuggestion tells whether the geocoder's answer is trusted enough to be proposed to moderators.
+func (o GeocodingOutlier) HasSuggestion() bool {
+	return o.Error == "" && o.Location.Score >= MinGeocodingScore
+}
+
+type GeocodingCorrection struct {
+	ID               int              `json:"id"`
+	Outlier          GeocodingOutlier `json:"outlier"`
+	TimeOfSubmission time.Time        `json:"time_of_submission"`
+}
+
+type GeocodingCorrections []GeocodingCorrection
+
+// CorrectionStore keeps the geocoding corrections waiting for a moderator.
+type CorrectionStore struct {
+	mutex       sync.Mutex
+	corrections GeocodingCorrections
+	lastID      int
+}
+
+// Queue adds a correction for every outlier having a trusted suggestion, replacing the pending correction of the same playground.
+// It returns the number of queued corrections.
+func (c *CorrectionStore) Queue(outliers []GeocodingOutlier) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	queued := 0
+	for _, outlier := range outliers {
+		if !outlier.HasSuggestion() {
+			continue
+		}
+		for i, correction := range c.corrections {
+			if correction.Outlier.PlaygroundID == outlier.PlaygroundID {
+				c.corrections = append(c.corrections[:i], c.corrections[i+1:]...)
+				break
+			}
+		}
+		c.lastID++
+		c.corrections = append(c.corrections, GeocodingCorrection{ID: c.lastID, Outlier: outlier, TimeOfSubmission: time.Now()})
+		queued++
+	}
+	return queued
+}
+
+func (c *CorrectionStore) AllCorrections() GeocodingCorrections {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	corrections := make(GeocodingCorrections, len(c.corrections))
+	copy(corrections, c.corrections)
+	return corrections
+}
+
+func (c *CorrectionStore) Correction(ID int) (GeocodingCorrection, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, correction := range c.corrections {
+		if correction.ID == ID {
+			return correction, nil
+		}
+	}
+	return GeocodingCorrection{}, ErrorNotFoundCorrection
+}
+
+func (c *CorrectionStore) DeleteCorrection(ID int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i, correction := range c.corrections {
+		if correction.ID == ID {
+			c.corrections = append(c.corrections[:i], c.corrections[i+1:]...)
+			return
+		}
+	}
+}
+
+// ApplyCorrection moves the playground to the suggested location and fixes its postal code, then removes the correction.
+func (d *PlaygroundDatabase) ApplyCorrection(correctionID int) error {
+	correction, err := d.Corrections.Correction(correctionID)
+	if err != nil {
+		return err
+	}
+	playground, err := d.MainPlaygroundStore.Playground(correction.Outlier.PlaygroundID)
+	if err != nil {
+		return err
+	}
+	location := correction.Outlier.Location
+	playground.Long = location.Long
+	playground.Lat = location.Lat
+	if location.PostalCode != "" {
+		playground.PostalCode = location.PostalCode
+	}
+	playground.GeocodingScore = location.Score
+	playground.GeocodedAddress = location.Label
+	playground.GeocodingSource = location.Provider
+	playground.LowConfidence = false
+
+	err = d.MainPlaygroundStore.UpdatePlayground(playground)
+	if err != nil {
+		return err
+	}
+	d.Corrections.DeleteCorrection(correctionID)
+	return nil
+}
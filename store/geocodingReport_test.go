@@ -0,0 +1,150 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+// mapClient geocodes the addresses it knows, any other address is not found.
+type mapClient struct {
+	stubClient
+	locations map[string]store.Location
+	err       error
+}
+
+func (m mapClient) Geocode(ctx context.Context, address string) (store.Location, error) {
+	if m.err != nil {
+		return store.Location{}, m.err
+	}
+	location, ok := m.locations[address]
+	if !ok {
+		return store.Location{}, store.ErrorNotFoundLocation
+	}
+	return location, nil
+}
+
+func TestCheckGeocoding(t *testing.T) {
+	flandre := store.Location{Long: 2.372452, Lat: 48.886835, Score: 0.9, Label: "42 Avenue de Flandre 75019 Paris", PostalCode: "75019", Provider: "stub"}
+	client := mapClient{locations: map[string]store.Location{
+		"42 avenue de Flandre 75019 Paris": flandre,
+		"42 avenue de Flandre Paris":       flandre,
+		"Rue de Rivoli 75001 Paris":        {Long: 2.35, Lat: 48.86, Score: 0.4, PostalCode: "75001"},
+		"1 rue Curial 75018 Paris":         {Long: 2.372, Lat: 48.893, Score: 0.9, PostalCode: "75019"},
+	}}
+
+	t.Run("Reports nothing when the stored location matches", func(t *testing.T) {
+		playgrounds := store.Playgrounds{
+			{ID: 1, Address: "42 avenue de Flandre", PostalCode: "75019", City: "Paris", Long: 2.3725, Lat: 48.8869},
+		}
+
+		report := playgrounds.CheckGeocoding(context.Background(), client, store.DefaultMaxGeocodingDistance)
+
+		if report.Checked != 1 || len(report.Outliers) != 0 {
+			t.Errorf("got %d checked and %v outliers, want 1 checked and no outlier", report.Checked, report.Outliers)
+		}
+	})
+	t.Run("Reports outliers with their reasons, furthest first", func(t *testing.T) {
+		playgrounds := store.Playgrounds{
+			{ID: 1, Name: "far", Address: "42 avenue de Flandre", PostalCode: "75019", City: "Paris", Long: 2.30, Lat: 48.80},
+			{ID: 2, Name: "insee", Address: "42 avenue de Flandre", PostalCode: "75056", City: "Paris", Long: 2.372452, Lat: 48.886835},
+			{ID: 3, Name: "vague", Address: "Rue de Rivoli", PostalCode: "75001", City: "Paris", Long: 2.35, Lat: 48.86},
+			{ID: 4, Name: "postal code", Address: "1 rue Curial", PostalCode: "75018", City: "Paris", Long: 2.372, Lat: 48.893},
+			{ID: 5, Name: "unknown", Address: "nowhere", PostalCode: "75019", City: "Paris"},
+		}
+
+		report := playgrounds.CheckGeocoding(context.Background(), client, store.DefaultMaxGeocodingDistance)
+
+		if report.Checked != 5 {
+			t.Errorf("got %d checked, want 5", report.Checked)
+		}
+		want := map[string][]string{
+			"far":         {store.OutlierDistance},
+			"insee":       {store.OutlierInseeCode, store.OutlierPostalCode},
+			"vague":       {store.OutlierLowScore},
+			"postal code": {store.OutlierPostalCode},
+			"unknown":     {store.OutlierNotFound},
+		}
+		if len(report.Outliers) != len(want) {
+			t.Fatalf("got %d outliers, want %d", len(report.Outliers), len(want))
+		}
+		for _, outlier := range report.Outliers {
+			if !reflect.DeepEqual(outlier.Reasons, want[outlier.Name]) {
+				t.Errorf("%s : got reasons %v, want %v", outlier.Name, outlier.Reasons, want[outlier.Name])
+			}
+		}
+		if report.Outliers[0].Name != "far" || report.Outliers[0].Distance < 10000 {
+			t.Errorf("Furthest outlier should be first, got %v", report.Outliers[0])
+		}
+	})
+	t.Run("Keeps the geocoder error in the report", func(t *testing.T) {
+		playgrounds := store.Playgrounds{{ID: 1, Address: "42 avenue de Flandre", PostalCode: "75019", City: "Paris"}}
+		failingClient := mapClient{err: errors.New("timeout")}
+
+		report := playgrounds.CheckGeocoding(context.Background(), failingClient, store.DefaultMaxGeocodingDistance)
+
+		if len(report.Outliers) != 1 || report.Outliers[0].Error != "timeout" || report.Outliers[0].HasSuggestion() {
+			t.Errorf("got %v", report.Outliers)
+		}
+	})
+}
+
+func TestGeocodingCorrections(t *testing.T) {
+	file, removeFile := createTempFile(t, `[
+		{"Name": "aaaa", "Address": "42 avenue de Flandre", "PostalCode": "75056", "City": "Paris", "Long": 2.30, "Lat": 48.80},
+		{"Name": "bbbb", "Address": "Rue de Rivoli", "PostalCode": "75001", "City": "Paris"}]`)
+	defer removeFile()
+	str, _ := store.New(file)
+	database := store.PlaygroundDatabase{
+		MainPlaygroundStore:      str,
+		SubmittedPlaygroundStore: &store.SubmittedPlaygroundStore{},
+		Corrections:              &store.CorrectionStore{},
+	}
+	suggestion := store.Location{Long: 2.372452, Lat: 48.886835, Score: 0.9, Label: "42 Avenue de Flandre 75019 Paris", PostalCode: "75019", Provider: "stub"}
+	outliers := []store.GeocodingOutlier{
+		{PlaygroundID: 1, Location: suggestion, Reasons: []string{store.OutlierDistance}},
+		{PlaygroundID: 2, Location: store.Location{Score: 0.3}, Reasons: []string{store.OutlierLowScore}},
+	}
+
+	t.Run("Queue only keeps outliers with a trusted suggestion", func(t *testing.T) {
+		queued := database.Corrections.Queue(outliers)
+
+		if queued != 1 || len(database.Corrections.AllCorrections()) != 1 {
+			t.Errorf("got %d queued corrections, want 1", queued)
+		}
+	})
+	t.Run("Queue replaces the pending correction of a playground", func(t *testing.T) {
+		database.Corrections.Queue(outliers)
+
+		corrections := database.Corrections.AllCorrections()
+		if len(corrections) != 1 || corrections[0].ID != 2 {
+			t.Errorf("got %v, want a single correction with ID 2", corrections)
+		}
+	})
+	t.Run("ApplyCorrection moves the playground and removes the correction", func(t *testing.T) {
+		err := database.ApplyCorrection(2)
+		if err != nil {
+			t.Fatalf("Couldn't apply correction, %s", err)
+		}
+
+		playground, _ := str.Playground(1)
+		if playground.Long != suggestion.Long || playground.Lat != suggestion.Lat || playground.PostalCode != "75019" || playground.GeocodingSource != "stub" {
+			t.Errorf("Playground wasn't corrected, got %v", playground)
+		}
+		nearest := str.NearestPlaygrounds(suggestion.Long, suggestion.Lat, 0, 1)
+		if len(nearest) != 1 || nearest[0].ID != 1 || nearest[0].Distance != 0 {
+			t.Errorf("Spatial index wasn't updated, got %v", nearest)
+		}
+		if len(database.Corrections.AllCorrections()) != 0 {
+			t.Errorf("Correction should be removed")
+		}
+	})
+	t.Run("ApplyCorrection returns an error if the correction doesn't exist", func(t *testing.T) {
+		err := database.ApplyCorrection(2)
+
+		assertError(t, err, store.ErrorNotFoundCorrection)
+	})
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownLinkRegexp      = regexp.MustCompile(`\[([^\]\n]+)\]\((https?://[^\s)]+)\)|https?://[^\s<]+[^\s<.,;:!?)]`)
+	markdownStrongRegexp    = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+	markdownEmphasisRegexp  = regexp.MustCompile(`\*([^*\n]+)\*`)
+	markdownUnderlineRegexp = regexp.MustCompile(`\b_([^_\n]+)_\b`)
+	markdownUnorderedItem   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	markdownOrderedItem     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+)
+
+// RenderMarkdown converts a limited Markdown to HTML : paragraphs, line breaks, lists, emphasis and http(s) links.
+// The text is escaped first, so any HTML written by the user is shown as text.
+func RenderMarkdown(text string) string {
+	var rendered strings.Builder
+	block := ""
+	closeBlock := func() {
+		if block != "" {
+			rendered.WriteString("</" + block + ">")
+			block = ""
+		}
+	}
+	openBlock := func(tag string) bool {
+		if block == tag {
+			return false
+		}
+		closeBlock()
+		rendered.WriteString("<" + tag + ">")
+		block = tag
+		return true
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			closeBlock()
+			continue
+		}
+		if item := markdownUnorderedItem.FindStringSubmatch(line); item != nil {
+			openBlock("ul")
+			rendered.WriteString("<li>" + renderInlineMarkdown(item[1]) + "</li>")
+			continue
+		}
+		if item := markdownOrderedItem.FindStringSubmatch(line); item != nil {
+			openBlock("ol")
+			rendered.WriteString("<li>" + renderInlineMarkdown(item[1]) + "</li>")
+			continue
+		}
+		if !openBlock("p") {
+			rendered.WriteString("<br>")
+		}
+		rendered.WriteString(renderInlineMarkdown(strings.TrimSpace(line)))
+	}
+	closeBlock()
+	return rendered.String()
+}
+
+// renderInlineMarkdown renders the links, and the emphasis outside of them.
+func renderInlineMarkdown(text string) string {
+	var rendered strings.Builder
+	position := 0
+	for _, match := range markdownLinkRegexp.FindAllStringSubmatchIndex(text, -1) {
+		rendered.WriteString(renderEmphasis(text[position:match[0]]))
+		label, URL := text[match[0]:match[1]], text[match[0]:match[1]]
+		if match[2] >= 0 {
+			label, URL = text[match[2]:match[3]], text[match[4]:match[5]]
+		}
+		rendered.WriteString(`<a href="` + html.EscapeString(URL) + `" rel="nofollow ugc noopener" target="_blank">` + html.EscapeString(label) + "</a>")
+		position = match[1]
+	}
+	rendered.WriteString(renderEmphasis(text[position:]))
+	return rendered.String()
+}
+
+func renderEmphasis(text string) string {
+	text = html.EscapeString(text)
+	text = markdownStrongRegexp.ReplaceAllString(text, "<strong>$1</strong>")
+	text = markdownEmphasisRegexp.ReplaceAllString(text, "<em>$1</em>")
+	return markdownUnderlineRegexp.ReplaceAllString(text, "<em>$1</em>")
+}
+
+// HTML renders the content of the comment, see RenderMarkdown.
+func (c Comment) HTML() string {
+	return RenderMarkdown(c.Content)
+}
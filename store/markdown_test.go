@@ -0,0 +1,51 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	cases := map[string]struct {
+		text string
+		want string
+	}{
+		"Paragraphs and line breaks": {
+			"Super terrain\nbien éclairé\n\nÀ bientôt",
+			"<p>Super terrain<br>bien éclairé</p><p>À bientôt</p>",
+		},
+		"Emphasis": {
+			"**Attention** le *panier* est _tordu_ mais pas snake_case_name",
+			"<p><strong>Attention</strong> le <em>panier</em> est <em>tordu</em> mais pas snake_case_name</p>",
+		},
+		"Lists": {
+			"À prévoir :\n- un ballon\n* de l'eau\n1. arriver tôt\n2. s'échauffer",
+			"<p>À prévoir :</p><ul><li>un ballon</li><li>de l&#39;eau</li></ul><ol><li>arriver tôt</li><li>s&#39;échauffer</li></ol>",
+		},
+		"Links": {
+			"Voir [le site](https://paris.fr/sport?a=1&b=2) ou (https://www.paris.fr/terrains).",
+			`<p>Voir <a href="https://paris.fr/sport?a=1&amp;b=2" rel="nofollow ugc noopener" target="_blank">le site</a> ou (<a href="https://www.paris.fr/terrains" rel="nofollow ugc noopener" target="_blank">https://www.paris.fr/terrains</a>).</p>`,
+		},
+		"Emphasis isn't applied inside links": {
+			"https://exemple.fr/a_b_c/*x*",
+			`<p><a href="https://exemple.fr/a_b_c/*x*" rel="nofollow ugc noopener" target="_blank">https://exemple.fr/a_b_c/*x*</a></p>`,
+		},
+		"HTML is escaped": {
+			`<script>alert("x")</script> <b>gras</b>`,
+			"<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &lt;b&gt;gras&lt;/b&gt;</p>",
+		},
+		"Only http links are rendered": {
+			`[clic](javascript:alert(1)) [clic](https://a.fr/"onmouseover="alert(1))`,
+			`<p>[clic](javascript:alert(1)) <a href="https://a.fr/&#34;onmouseover=&#34;alert(1" rel="nofollow ugc noopener" target="_blank">clic</a>)</p>`,
+		},
+	}
+	for description, c := range cases {
+		t.Run(description, func(t *testing.T) {
+			got := store.RenderMarkdown(c.text)
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrorAlreadyReported         = errors.New("Comment already reported by this user")
+	ErrorInvalidReportReason     = errors.New("Unknown report reason")
+	ErrorUnknownModerationAction = errors.New("Unknown moderation action")
+)
+
+const (
+	ReportSpam     = "spam"
+	ReportAbuse    = "abuse"
+	ReportOffTopic = "off_topic"
+	ReportOther    = "other"
+)
+
+const (
+	ModerationHide    = "hide"
+	ModerationUnhide  = "unhide"
+	ModerationDelete  = "delete"
+	ModerationDismiss = "dismiss"
+	// Shadow-ban actions apply to a user, not to a comment.
+	ModerationShadowBan     = "shadow_ban"
+	ModerationLiftShadowBan = "lift_shadow_ban"
+	// Photo actions.
+	ModerationApprove = "approve"
+	ModerationReject  = "reject"
+)
+
+const HiddenCommentContent = "[hidden]"
+
+type CommentReport struct {
+	PlaygroundID     int       `json:"playground_id"`
+	CommentID        int       `json:"comment_id"`
+	Reporter         string    `json:"reporter"`
+	Reason           string    `json:"reason"`
+	Details          string    `json:"details"`
+	TimeOfSubmission time.Time `json:"time_of_submission"`
+}
+
+// ReportedComment is an entry of the moderation queue, Content and Author are sent even if the comment is hidden.
+type ReportedComment struct {
+	PlaygroundID   int             `json:"playground_id"`
+	PlaygroundName string          `json:"playground_name"`
+	CommentID      int             `json:"comment_id"`
+	Content        string          `json:"content"`
+	Author         string          `json:"author"`
+	Hidden         bool            `json:"hidden"`
+	Reports        []CommentReport `json:"reports"`
+	// Versions has every version of an edited comment, from the oldest.
+	Versions []CommentVersion `json:"versions"`
+}
+
+// ModerationAction is an audit record, it keeps the comment as it was when the action was taken.
+type ModerationAction struct {
+	ID           int       `json:"id"`
+	Moderator    string    `json:"moderator"`
+	Action       string    `json:"action"`
+	Reason       string    `json:"reason"`
+	PlaygroundID int       `json:"playground_id"`
+	CommentID    int       `json:"comment_id"`
+	PhotoID      int       `json:"photo_id"`
+	Content      string    `json:"content"`
+	Author       string    `json:"author"`
+	Time         time.Time `json:"time"`
+}
+
+// ModerationStore keeps the pending reports and the audit log of moderation actions.
+type ModerationStore struct {
+	mutex        sync.Mutex
+	reports      []CommentReport
+	actions      []ModerationAction
+	lastActionID int
+	shadowBanned map[string]bool
+}
+
+func isReportReason(reason string) bool {
+	switch reason {
+	case ReportSpam, ReportAbuse, ReportOffTopic, ReportOther:
+		return true
+	}
+	return false
+}
+
+// masked returns the comment as shown to users.
+func (c Comment) masked() Comment {
+	if c.Hidden && !c.Deleted {
+		c.Content = HiddenCommentContent
+		c.Author = ""
+	}
+	return c
+}
+
+// MarshalJSON masks the content and author of hidden comments, moderators read them from the moderation queue.
+func (c Comment) MarshalJSON() ([]byte, error) {
+	type comment Comment
+	return json.Marshal(comment(c.masked()))
+}
+
+// MarshalJSON is needed as CommentThread would otherwise use the method of the embedded Comment and lose its replies.
+func (c CommentThread) MarshalJSON() ([]byte, error) {
+	type comment Comment
+	return json.Marshal(struct {
+		comment
+		Replies []CommentThread `json:"replies"`
+	}{comment(c.Comment.masked()), c.Replies})
+}
+
+func (p *Playground) HideComment(commentID int, hidden bool) error {
+	for index, comment := range p.Comments {
+		if comment.ID == commentID {
+			p.Comments[index].Hidden = hidden
+			return nil
+		}
+	}
+	return ErrorNotFoundComment
+}
+
+func (m *ModerationStore) Report(report CommentReport) error {
+	if !isReportReason(report.Reason) {
+		return ErrorInvalidReportReason
+	}
+	if report.Reporter == "" {
+		return ErrEmptyField
+	}
+	report.Details = strings.TrimSpace(report.Details)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, existingReport := range m.reports {
+		if existingReport.PlaygroundID == report.PlaygroundID && existingReport.CommentID == report.CommentID && existingReport.Reporter == report.Reporter {
+			return ErrorAlreadyReported
+		}
+	}
+	m.reports = append(m.reports, report)
+	return nil
+}
+
+func (m *ModerationStore) Reports() []CommentReport {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	reports := make([]CommentReport, len(m.reports))
+	copy(reports, m.reports)
+	return reports
+}
+
+func (m *ModerationStore) dismissReports(playgroundID, commentID int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	reports := m.reports[:0]
+	for _, report := range m.reports {
+		if report.PlaygroundID != playgroundID || report.CommentID != commentID {
+			reports = append(reports, report)
+		}
+	}
+	m.reports = reports
+}
+
+func (m *ModerationStore) record(action ModerationAction) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lastActionID++
+	action.ID = m.lastActionID
+	m.actions = append(m.actions, action)
+}
+
+// AuditLog returns the moderation actions from the newest.
+func (m *ModerationStore) AuditLog() []ModerationAction {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	actions := make([]ModerationAction, len(m.actions))
+	for i, action := range m.actions {
+		actions[len(actions)-1-i] = action
+	}
+	return actions
+}
+
+// ShadowBan doesn't tell the user, their comments are accepted but only shown to them and their submissions are dropped.
+func (m *ModerationStore) ShadowBan(username string, banned bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.shadowBanned == nil {
+		m.shadowBanned = make(map[string]bool)
+	}
+	if banned {
+		m.shadowBanned[username] = true
+	} else {
+		delete(m.shadowBanned, username)
+	}
+}
+
+func (m *ModerationStore) IsShadowBanned(username string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.shadowBanned[username]
+}
+
+func (m *ModerationStore) ShadowBannedUsers() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	users := make([]string, 0, len(m.shadowBanned))
+	for username := range m.shadowBanned {
+		users = append(users, username)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// ReportComment checks that the comment can still be seen by the reporter before reporting it.
+func (d *PlaygroundDatabase) ReportComment(report CommentReport) error {
+	playground, err := d.MainPlaygroundStore.Playground(report.PlaygroundID)
+	if err != nil {
+		return ErrorNotFoundPlayground
+	}
+	comment, err := playground.FindComment(report.CommentID)
+	if err != nil || comment.Deleted || (comment.Shadowed && comment.Author != report.Reporter) {
+		return ErrorNotFoundComment
+	}
+	return d.Moderation.Report(report)
+}
+
+// ModerationQueue groups the reports by comment, the most reported first then the oldest.
+// Reports on comments deleted meanwhile are dropped.
+func (d *PlaygroundDatabase) ModerationQueue() []ReportedComment {
+	queue := []ReportedComment{}
+	positions := make(map[[2]int]int)
+	for _, report := range d.Moderation.Reports() {
+		key := [2]int{report.PlaygroundID, report.CommentID}
+		position, ok := positions[key]
+		if !ok {
+			playground, err := d.MainPlaygroundStore.Playground(report.PlaygroundID)
+			if err != nil {
+				d.Moderation.dismissReports(report.PlaygroundID, report.CommentID)
+				continue
+			}
+			comment, err := playground.FindComment(report.CommentID)
+			if err != nil || comment.Deleted {
+				d.Moderation.dismissReports(report.PlaygroundID, report.CommentID)
+				continue
+			}
+			position = len(queue)
+			positions[key] = position
+			queue = append(queue, ReportedComment{
+				PlaygroundID:   playground.ID,
+				PlaygroundName: playground.Name,
+				CommentID:      comment.ID,
+				Content:        comment.Content,
+				Author:         comment.Author,
+				Hidden:         comment.Hidden,
+			})
+			if comment.Edited() {
+				queue[position].Versions, _ = playground.CommentHistory(comment.ID)
+			}
+		}
+		queue[position].Reports = append(queue[position].Reports, report)
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		return len(queue[i].Reports) > len(queue[j].Reports)
+	})
+	return queue
+}
+
+// ModerateComment applies action to the comment, hiding, deleting or dismissing a comment resolves its reports.
+// Every action is recorded in the audit log.
+func (d *PlaygroundDatabase) ModerateComment(playgroundID, commentID int, moderator, action, reason string) error {
+	playground, err := d.MainPlaygroundStore.Playground(playgroundID)
+	if err != nil {
+		return ErrorNotFoundPlayground
+	}
+	comment, err := playground.FindComment(commentID)
+	if err != nil {
+		return ErrorNotFoundComment
+	}
+
+	switch action {
+	case ModerationHide, ModerationUnhide:
+		err = d.MainPlaygroundStore.HideComment(playgroundID, commentID, action == ModerationHide)
+	case ModerationDelete:
+		err = d.MainPlaygroundStore.RemoveComment(playgroundID, commentID)
+	case ModerationDismiss:
+	default:
+		return ErrorUnknownModerationAction
+	}
+	if err != nil {
+		return err
+	}
+	if action != ModerationUnhide {
+		d.Moderation.dismissReports(playgroundID, commentID)
+	}
+	d.Moderation.record(ModerationAction{
+		Moderator:    moderator,
+		Action:       action,
+		Reason:       strings.TrimSpace(reason),
+		PlaygroundID: playgroundID,
+		CommentID:    commentID,
+		Content:      comment.Content,
+		Author:       comment.Author,
+		Time:         time.Now(),
+	})
+	return nil
+}
+
+// ShadowBanUser records the change of status in the audit log, the comments already posted stay visible.
+func (d *PlaygroundDatabase) ShadowBanUser(username, moderator string, banned bool, reason string) error {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return ErrEmptyField
+	}
+	d.Moderation.ShadowBan(username, banned)
+	action := ModerationShadowBan
+	if !banned {
+		action = ModerationLiftShadowBan
+	}
+	d.Moderation.record(ModerationAction{
+		Moderator: moderator,
+		Action:    action,
+		Reason:    strings.TrimSpace(reason),
+		Author:    username,
+		Time:      time.Now(),
+	})
+	return nil
+}
@@ -0,0 +1,136 @@
+package store_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestModeration(t *testing.T) {
+	file, removeFile := createTempFile(t, `[{"Name": "aaaa", "Address": "aaaa"}]`)
+	defer removeFile()
+	str, _ := store.New(file)
+	database := store.PlaygroundDatabase{
+		MainPlaygroundStore: str,
+		Moderation:          &store.ModerationStore{},
+	}
+	str.AddComment(1, store.Comment{Author: "Youssef", Content: "Achetez mes baskets"})
+	str.AddComment(1, store.Comment{Author: "Clélia", Content: "Super terrain"})
+	str.AddComment(1, store.Comment{Author: "Thibaut", Content: "Pas d'accord", ParentID: 2})
+	str.AddComment(1, store.Comment{Author: "Spammeur", Content: "Achetez mes maillots", Shadowed: true})
+
+	t.Run("Reports a comment once per user", func(t *testing.T) {
+		err := database.ReportComment(store.CommentReport{PlaygroundID: 1, CommentID: 1, Reporter: "Clélia", Reason: store.ReportSpam})
+		assertError(t, err, nil)
+		err = database.ReportComment(store.CommentReport{PlaygroundID: 1, CommentID: 1, Reporter: "Thibaut", Reason: store.ReportSpam, Details: " pub "})
+		assertError(t, err, nil)
+		database.ReportComment(store.CommentReport{PlaygroundID: 1, CommentID: 2, Reporter: "Youssef", Reason: store.ReportOffTopic})
+
+		err = database.ReportComment(store.CommentReport{PlaygroundID: 1, CommentID: 1, Reporter: "Clélia", Reason: store.ReportAbuse})
+		assertError(t, err, store.ErrorAlreadyReported)
+		err = database.ReportComment(store.CommentReport{PlaygroundID: 1, CommentID: 1, Reporter: "Youssef", Reason: "boring"})
+		assertError(t, err, store.ErrorInvalidReportReason)
+		err = database.ReportComment(store.CommentReport{PlaygroundID: 1, CommentID: 9, Reporter: "Youssef", Reason: store.ReportSpam})
+		assertError(t, err, store.ErrorNotFoundComment)
+		err = database.ReportComment(store.CommentReport{PlaygroundID: 2, CommentID: 1, Reporter: "Youssef", Reason: store.ReportSpam})
+		assertError(t, err, store.ErrorNotFoundPlayground)
+		err = database.ReportComment(store.CommentReport{PlaygroundID: 1, CommentID: 4, Reporter: "Youssef", Reason: store.ReportSpam})
+		assertError(t, err, store.ErrorNotFoundComment)
+	})
+	t.Run("Queues the most reported comments first", func(t *testing.T) {
+		queue := database.ModerationQueue()
+
+		if len(queue) != 2 || queue[0].CommentID != 1 || len(queue[0].Reports) != 2 || queue[1].CommentID != 2 {
+			t.Fatalf("Got %v", queue)
+		}
+		if queue[0].PlaygroundName != "aaaa" || queue[0].Reports[1].Details != "pub" {
+			t.Errorf("Got %v", queue[0])
+		}
+	})
+	t.Run("Hides a comment and masks it", func(t *testing.T) {
+		err := database.ModerateComment(1, 1, "Moderator", store.ModerationHide, "publicité")
+		assertError(t, err, nil)
+
+		playground, _ := str.Playground(1)
+		comment, _ := playground.FindComment(1)
+		if !comment.Hidden || comment.Content != "Achetez mes baskets" {
+			t.Errorf("Got %v", comment)
+		}
+		data, _ := json.Marshal(comment)
+		if strings.Contains(string(data), "baskets") || strings.Contains(string(data), "Youssef") {
+			t.Errorf("Hidden comment is sent, got %s", data)
+		}
+		if got := playground.ThreadedComments()[0]; got.Content != store.HiddenCommentContent || got.CanReply() {
+			t.Errorf("Got %v", got)
+		}
+		if queue := database.ModerationQueue(); len(queue) != 1 || queue[0].CommentID != 2 {
+			t.Errorf("Reports of hidden comment should be resolved, got %v", queue)
+		}
+	})
+	t.Run("Deletes a comment of any author", func(t *testing.T) {
+		err := database.ModerateComment(1, 2, "Moderator", store.ModerationDelete, "")
+		assertError(t, err, nil)
+
+		playground, _ := str.Playground(1)
+		comment, _ := playground.FindComment(2)
+		if !comment.Deleted {
+			t.Errorf("Comment with replies should be marked deleted, got %v", comment)
+		}
+		if queue := database.ModerationQueue(); len(queue) != 0 {
+			t.Errorf("Got %v", queue)
+		}
+	})
+	t.Run("Returns an error for unknown actions and comments", func(t *testing.T) {
+		assertError(t, database.ModerateComment(1, 3, "Moderator", "ban", ""), store.ErrorUnknownModerationAction)
+		assertError(t, database.ModerateComment(1, 9, "Moderator", store.ModerationHide, ""), store.ErrorNotFoundComment)
+		assertError(t, database.ModerateComment(2, 1, "Moderator", store.ModerationHide, ""), store.ErrorNotFoundPlayground)
+	})
+	t.Run("Shadow bans a user", func(t *testing.T) {
+		assertError(t, database.ShadowBanUser("Thibaut", "Moderator", true, "spam"), nil)
+		assertError(t, database.ShadowBanUser(" ", "Moderator", true, ""), store.ErrEmptyField)
+
+		if !database.Moderation.IsShadowBanned("Thibaut") || database.Moderation.IsShadowBanned("Clélia") {
+			t.Errorf("Got %v", database.Moderation.ShadowBannedUsers())
+		}
+		assertError(t, database.ShadowBanUser("Thibaut", "Moderator", false, ""), nil)
+		if users := database.Moderation.ShadowBannedUsers(); len(users) != 0 {
+			t.Errorf("Got %v", users)
+		}
+	})
+	t.Run("Records every action in the audit log from the newest", func(t *testing.T) {
+		log := database.Moderation.AuditLog()
+
+		if len(log) != 4 {
+			t.Fatalf("Got %v", log)
+		}
+		if log[0].Action != store.ModerationLiftShadowBan || log[1].Action != store.ModerationShadowBan || log[1].Author != "Thibaut" || log[1].Reason != "spam" {
+			t.Errorf("Got %v", log[:2])
+		}
+		log = log[2:]
+		if log[0].ID != 2 || log[0].Action != store.ModerationDelete || log[0].Author != "Clélia" || log[0].Content != "Super terrain" {
+			t.Errorf("Got %v", log[0])
+		}
+		if log[1].Action != store.ModerationHide || log[1].Moderator != "Moderator" || log[1].Reason != "publicité" {
+			t.Errorf("Got %v", log[1])
+		}
+	})
+}
+
+func TestVisibleComments(t *testing.T) {
+	comments := store.Comments{
+		{ID: 1, Author: "Youssef", Content: "Super terrain"},
+		{ID: 2, Author: "Thibaut", Content: "Achetez mes baskets", Shadowed: true},
+	}
+
+	if got := comments.VisibleTo("Clélia"); len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("Got %v", got)
+	}
+	if got := comments.VisibleTo(""); len(got) != 1 {
+		t.Errorf("Got %v", got)
+	}
+	if got := comments.VisibleTo("Thibaut"); len(got) != 2 {
+		t.Errorf("Shadowed comments should be visible to their author, got %v", got)
+	}
+}
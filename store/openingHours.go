@@ -0,0 +1,483 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	// The opening hours are evaluated in Paris time even where the system has no time zone database.
+	_ "time/tzdata"
+)
+
+var ErrorInvalidOpeningHours = errors.New("Opening hours should follow the OpenStreetMap opening_hours syntax, ex : Mo-Fr 08:00-20:00; PH off")
+
+// ParisLocation is the time zone of the opening hours.
+var ParisLocation = mustLoadLocation("Europe/Paris")
+
+const minutesPerDay = 24 * 60
+
+var (
+	monthNames         = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	weekdayNames       = []string{"Su", "Mo", "Tu", "We", "Th", "Fr", "Sa"}
+	frenchMonthNames   = []string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."}
+	frenchWeekdayNames = []string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."}
+)
+
+var (
+	openingDatesRegexp    = regexp.MustCompile(`^(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)(?: ([0-3]?[0-9]))?(?:-(?:(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)(?: ([0-3]?[0-9]))?|([0-3]?[0-9])))?`)
+	openingWeekdaysRegexp = regexp.MustCompile(`^(Mo|Tu|We|Th|Fr|Sa|Su|PH)(?:-(Mo|Tu|We|Th|Fr|Sa|Su))?`)
+	openingTimesRegexp    = regexp.MustCompile(`^([0-2][0-9]):([0-5][0-9])-([0-2][0-9]):([0-5][0-9])`)
+)
+
+// OpeningHours is a weekly schedule written with a subset of the OpenStreetMap opening_hours syntax :
+// rules separated by ";", each one made of optional dates (Jul-Aug, Dec 25, Dec 24-Jan 02), optional days (Mo-Fr,Su)
+// or public holidays (PH), then time spans (08:00-12:00,14:00-20:00, 22:00-02:00 going past midnight), "off" or nothing for the whole day.
+// "24/7" is open all the time. As in OpenStreetMap, a rule replaces the previous ones on the days it matches,
+// unless it follows a "," in which case its time spans are added to them.
+type OpeningHours struct {
+	rules []openingRule
+}
+
+type openingRule struct {
+	dates []dateRange
+	// days by time.Weekday, every day if there is no day nor PH selector.
+	days       [7]bool
+	holidays   bool
+	daySet     bool
+	spans      []timeSpan
+	additional bool
+}
+
+// dateRange bounds are month*100+day, to can be before from for a range going over the new year.
+type dateRange struct {
+	from, to int
+}
+
+// timeSpan is in minutes from midnight, to is after minutesPerDay for spans ending the next day.
+type timeSpan struct {
+	from, to int
+}
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("Couldn't load time zone %s, %s", name, err))
+	}
+	return location
+}
+
+func ParseOpeningHours(text string) (OpeningHours, error) {
+	hours := OpeningHours{}
+	for _, ruleText := range strings.Split(text, ";") {
+		ruleText = strings.TrimSpace(ruleText)
+		if ruleText == "" {
+			continue
+		}
+		rules, err := parseOpeningRule(ruleText, false)
+		if err != nil {
+			return OpeningHours{}, err
+		}
+		hours.rules = append(hours.rules, rules...)
+	}
+	if len(hours.rules) == 0 {
+		return OpeningHours{}, ErrorInvalidOpeningHours
+	}
+	return hours, nil
+}
+
+// parseOpeningRule returns the rule and the additional rules following it.
+func parseOpeningRule(text string, additional bool) ([]openingRule, error) {
+	rule := openingRule{additional: additional}
+	if text == "24/7" {
+		rule.spans = []timeSpan{{0, minutesPerDay}}
+		return []openingRule{rule}, nil
+	}
+	rest := text
+	for {
+		match := openingDatesRegexp.FindStringSubmatch(rest)
+		if match == nil {
+			break
+		}
+		// In "Jul 10:00-12:00", 10 is an hour and not a day.
+		if match[2] != "" && match[3] == "" && match[5] == "" && strings.HasPrefix(rest[len(match[0]):], ":") {
+			match = []string{match[1], match[1], "", "", "", ""}
+		}
+		dates, err := parseDateRange(match)
+		if err != nil {
+			return nil, err
+		}
+		rule.dates = append(rule.dates, dates)
+		rest = rest[len(match[0]):]
+		if !strings.HasPrefix(rest, ",") {
+			break
+		}
+		rest = rest[1:]
+	}
+	rest = strings.TrimLeft(rest, " :")
+	for {
+		match := openingWeekdaysRegexp.FindStringSubmatch(rest)
+		if match == nil {
+			break
+		}
+		rule.daySet = true
+		if match[1] == "PH" {
+			rule.holidays = true
+		} else {
+			from, to := weekdayIndex(match[1]), weekdayIndex(match[1])
+			if match[2] != "" {
+				to = weekdayIndex(match[2])
+			}
+			for day := from; ; day = (day + 1) % 7 {
+				rule.days[day] = true
+				if day == to {
+					break
+				}
+			}
+		}
+		rest = rest[len(match[0]):]
+		if !strings.HasPrefix(rest, ",") {
+			break
+		}
+		rest = rest[1:]
+	}
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(rest) {
+	case "off", "closed":
+		return []openingRule{rule}, nil
+	case "", "open":
+		if len(rule.dates) == 0 && !rule.daySet {
+			return nil, ErrorInvalidOpeningHours
+		}
+		rule.spans = []timeSpan{{0, minutesPerDay}}
+		return []openingRule{rule}, nil
+	}
+	for {
+		match := openingTimesRegexp.FindStringSubmatch(rest)
+		if match == nil {
+			return nil, ErrorInvalidOpeningHours
+		}
+		span, err := parseTimeSpan(match)
+		if err != nil {
+			return nil, err
+		}
+		rule.spans = append(rule.spans, span)
+		rest = strings.TrimSpace(rest[len(match[0]):])
+		if rest == "" {
+			return []openingRule{rule}, nil
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return nil, ErrorInvalidOpeningHours
+		}
+		rest = strings.TrimSpace(rest[1:])
+		if !openingTimesRegexp.MatchString(rest) {
+			additionalRules, err := parseOpeningRule(rest, true)
+			if err != nil {
+				return nil, err
+			}
+			return append([]openingRule{rule}, additionalRules...), nil
+		}
+	}
+}
+
+func parseDateRange(match []string) (dateRange, error) {
+	fromMonth := monthIndex(match[1])
+	fromDay, toDay := 1, 31
+	if match[2] != "" {
+		fromDay, _ = strconv.Atoi(match[2])
+		toDay = fromDay
+	}
+	toMonth := fromMonth
+	switch {
+	case match[3] != "":
+		toMonth = monthIndex(match[3])
+		toDay = 31
+		if match[4] != "" {
+			toDay, _ = strconv.Atoi(match[4])
+		}
+	case match[5] != "":
+		toDay, _ = strconv.Atoi(match[5])
+	}
+	if fromDay < 1 || fromDay > 31 || toDay < 1 || toDay > 31 {
+		return dateRange{}, ErrorInvalidOpeningHours
+	}
+	return dateRange{from: fromMonth*100 + fromDay, to: toMonth*100 + toDay}, nil
+}
+
+func parseTimeSpan(match []string) (timeSpan, error) {
+	values := make([]int, 4)
+	for i := range values {
+		values[i], _ = strconv.Atoi(match[i+1])
+	}
+	from, to := values[0]*60+values[1], values[2]*60+values[3]
+	if from >= minutesPerDay || to > minutesPerDay || from == to {
+		return timeSpan{}, ErrorInvalidOpeningHours
+	}
+	if to < from {
+		to += minutesPerDay
+	}
+	return timeSpan{from: from, to: to}, nil
+}
+
+func monthIndex(name string) int {
+	for i, month := range monthNames {
+		if month == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func weekdayIndex(name string) int {
+	for i, day := range weekdayNames {
+		if day == name {
+			return i
+		}
+	}
+	return 0
+}
+
+func (r openingRule) matches(date time.Time) bool {
+	if len(r.dates) > 0 {
+		monthDay := int(date.Month())*100 + date.Day()
+		inDates := false
+		for _, dates := range r.dates {
+			if dates.contains(monthDay) {
+				inDates = true
+				break
+			}
+		}
+		if !inDates {
+			return false
+		}
+	}
+	if !r.daySet {
+		return true
+	}
+	return r.days[date.Weekday()] || (r.holidays && IsFrenchPublicHoliday(date))
+}
+
+func (d dateRange) contains(monthDay int) bool {
+	if d.from <= d.to {
+		return d.from <= monthDay && monthDay <= d.to
+	}
+	return monthDay >= d.from || monthDay <= d.to
+}
+
+// spansOn returns the time spans of the day of date, the last matching rule replacing the previous ones.
+func (h OpeningHours) spansOn(date time.Time) []timeSpan {
+	var spans []timeSpan
+	for _, rule := range h.rules {
+		if !rule.matches(date) {
+			continue
+		}
+		if rule.additional {
+			spans = append(spans, rule.spans...)
+		} else {
+			spans = append([]timeSpan{}, rule.spans...)
+		}
+	}
+	return spans
+}
+
+// OpenAt tells whether t, in Paris time, is in the time spans of its day or in the ones of the day before going past midnight.
+func (h OpeningHours) OpenAt(t time.Time) bool {
+	t = t.In(ParisLocation)
+	minutes := t.Hour()*60 + t.Minute()
+	for _, span := range h.spansOn(t) {
+		if span.from <= minutes && minutes < span.to {
+			return true
+		}
+	}
+	for _, span := range h.spansOn(t.AddDate(0, 0, -1)) {
+		if minutes+minutesPerDay < span.to {
+			return true
+		}
+	}
+	return false
+}
+
+// Description returns a line in French for each rule, ex : "lun.-ven. : 08:00-20:00".
+func (h OpeningHours) Description() []string {
+	lines := make([]string, 0, len(h.rules))
+	for _, rule := range h.rules {
+		lines = append(lines, rule.description())
+	}
+	return lines
+}
+
+func (r openingRule) description() string {
+	selectors := []string{}
+	if len(r.dates) > 0 {
+		dates := make([]string, len(r.dates))
+		for i, dateRange := range r.dates {
+			dates[i] = dateRange.description()
+		}
+		selectors = append(selectors, strings.Join(dates, ", "))
+	}
+	if r.daySet {
+		selectors = append(selectors, r.daysDescription())
+	}
+	if len(selectors) == 0 {
+		selectors = append(selectors, "tous les jours")
+	}
+	if r.additional {
+		selectors[0] = "et " + selectors[0]
+	}
+	return strings.Join(selectors, " ") + " : " + r.spansDescription()
+}
+
+func (r openingRule) daysDescription() string {
+	days := []string{}
+	// Days are listed from Monday, consecutive days being shown as a range.
+	for start := 0; start < 7; start++ {
+		if !r.days[(start+1)%7] {
+			continue
+		}
+		end := start
+		for end+1 < 7 && r.days[(end+2)%7] {
+			end++
+		}
+		if end == start {
+			days = append(days, frenchWeekdayNames[(start+1)%7])
+		} else {
+			days = append(days, frenchWeekdayNames[(start+1)%7]+"-"+frenchWeekdayNames[(end+1)%7])
+		}
+		start = end
+	}
+	if r.holidays {
+		days = append(days, "jours fériés")
+	}
+	return strings.Join(days, ", ")
+}
+
+func (r openingRule) spansDescription() string {
+	if len(r.spans) == 0 {
+		return "fermé"
+	}
+	if len(r.spans) == 1 && r.spans[0] == (timeSpan{0, minutesPerDay}) {
+		return "24h/24"
+	}
+	spans := make([]string, len(r.spans))
+	for i, span := range r.spans {
+		spans[i] = fmt.Sprintf("%s-%s", formatMinutes(span.from), formatMinutes(span.to))
+	}
+	return strings.Join(spans, ", ")
+}
+
+func formatMinutes(minutes int) string {
+	if minutes > minutesPerDay {
+		minutes -= minutesPerDay
+	}
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+func (d dateRange) description() string {
+	from, to := formatMonthDay(d.from, true), formatMonthDay(d.to, false)
+	if from == to {
+		return from
+	}
+	return from + "-" + to
+}
+
+// formatMonthDay leaves out the first or the last day of a whole month.
+func formatMonthDay(monthDay int, start bool) string {
+	month, day := frenchMonthNames[monthDay/100-1], monthDay%100
+	if (start && day == 1) || (!start && day == 31) {
+		return month
+	}
+	return fmt.Sprintf("%d %s", day, month)
+}
+
+// IsFrenchPublicHoliday tells whether the day of date is a public holiday in metropolitan France.
+func IsFrenchPublicHoliday(date time.Time) bool {
+	year, month, day := date.Date()
+	fixedHolidays := map[time.Month][]int{
+		time.January:  {1},
+		time.May:      {1, 8},
+		time.July:     {14},
+		time.August:   {15},
+		time.November: {1, 11},
+		time.December: {25},
+	}
+	for _, holiday := range fixedHolidays[month] {
+		if holiday == day {
+			return true
+		}
+	}
+	easter := easterSunday(year)
+	// Easter Monday, Ascension Day and Whit Monday.
+	for _, offset := range []int{1, 39, 50} {
+		holiday := easter.AddDate(0, 0, offset)
+		if holiday.Month() == month && holiday.Day() == day {
+			return true
+		}
+	}
+	return false
+}
+
+// easterSunday uses the anonymous Gregorian algorithm.
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// parseOpeningHours keeps the parsed opening hours in the playground, so that they aren't parsed again on every request.
+// It has to be called whenever OpeningHours changes.
+func (p *Playground) parseOpeningHours() {
+	p.openingHours = nil
+	if strings.TrimSpace(p.OpeningHours) == "" {
+		return
+	}
+	hours, err := ParseOpeningHours(p.OpeningHours)
+	if err != nil {
+		hours = OpeningHours{}
+	}
+	p.openingHours = &hours
+}
+
+// parsedOpeningHours is empty when the playground has no readable opening hours.
+func (p Playground) parsedOpeningHours() OpeningHours {
+	if p.openingHours == nil {
+		p.parseOpeningHours()
+	}
+	if p.openingHours == nil {
+		return OpeningHours{}
+	}
+	return *p.openingHours
+}
+
+// IsOpenAt tells whether the playground is open at t, known is false when it has no readable opening hours.
+func (p Playground) IsOpenAt(t time.Time) (open, known bool) {
+	hours := p.parsedOpeningHours()
+	if len(hours.rules) == 0 {
+		return false, false
+	}
+	return hours.OpenAt(t), true
+}
+
+func (p Playground) OpenNow() bool {
+	open, _ := p.IsOpenAt(time.Now())
+	return open
+}
+
+// OpeningHoursDescription is empty when the playground has no readable opening hours.
+func (p Playground) OpeningHoursDescription() []string {
+	hours := p.parsedOpeningHours()
+	if len(hours.rules) == 0 {
+		return nil
+	}
+	return hours.Description()
+}
@@ -0,0 +1,166 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func parisTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	date, err := time.ParseInLocation("2006-01-02 15:04", value, store.ParisLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date
+}
+
+func TestOpeningHours(t *testing.T) {
+	cases := []struct {
+		hours string
+		times map[string]bool
+	}{
+		{"24/7", map[string]bool{"2024-07-14 03:00": true}},
+		{"Mo-Fr 08:00-12:00,14:00-20:00; Sa 10:00-18:00", map[string]bool{
+			"2024-03-04 08:00": true,
+			"2024-03-04 12:00": false,
+			"2024-03-04 19:59": true,
+			"2024-03-09 09:59": false,
+			"2024-03-09 10:00": true,
+			"2024-03-10 12:00": false,
+		}},
+		{"Mo-Su 08:00-22:00; PH off", map[string]bool{
+			"2024-07-13 12:00": true,
+			"2024-07-14 12:00": false,
+			// Easter Monday, Ascension Day and Whit Monday.
+			"2024-04-01 12:00": false,
+			"2024-05-09 12:00": false,
+			"2024-05-20 12:00": false,
+			"2024-05-21 12:00": true,
+		}},
+		{"Fr,Sa 20:00-02:00", map[string]bool{
+			"2024-03-08 23:00": true,
+			"2024-03-09 01:30": true,
+			"2024-03-10 01:30": true,
+			"2024-03-10 02:00": false,
+			"2024-03-11 01:00": false,
+		}},
+		{"Mo-Su 09:00-19:00; Jul-Aug Mo-Su 08:00-23:00; Dec 24-Jan 02 off", map[string]bool{
+			"2024-06-30 20:00": false,
+			"2024-07-01 20:00": true,
+			"2024-12-31 12:00": false,
+			"2025-01-03 12:00": true,
+		}},
+		{"Jul 10:00-12:00", map[string]bool{"2024-07-02 11:00": true, "2024-08-02 11:00": false}},
+		{"Mo-Fr 08:00-12:00, Sa 10:00-12:00; Mo 08:00-09:00, Mo 17:00-18:00", map[string]bool{
+			"2024-03-04 10:00": false,
+			"2024-03-04 17:30": true,
+			"2024-03-05 10:00": true,
+			"2024-03-09 11:00": true,
+		}},
+	}
+	for _, c := range cases {
+		hours, err := store.ParseOpeningHours(c.hours)
+		if err != nil {
+			t.Fatalf("%q got %v", c.hours, err)
+		}
+		for value, want := range c.times {
+			if got := hours.OpenAt(parisTime(t, value)); got != want {
+				t.Errorf("%q at %s got %v, want %v", c.hours, value, got, want)
+			}
+		}
+	}
+
+	t.Run("Evaluates in Paris time", func(t *testing.T) {
+		hours, _ := store.ParseOpeningHours("Mo-Su 08:00-20:00")
+
+		// 19:30 UTC is 21:30 in Paris in summer and 20:30 in winter.
+		if hours.OpenAt(time.Date(2024, 7, 1, 19, 30, 0, 0, time.UTC)) || hours.OpenAt(time.Date(2024, 1, 8, 19, 30, 0, 0, time.UTC)) {
+			t.Errorf("Should be closed")
+		}
+		if !hours.OpenAt(time.Date(2024, 7, 1, 17, 30, 0, 0, time.UTC)) {
+			t.Errorf("Should be open")
+		}
+	})
+	t.Run("Returns an error for invalid opening hours", func(t *testing.T) {
+		for _, text := range []string{"", "tous les jours", "08:00-20:00 Mo", "Mo-Fr 8h-20h", "Mo-Fr 20:00-20:00", "Mo 25:00-26:00", "Feb 32 off", "Mo-Fr 08:00-12:00,"} {
+			_, err := store.ParseOpeningHours(text)
+			assertError(t, err, store.ErrorInvalidOpeningHours)
+		}
+	})
+	t.Run("Describes the opening hours in French", func(t *testing.T) {
+		hours, _ := store.ParseOpeningHours("Mo-Fr,Su 08:00-20:00; Jul-Aug Sa 10:00-02:00; Dec 25 off; PH off")
+
+		want := []string{
+			"lun.-ven., dim. : 08:00-20:00",
+			"juil.-août sam. : 10:00-02:00",
+			"25 déc. : fermé",
+			"jours fériés : fermé",
+		}
+		if got := hours.Description(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestFrenchPublicHolidays(t *testing.T) {
+	holidays := []string{"2024-01-01", "2024-04-01", "2024-05-01", "2024-05-08", "2024-05-09", "2024-05-20", "2024-07-14", "2024-08-15", "2024-11-01", "2024-11-11", "2024-12-25", "2025-04-21", "2025-05-29", "2025-06-09"}
+	for _, holiday := range holidays {
+		if !store.IsFrenchPublicHoliday(parisTime(t, holiday+" 12:00")) {
+			t.Errorf("%s should be a public holiday", holiday)
+		}
+	}
+	for _, day := range []string{"2024-03-31", "2024-05-02", "2024-12-26", "2025-04-20"} {
+		if store.IsFrenchPublicHoliday(parisTime(t, day+" 12:00")) {
+			t.Errorf("%s shouldn't be a public holiday", day)
+		}
+	}
+}
+
+func TestFilterOpenAt(t *testing.T) {
+	playgrounds := store.Playgrounds{
+		{ID: 1, Name: "Jour", OpeningHours: "Mo-Su 08:00-20:00"},
+		{ID: 2, Name: "Nuit", OpeningHours: "Mo-Su 20:00-08:00"},
+		{ID: 3, Name: "Inconnu"},
+	}
+	evening := parisTime(t, "2024-03-04 21:00")
+
+	got := playgrounds.Filter(store.PlaygroundFilter{OpenAt: evening})
+	if len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("Got %v", got)
+	}
+	if open, known := playgrounds[2].IsOpenAt(evening); open || known {
+		t.Errorf("Got %v, %v", open, known)
+	}
+	nearby := playgrounds.Nearby(0, 0, 0, 0).OpenAt(parisTime(t, "2024-03-04 12:00"), 1)
+	if len(nearby) != 1 || nearby[0].ID != 1 {
+		t.Errorf("Got %v", nearby)
+	}
+}
+
+func TestStoredOpeningHours(t *testing.T) {
+	file, removeFile := createTempFile(t, `[{"Name": "Jour", "Address": "aaaa", "opening_hours": "Mo-Su 08:00-20:00"}]`)
+	defer removeFile()
+	str, _ := store.New(file)
+	noon := parisTime(t, "2024-03-04 12:00")
+
+	playground, _ := str.Playground(1)
+	if open, known := playground.IsOpenAt(noon); !open || !known {
+		t.Errorf("Got %v, %v", open, known)
+	}
+
+	playground.OpeningHours = "Mo-Su 20:00-08:00"
+	str.UpdatePlayground(playground)
+	playground, _ = str.Playground(1)
+	if open, known := playground.IsOpenAt(noon); open || !known {
+		t.Errorf("Updated opening hours should be used, got %v, %v", open, known)
+	}
+
+	str.NewPlayground(store.Playground{Name: "Nuit", OpeningHours: "Mo-Su 20:00-08:00"})
+	got := str.AllPlaygrounds().Filter(store.PlaygroundFilter{OpenAt: parisTime(t, "2024-03-04 21:00")})
+	if len(got) != 2 {
+		t.Errorf("Got %v", got)
+	}
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrorNotFoundPhoto     = errors.New("Couldn't find photo")
+	ErrorNotFoundBlob      = errors.New("Couldn't find blob")
+	ErrorPhotoTooLarge     = errors.New("Photo should be smaller than 5 MB")
+	ErrorInvalidPhotoType  = errors.New("Photo should be a JPEG or PNG image")
+	ErrorInvalidPhoto      = errors.New("Photo couldn't be read")
+	ErrorPhotoDimensions   = errors.New("Photo has too many pixels")
+	ErrorInvalidPhotoState = errors.New("Unknown photo status")
+	ErrorPhotoRejected     = errors.New("Photo has already been rejected")
+)
+
+const (
+	PhotoPending  = "pending"
+	PhotoApproved = "approved"
+	PhotoRejected = "rejected"
+)
+
+const (
+	MaxPhotoSize = 5 << 20
+	// Processing holds a few RGBA copies of 4 bytes per pixel, about 50 MB each at this size.
+	MaxPhotoPixels = 12000000
+	// Thumbnails fit in a square of ThumbnailSize pixels.
+	ThumbnailSize = 320
+)
+
+// Photo is only shown once approved by a moderator.
+type Photo struct {
+	ID               int       `json:"id"`
+	Author           string    `json:"author"`
+	TimeOfSubmission time.Time `json:"time_of_submission"`
+	Status           string    `json:"status"`
+	ContentType      string    `json:"content_type"`
+	Width            int       `json:"width"`
+	Height           int       `json:"height"`
+	// Keys of the image and of its thumbnail in the BlobStore.
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+}
+
+// BlobStore keeps the uploaded files, Get returns ErrorNotFoundBlob for an unknown key.
+type BlobStore interface {
+	Put(key, contentType string, data []byte) error
+	Get(key string) (data []byte, contentType string, err error)
+	Delete(key string) error
+}
+
+// PendingPhoto is an entry of the photo moderation queue.
+type PendingPhoto struct {
+	PlaygroundID   int    `json:"playground_id"`
+	PlaygroundName string `json:"playground_name"`
+	Photo
+}
+
+func isPhotoStatus(status string) bool {
+	switch status {
+	case PhotoPending, PhotoApproved, PhotoRejected:
+		return true
+	}
+	return false
+}
+
+// AddPhoto gives the photo an ID, new photos wait for moderation.
+func (p *Playground) AddPhoto(photo Photo) (Photo, error) {
+	if photo.Author == "" || photo.Key == "" {
+		return Photo{}, ErrEmptyField
+	}
+	photo.ID = 1
+	for _, existingPhoto := range p.Photos {
+		if existingPhoto.ID >= photo.ID {
+			photo.ID = existingPhoto.ID + 1
+		}
+	}
+	photo.Status = PhotoPending
+	p.Photos = append(p.Photos, photo)
+	return photo, nil
+}
+
+func (p Playground) FindPhoto(photoID int) (Photo, error) {
+	for _, photo := range p.Photos {
+		if photo.ID == photoID {
+			return photo, nil
+		}
+	}
+	return Photo{}, ErrorNotFoundPhoto
+}
+
+// SetPhotoStatus refuses to change a rejected photo : its files are deleted, it can't be approved again.
+func (p *Playground) SetPhotoStatus(photoID int, status string) (Photo, error) {
+	if !isPhotoStatus(status) {
+		return Photo{}, ErrorInvalidPhotoState
+	}
+	for index, photo := range p.Photos {
+		if photo.ID == photoID {
+			if photo.Status == PhotoRejected {
+				return Photo{}, ErrorPhotoRejected
+			}
+			p.Photos[index].Status = status
+			return p.Photos[index], nil
+		}
+	}
+	return Photo{}, ErrorNotFoundPhoto
+}
+
+// ApprovedPhotos returns the photos shown to users, the newest first.
+func (p Playground) ApprovedPhotos() []Photo {
+	photos := []Photo{}
+	for _, photo := range p.Photos {
+		if photo.Status == PhotoApproved {
+			photos = append(photos, photo)
+		}
+	}
+	sort.SliceStable(photos, func(i, j int) bool {
+		return photos[i].TimeOfSubmission.After(photos[j].TimeOfSubmission)
+	})
+	return photos
+}
+
+// PhotoQueue returns the photos waiting for moderation, the oldest first.
+func (d *PlaygroundDatabase) PhotoQueue() []PendingPhoto {
+	queue := []PendingPhoto{}
+	for _, playground := range d.MainPlaygroundStore.AllPlaygrounds() {
+		for _, photo := range playground.Photos {
+			if photo.Status == PhotoPending {
+				queue = append(queue, PendingPhoto{PlaygroundID: playground.ID, PlaygroundName: playground.Name, Photo: photo})
+			}
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].TimeOfSubmission.Before(queue[j].TimeOfSubmission)
+	})
+	return queue
+}
+
+// ModeratePhoto approves or rejects a photo and records it in the audit log.
+// It returns the photo so that the caller can delete the files of a rejected one.
+func (d *PlaygroundDatabase) ModeratePhoto(playgroundID, photoID int, moderator, action, reason string) (Photo, error) {
+	var status string
+	switch action {
+	case ModerationApprove:
+		status = PhotoApproved
+	case ModerationReject:
+		status = PhotoRejected
+	default:
+		return Photo{}, ErrorUnknownModerationAction
+	}
+	photo, err := d.MainPlaygroundStore.SetPhotoStatus(playgroundID, photoID, status)
+	if err != nil {
+		return Photo{}, err
+	}
+	d.Moderation.record(ModerationAction{
+		Moderator:    moderator,
+		Action:       action,
+		Reason:       reason,
+		PlaygroundID: playgroundID,
+		PhotoID:      photoID,
+		Author:       photo.Author,
+		Time:         time.Now(),
+	})
+	return photo, nil
+}
@@ -0,0 +1,217 @@
+package store_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestProcessPhoto(t *testing.T) {
+	t.Run("Applies the EXIF orientation and strips the metadata", func(t *testing.T) {
+		data := withExif(t, encodeJPEG(t, twoColorImage(40, 20)), 6)
+
+		processed, err := store.ProcessPhoto(data)
+		assertError(t, err, nil)
+
+		if processed.ContentType != "image/jpeg" || processed.Width != 20 || processed.Height != 40 {
+			t.Errorf("Got %s %dx%d", processed.ContentType, processed.Width, processed.Height)
+		}
+		if bytes.Contains(processed.Image, []byte("Exif")) || bytes.Contains(processed.Image, []byte("GPS")) {
+			t.Errorf("Metadata should be removed")
+		}
+		decoded, err := jpeg.Decode(bytes.NewReader(processed.Image))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Rotated clockwise, the red left half is on top.
+		if top, bottom := decoded.At(10, 5), decoded.At(10, 35); !isRed(top) || isRed(bottom) {
+			t.Errorf("Got top %v and bottom %v", top, bottom)
+		}
+	})
+	t.Run("Makes a thumbnail without enlarging small photos", func(t *testing.T) {
+		cases := []struct {
+			width, height                   int
+			thumbnailWidth, thumbnailHeight int
+		}{
+			{800, 400, store.ThumbnailSize, store.ThumbnailSize / 2},
+			{300, 1200, store.ThumbnailSize / 4, store.ThumbnailSize},
+			{100, 50, 100, 50},
+		}
+		for _, c := range cases {
+			var buffer bytes.Buffer
+			png.Encode(&buffer, twoColorImage(c.width, c.height))
+
+			processed, err := store.ProcessPhoto(buffer.Bytes())
+			assertError(t, err, nil)
+
+			thumbnail, err := png.Decode(bytes.NewReader(processed.Thumbnail))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bounds := thumbnail.Bounds(); processed.ContentType != "image/png" || bounds.Dx() != c.thumbnailWidth || bounds.Dy() != c.thumbnailHeight {
+				t.Errorf("Got %s %v for %dx%d", processed.ContentType, bounds, c.width, c.height)
+			}
+		}
+	})
+	t.Run("Refuses invalid photos", func(t *testing.T) {
+		_, err := store.ProcessPhoto([]byte("<html><script>alert(1)</script></html>"))
+		assertError(t, err, store.ErrorInvalidPhotoType)
+		_, err = store.ProcessPhoto(append([]byte("GIF89a"), make([]byte, 100)...))
+		assertError(t, err, store.ErrorInvalidPhotoType)
+		_, err = store.ProcessPhoto(make([]byte, store.MaxPhotoSize+1))
+		assertError(t, err, store.ErrorPhotoTooLarge)
+		_, err = store.ProcessPhoto(encodeJPEG(t, twoColorImage(10, 10))[:200])
+		assertError(t, err, store.ErrorInvalidPhoto)
+		_, err = store.ProcessPhoto(pngWithSize(t, 10000, 10000))
+		assertError(t, err, store.ErrorPhotoDimensions)
+	})
+}
+
+func TestPhotoModeration(t *testing.T) {
+	file, removeFile := createTempFile(t, `[{"Name": "aaaa", "Address": "aaaa"}]`)
+	defer removeFile()
+	str, _ := store.New(file)
+	database := store.PlaygroundDatabase{
+		MainPlaygroundStore: str,
+		Moderation:          &store.ModerationStore{},
+	}
+	now := time.Now()
+	for i, author := range []string{"Youssef", "Clélia", "Thibaut"} {
+		photo, err := str.AddPhoto(1, store.Photo{Author: author, Key: author + ".jpg", TimeOfSubmission: now.Add(time.Duration(i) * time.Minute)})
+		assertError(t, err, nil)
+		if photo.ID != i+1 || photo.Status != store.PhotoPending {
+			t.Errorf("Got %v", photo)
+		}
+	}
+	_, err := str.AddPhoto(2, store.Photo{Author: "Youssef", Key: "a.jpg"})
+	assertError(t, err, store.ErrorNotFoundPlayground)
+	_, err = str.AddPhoto(1, store.Photo{Author: "Youssef"})
+	assertError(t, err, store.ErrEmptyField)
+
+	t.Run("Queues the pending photos from the oldest", func(t *testing.T) {
+		queue := database.PhotoQueue()
+
+		if len(queue) != 3 || queue[0].Author != "Youssef" || queue[0].PlaygroundName != "aaaa" {
+			t.Errorf("Got %v", queue)
+		}
+	})
+	t.Run("Shows the approved photos only", func(t *testing.T) {
+		_, err := database.ModeratePhoto(1, 1, "Moderator", store.ModerationApprove, "")
+		assertError(t, err, nil)
+		_, err = database.ModeratePhoto(1, 3, "Moderator", store.ModerationApprove, "")
+		assertError(t, err, nil)
+		photo, err := database.ModeratePhoto(1, 2, "Moderator", store.ModerationReject, "floue")
+		assertError(t, err, nil)
+		if photo.Status != store.PhotoRejected || photo.Key != "Clélia.jpg" {
+			t.Errorf("Got %v", photo)
+		}
+
+		playground, _ := str.Playground(1)
+		photos := playground.ApprovedPhotos()
+		if len(photos) != 2 || photos[0].Author != "Thibaut" || photos[1].Author != "Youssef" {
+			t.Errorf("Got %v", photos)
+		}
+		if queue := database.PhotoQueue(); len(queue) != 0 {
+			t.Errorf("Got %v", queue)
+		}
+	})
+	t.Run("Returns an error for unknown actions and photos", func(t *testing.T) {
+		_, err := database.ModeratePhoto(1, 1, "Moderator", store.ModerationHide, "")
+		assertError(t, err, store.ErrorUnknownModerationAction)
+		_, err = database.ModeratePhoto(1, 9, "Moderator", store.ModerationApprove, "")
+		assertError(t, err, store.ErrorNotFoundPhoto)
+		_, err = database.ModeratePhoto(2, 1, "Moderator", store.ModerationApprove, "")
+		assertError(t, err, store.ErrorNotFoundPlayground)
+	})
+	t.Run("Refuses to change a rejected photo", func(t *testing.T) {
+		_, err := database.ModeratePhoto(1, 2, "Moderator", store.ModerationApprove, "")
+		assertError(t, err, store.ErrorPhotoRejected)
+		_, err = database.ModeratePhoto(1, 2, "Moderator", store.ModerationReject, "")
+		assertError(t, err, store.ErrorPhotoRejected)
+	})
+	t.Run("Records the photo actions in the audit log", func(t *testing.T) {
+		log := database.Moderation.AuditLog()
+
+		if len(log) != 3 || log[0].Action != store.ModerationReject || log[0].PhotoID != 2 || log[0].Author != "Clélia" || log[0].Reason != "floue" {
+			t.Errorf("Got %v", log)
+		}
+	})
+}
+
+// twoColorImage is red on its left half and blue on its right half.
+func twoColorImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xC000 && b < 0x4000
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	err := jpeg.Encode(&buffer, img, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+// withExif inserts an EXIF segment with the orientation and a GPS position after the start of the JPEG.
+func withExif(t *testing.T, data []byte, orientation uint16) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	// IFD0 : orientation then a pointer to the GPS IFD.
+	binary.Write(&tiff, binary.BigEndian, uint16(2))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3, 0, 1, orientation, 0})
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x8825, 4, 0, 1, 0, 38})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+	// GPS IFD : latitude reference.
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0001, 2, 0, 2})
+	tiff.WriteString("N\x00\x00\x00")
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+	tiff.WriteString("GPS 48.8566 2.3522")
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	header := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+	result := append([]byte{}, data[:2]...)
+	result = append(result, header...)
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+// pngWithSize returns a PNG whose header announces width x height pixels.
+func pngWithSize(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	png.Encode(&buffer, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := buffer.Bytes()
+	// The IHDR chunk follows the 8 bytes signature : length, type, width, height...
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
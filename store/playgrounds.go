@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 
 var ErrorNotFoundPlayground = errors.New("Playground doesn't exist")
 var ErrorNotFoundComment = errors.New("Comment doesn't exist")
+var ErrorNotFoundLocation = errors.New("Location doesn't exist")
 
 type Playground struct {
 	Name             string    `json:"name"`
@@ -25,10 +27,29 @@ type Playground struct {
 	Coating          string    `json:"coating"`
 	Type             string    `json:"type"`
 	Open             bool      `json:"open"`
+	OpeningHours     string    `json:"opening_hours"`
 	ID               int       `json:"id"`
 	Author           string    `json:"author"`
 	TimeOfSubmission time.Time `json:"time_of_submission"`
 	Comments         Comments  `json:"comments"`
+	GeocodingScore   float64   `json:"geocoding_score"`
+	GeocodedAddress  string    `json:"geocoded_address"`
+	LowConfidence    bool      `json:"low_confidence"`
+	GeocodingSource  string    `json:"geocoding_source"`
+	// Ratings by username, only the aggregate is sent.
+	Ratings       map[string]int `json:"-"`
+	AverageRating float64        `json:"average_rating"`
+	RatingsCount  int            `json:"ratings_count"`
+	// Newest first, reports older than ConditionMaxAge are dropped.
+	ConditionReports ConditionReports `json:"condition_reports"`
+	// Votes on comments by comment ID then username, comments only carry the totals.
+	CommentVotes map[int]map[string]int `json:"-"`
+	// Previous versions of the edited comments by comment ID, only sent to moderators.
+	CommentVersions map[int][]CommentVersion `json:"-"`
+	// Photos of every status, see ApprovedPhotos for the ones shown to users.
+	Photos []Photo `json:"-"`
+	// Parsed OpeningHours, nil until parseOpeningHours is called.
+	openingHours *OpeningHours
 }
 
 type Playgrounds []Playground
@@ -38,6 +59,17 @@ type Comment struct {
 	Content          string    `json:"content"`
 	Author           string    `json:"author"`
 	TimeOfSubmission time.Time `json:"time_of_submission"`
+	TimeOfEdit       time.Time `json:"time_of_edit"`
+	Upvotes          int       `json:"upvotes"`
+	Downvotes        int       `json:"downvotes"`
+	Score            int       `json:"score"`
+	// ParentID is the ID of the comment this one replies to, 0 for a top level comment.
+	ParentID int  `json:"parent_id"`
+	Deleted  bool `json:"deleted"`
+	// Hidden by a moderator, its content is only sent to moderators.
+	Hidden bool `json:"hidden"`
+	// Shadowed comments were posted by a shadow-banned user, only their author sees them.
+	Shadowed bool `json:"-"`
 }
 
 type Comments []Comment
@@ -48,6 +80,9 @@ func NewPlaygroundsFromJSON(input io.Reader) (Playgrounds, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Unable to parse input %q into slice, '%v'", input, err)
 	}
+	for i := range playgrounds {
+		playgrounds[i].parseOpeningHours()
+	}
 	return playgrounds, nil
 }
 
@@ -68,17 +103,53 @@ func (p Playgrounds) Find(ID int) (Playground, int, error) {
 	}
 	return Playground{}, 0, ErrorNotFoundPlayground
 }
-func (p Playgrounds) FindNearestPlaygrounds(client GeolocationClient, address string) (Playgrounds, error) {
-	long, lat, err := client.GetLongAndLat(address)
+func (p Playgrounds) FindNearestPlaygrounds(ctx context.Context, client GeolocationClient, address string) (Playgrounds, error) {
+	long, lat, err := client.GetLongAndLat(ctx, address)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't get longitude and lattitude, %s", err)
 	}
-	playgroundsSorted := p.sortByProximity(long, lat)
-	return playgroundsSorted, nil
+	return p.FindNearestPlaygroundsFrom(long, lat), nil
+}
+
+func (p Playgrounds) FindNearestPlaygroundsFrom(long, lat float64) Playgrounds {
+	return p.sortByProximity(long, lat)
 }
 
 type GeolocationClient interface {
-	GetLongAndLat(address string) (long, lat float64, err error)
+	GetLongAndLat(ctx context.Context, address string) (long, lat float64, err error)
+	Geocode(ctx context.Context, address string) (Location, error)
+	ReverseGeocode(ctx context.Context, long, lat float64) (Location, error)
+	SuggestAddresses(ctx context.Context, query string, limit int) ([]Location, error)
+}
+
+type Location struct {
+	Long       float64 `json:"long"`
+	Lat        float64 `json:"lat"`
+	Score      float64 `json:"score"`
+	Label      string  `json:"label"`
+	Address    string  `json:"address"`
+	PostalCode string  `json:"postal_code"`
+	City       string  `json:"city"`
+	Department string  `json:"department"`
+	Provider   string  `json:"provider"`
+}
+
+// Below this score the geocoder's answer is not trusted and the moderator has to check the coordinates.
+const MinGeocodingScore = 0.6
+
+func (p *Playground) Geolocate(ctx context.Context, client GeolocationClient) error {
+	location, err := client.Geocode(ctx, fmt.Sprintf("%s %s %s", p.Address, p.PostalCode, p.City))
+	if err != nil {
+		p.LowConfidence = true
+		return fmt.Errorf("Couldn't geocode playground, %s", err)
+	}
+	p.Long = location.Long
+	p.Lat = location.Lat
+	p.GeocodingScore = location.Score
+	p.GeocodedAddress = location.Label
+	p.GeocodingSource = location.Provider
+	p.LowConfidence = location.Score < MinGeocodingScore
+	return nil
 }
 
 func (p Playgrounds) sortByName() {
@@ -88,18 +159,66 @@ func (p Playgrounds) sortByName() {
 }
 
 func (p Playgrounds) sortByProximity(long, lat float64) Playgrounds {
-	playgroundsSorted := make(Playgrounds, len(p))
-	copy(playgroundsSorted, p)
-	sort.SliceStable(playgroundsSorted, func(i, j int) bool {
-		distanceFromAddressToI := playgroundsSorted[i].calculateSquaredDistanceFrom(long, lat)
-		distanceFromAddressToJ := playgroundsSorted[j].calculateSquaredDistanceFrom(long, lat)
-		return distanceFromAddressToI < distanceFromAddressToJ
-	})
+	nearbyPlaygrounds := p.Nearby(long, lat, 0, 0)
+	playgroundsSorted := make(Playgrounds, len(nearbyPlaygrounds))
+	for i, nearbyPlayground := range nearbyPlaygrounds {
+		playgroundsSorted[i] = nearbyPlayground.Playground
+	}
 	return playgroundsSorted
 }
 
-func (p Playground) calculateSquaredDistanceFrom(long, lat float64) float64 {
-	return math.Pow(long-p.Long, 2) + math.Pow(lat-p.Lat, 2)
+func (p Playgrounds) Within(box BoundingBox) Playgrounds {
+	playgrounds := Playgrounds{}
+	for _, playground := range p {
+		if box.Contains(playground.Long, playground.Lat) {
+			playgrounds = append(playgrounds, playground)
+		}
+	}
+	return playgrounds
+}
+
+type NearbyPlayground struct {
+	Playground
+	Distance float64 `json:"distance"`
+}
+
+type NearbyPlaygrounds []NearbyPlayground
+
+// Nearby returns the playgrounds sorted by distance (in metres) from the given coordinates.
+// Playgrounds farther than radius are left out and at most limit playgrounds are returned, a zero value disabling the bound.
+func (p Playgrounds) Nearby(long, lat, radius float64, limit int) NearbyPlaygrounds {
+	nearbyPlaygrounds := make(NearbyPlaygrounds, 0, len(p))
+	for _, playground := range p {
+		distance := playground.DistanceFrom(long, lat)
+		if radius > 0 && distance > radius {
+			continue
+		}
+		nearbyPlaygrounds = append(nearbyPlaygrounds, NearbyPlayground{Playground: playground, Distance: distance})
+	}
+	sort.SliceStable(nearbyPlaygrounds, func(i, j int) bool {
+		return nearbyPlaygrounds[i].Distance < nearbyPlaygrounds[j].Distance
+	})
+	if limit > 0 && len(nearbyPlaygrounds) > limit {
+		nearbyPlaygrounds = nearbyPlaygrounds[:limit]
+	}
+	return nearbyPlaygrounds
+}
+
+const earthRadius = 6371008.8
+
+// DistanceFrom returns the great-circle distance in metres between the playground and the given coordinates (haversine formula).
+func (p Playground) DistanceFrom(long, lat float64) float64 {
+	return haversine(p.Long, p.Lat, long, lat)
+}
+
+func haversine(long1, lat1, long2, lat2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (long2 - long1) * math.Pi / 180
+
+	a := math.Pow(math.Sin(deltaPhi/2), 2) + math.Cos(phi1)*math.Cos(phi2)*math.Pow(math.Sin(deltaLambda/2), 2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
 }
 
 func (p Playground) FindComment(commentID int) (Comment, error) {
@@ -117,20 +236,59 @@ func (p *Playground) AddComment(comment Comment) error {
 	if content == "" || author == "" {
 		return ErrEmptyField
 	}
+	if comment.ParentID != 0 {
+		depth, err := p.replyDepth(comment.ParentID)
+		if err != nil {
+			return err
+		}
+		if depth > MaxCommentDepth {
+			return ErrorMaxCommentDepth
+		}
+	}
 	newComment := Comment{
 		Content:          comment.Content,
 		Author:           comment.Author,
-		ID:               len(p.Comments) + 1,
+		ID:               p.nextCommentID(),
 		TimeOfSubmission: comment.TimeOfSubmission,
+		ParentID:         comment.ParentID,
+		Shadowed:         comment.Shadowed,
 	}
-	p.Comments = append(Comments{newComment}, p.Comments...)
+	p.Comments = append(p.Comments, newComment)
 	return nil
 }
 
+// nextCommentID follows the highest ID, counting the comments would give an existing ID once one has been deleted.
+func (p Playground) nextCommentID() int {
+	ID := 1
+	for _, comment := range p.Comments {
+		if comment.ID >= ID {
+			ID = comment.ID + 1
+		}
+	}
+	return ID
+}
+
+// DeleteComment keeps a comment with replies as "[deleted]" so that the thread stays readable.
+// A deleted comment is removed along with its last reply.
 func (p *Playground) DeleteComment(commentID int) error {
 	for index, comment := range p.Comments {
 		if comment.ID == commentID {
+			delete(p.CommentVotes, commentID)
+			delete(p.CommentVersions, commentID)
+			if p.hasReplies(commentID) {
+				p.Comments[index] = Comment{
+					ID:               comment.ID,
+					Content:          DeletedCommentContent,
+					TimeOfSubmission: comment.TimeOfSubmission,
+					ParentID:         comment.ParentID,
+					Deleted:          true,
+				}
+				return nil
+			}
 			p.Comments = append(p.Comments[:index], p.Comments[index+1:]...)
+			if parent, err := p.FindComment(comment.ParentID); err == nil && parent.Deleted && !p.hasReplies(parent.ID) {
+				return p.DeleteComment(parent.ID)
+			}
 			return nil
 		}
 	}
@@ -147,8 +305,12 @@ func (p *Playground) UpdateComment(updatedComment Comment) error {
 			if content == "" {
 				return ErrEmptyField
 			}
+			if content == comment.Content {
+				return nil
+			}
+			p.addCommentVersion(comment)
 			p.Comments[index].Content = content
-			p.Comments[index].TimeOfSubmission = updatedComment.TimeOfSubmission
+			p.Comments[index].TimeOfEdit = updatedComment.TimeOfEdit
 			return nil
 		}
 	}
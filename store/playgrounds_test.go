@@ -1,6 +1,9 @@
 package store_test
 
 import (
+	"context"
+	"errors"
+	"math"
 	"reflect"
 	"strings"
 	"testing"
@@ -12,11 +15,29 @@ import (
 
 type stubClient struct{}
 
-func (s stubClient) GetLongAndLat(address string) (long, lat float64, err error) {
+func (s stubClient) GetLongAndLat(ctx context.Context, address string) (long, lat float64, err error) {
 	long = 2.372452
 	lat = 48.886835
 	return long, lat, nil
 }
+
+func (s stubClient) Geocode(ctx context.Context, address string) (store.Location, error) {
+	switch address {
+	case "unknown":
+		return store.Location{}, errors.New("Empty answer from the API")
+	case "vague":
+		return store.Location{Long: 2.35, Lat: 48.85, Score: 0.3, Label: "Paris"}, nil
+	}
+	return store.Location{Long: 2.372452, Lat: 48.886835, Score: 0.9, Label: "42 Avenue de Flandre 75019 Paris", Provider: "stub"}, nil
+}
+
+func (s stubClient) ReverseGeocode(ctx context.Context, long, lat float64) (store.Location, error) {
+	return store.Location{Long: long, Lat: lat, Score: 0.9, Label: "42 Avenue de Flandre 75019 Paris"}, nil
+}
+
+func (s stubClient) SuggestAddresses(ctx context.Context, query string, limit int) ([]store.Location, error) {
+	return []store.Location{}, nil
+}
 func TestPlaygrounds(t *testing.T) {
 	t.Run("FindNearestPlaygrounds returns playgrounds from nearest to farthest", func(t *testing.T) {
 		// {TEP JARDINS SAINT PAUL 2.36016 48.8532}
@@ -49,10 +70,95 @@ func TestPlaygrounds(t *testing.T) {
 			farthestPlayground,
 		}
 
-		got, _ := playgrounds.FindNearestPlaygrounds(client, "42 avenue de Flandre Paris")
+		got, _ := playgrounds.FindNearestPlaygrounds(context.Background(), client, "42 avenue de Flandre Paris")
 
 		test.AssertPlaygrounds(t, got, want)
 	})
+	t.Run("FindNearestPlaygroundsFrom returns playgrounds from nearest to farthest from coordinates", func(t *testing.T) {
+		nearestPlayground := store.Playground{Name: "LYCEE VICTOR DURUY", Long: 2.31565, Lat: 48.8533}
+		farthestPlayground := store.Playground{Name: "TEP JARDINS SAINT PAUL", Long: 2.36016000, Lat: 48.85320000}
+		playgrounds := store.Playgrounds{farthestPlayground, nearestPlayground}
+
+		got := playgrounds.FindNearestPlaygroundsFrom(2.3157, 48.8534)
+
+		test.AssertPlaygrounds(t, got, store.Playgrounds{nearestPlayground, farthestPlayground})
+	})
+	t.Run("DistanceFrom returns the distance in metres", func(t *testing.T) {
+		playground := store.Playground{Name: "LYCEE CONDORCET", Long: 2.32743, Lat: 48.87484}
+
+		got := playground.DistanceFrom(2.372452, 48.886835)
+
+		if math.Abs(got-3552) > 1 {
+			t.Errorf("got : %f, want : 3552 metres", got)
+		}
+	})
+	t.Run("Nearby ", func(t *testing.T) {
+		nearestPlayground := store.Playground{Name: "TEP JARDINS SAINT PAUL", Long: 2.36016000, Lat: 48.85320000}
+		intermediatePlayground := store.Playground{Name: "ETABLISSEMENT FENELON", Long: 2.31718, Lat: 48.87867}
+		farthestPlayground := store.Playground{Name: "LYCEE VICTOR DURUY", Long: 2.31565, Lat: 48.8533}
+		playgrounds := store.Playgrounds{intermediatePlayground, farthestPlayground, nearestPlayground}
+
+		t.Run("RETURNS playgrounds with their distance from nearest to farthest", func(t *testing.T) {
+			got := playgrounds.Nearby(2.372452, 48.886835, 0, 0)
+
+			assertNearbyPlaygroundNames(t, got, nearestPlayground.Name, intermediatePlayground.Name, farthestPlayground.Name)
+			for i := 1; i < len(got); i++ {
+				if got[i].Distance < got[i-1].Distance {
+					t.Errorf("Playgrounds should be sorted by distance, got %v", got)
+				}
+			}
+		})
+		t.Run("LEAVES OUT playgrounds outside of radius", func(t *testing.T) {
+			got := playgrounds.Nearby(2.372452, 48.886835, 5000, 0)
+
+			assertNearbyPlaygroundNames(t, got, nearestPlayground.Name, intermediatePlayground.Name)
+		})
+		t.Run("RETURNS at most limit playgrounds", func(t *testing.T) {
+			got := playgrounds.Nearby(2.372452, 48.886835, 0, 1)
+
+			assertNearbyPlaygroundNames(t, got, nearestPlayground.Name)
+		})
+	})
+	t.Run("Geolocate ", func(t *testing.T) {
+		client := stubClient{}
+		t.Run("SETS coordinates, score and normalized address", func(t *testing.T) {
+			playground := store.Playground{Address: "42 avenue de Flandre", PostalCode: "75019", City: "Paris"}
+
+			err := playground.Geolocate(context.Background(), client)
+			if err != nil {
+				t.Fatalf("There shouldn't be an error, %s", err)
+			}
+
+			if playground.Long != 2.372452 || playground.Lat != 48.886835 {
+				t.Errorf("Wrong coordinates, got %v", playground)
+			}
+			if playground.GeocodedAddress != "42 Avenue de Flandre 75019 Paris" || playground.GeocodingSource != "stub" || playground.LowConfidence {
+				t.Errorf("Wrong geocoding result, got %v", playground)
+			}
+		})
+		t.Run("FLAGS low confidence results", func(t *testing.T) {
+			playground := store.Playground{Address: "vague"}
+			vagueClient := addressClient{stubClient{}, "vague"}
+
+			playground.Geolocate(context.Background(), vagueClient)
+
+			if !playground.LowConfidence {
+				t.Errorf("Playground should be flagged, got %v", playground)
+			}
+		})
+		t.Run("FLAGS and returns an error if address is unknown", func(t *testing.T) {
+			playground := store.Playground{}
+			unknownClient := addressClient{stubClient{}, "unknown"}
+
+			err := playground.Geolocate(context.Background(), unknownClient)
+			if err == nil {
+				t.Errorf("There should be an error")
+			}
+			if !playground.LowConfidence {
+				t.Errorf("Playground should be flagged, got %v", playground)
+			}
+		})
+	})
 	t.Run("Find returns correct playground", func(t *testing.T) {
 		playgrounds := setupPlaygrounds()
 		want := playgrounds[0]
@@ -72,6 +178,16 @@ func TestPlaygrounds(t *testing.T) {
 	})
 }
 
+// addressClient forces the address sent to the stub so that its canned answers can be selected.
+type addressClient struct {
+	stubClient
+	address string
+}
+
+func (a addressClient) Geocode(ctx context.Context, address string) (store.Location, error) {
+	return a.stubClient.Geocode(ctx, a.address)
+}
+
 func TestComments(t *testing.T) {
 	t.Run("AddComment ", func(t *testing.T) {
 		t.Run("ADDS a new comment with correct ID", func(t *testing.T) {
@@ -235,6 +351,18 @@ func TestComments(t *testing.T) {
 	})
 }
 
+func assertNearbyPlaygroundNames(t *testing.T, got store.NearbyPlaygrounds, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d playgrounds, want %d", len(got), len(want))
+	}
+	for i, name := range want {
+		if got[i].Name != name {
+			t.Errorf("got : %q at position %d, want : %q", got[i].Name, i, name)
+		}
+	}
+}
+
 func setupPlaygrounds() store.Playgrounds {
 	playground1 := store.Playground{
 		Name: "1",
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrorInvalidRating = errors.New("Rating should be between 1 and 5")
+
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// Rate sets the rating of username, replacing the previous one, and updates the average.
+func (p *Playground) Rate(username string, rating int) error {
+	if rating < MinRating || rating > MaxRating {
+		return ErrorInvalidRating
+	}
+	if username == "" {
+		return ErrEmptyField
+	}
+	if p.Ratings == nil {
+		p.Ratings = make(map[string]int)
+	}
+	p.Ratings[username] = rating
+
+	sum := 0
+	for _, rating := range p.Ratings {
+		sum += rating
+	}
+	p.RatingsCount = len(p.Ratings)
+	p.AverageRating = math.Round(float64(sum)/float64(p.RatingsCount)*100) / 100
+	return nil
+}
+
+// Rating returns the rating given by username, 0 if there is none.
+func (p Playground) Rating(username string) int {
+	return p.Ratings[username]
+}
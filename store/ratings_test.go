@@ -0,0 +1,58 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestRate(t *testing.T) {
+	playground := store.Playground{}
+
+	t.Run("Computes the average of the ratings", func(t *testing.T) {
+		playground.Rate("Youssef", 5)
+		playground.Rate("Clélia", 4)
+		playground.Rate("Thibaut", 4)
+
+		assertRating(t, playground, 4.33, 3)
+	})
+	t.Run("Keeps one rating per user", func(t *testing.T) {
+		playground.Rate("Youssef", 1)
+
+		assertRating(t, playground, 3, 3)
+		if playground.Rating("Youssef") != 1 || playground.Rating("Jean") != 0 {
+			t.Errorf("got ratings %v", playground.Ratings)
+		}
+	})
+	t.Run("Refuses ratings out of range", func(t *testing.T) {
+		for _, rating := range []int{0, 6, -1} {
+			err := playground.Rate("Youssef", rating)
+
+			assertError(t, err, store.ErrorInvalidRating)
+		}
+		assertRating(t, playground, 3, 3)
+	})
+	t.Run("Refuses anonymous ratings", func(t *testing.T) {
+		err := playground.Rate("", 3)
+
+		assertError(t, err, store.ErrEmptyField)
+	})
+	t.Run("Sorts by rating, then by number of ratings", func(t *testing.T) {
+		playgrounds := store.Playgrounds{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+		playgrounds[0].Rate("Youssef", 3)
+		playgrounds[1].Rate("Youssef", 5)
+		playgrounds[2].Rate("Youssef", 3)
+		playgrounds[2].Rate("Clélia", 3)
+
+		got, _ := playgrounds.Sort(store.SortByRating, 0, 0)
+
+		assertIDs(t, got, []int{2, 3, 1, 4})
+	})
+}
+
+func assertRating(t *testing.T, playground store.Playground, average float64, count int) {
+	t.Helper()
+	if playground.AverageRating != average || playground.RatingsCount != count {
+		t.Errorf("got average %v of %d ratings, want %v of %d", playground.AverageRating, playground.RatingsCount, average, count)
+	}
+}
@@ -0,0 +1,330 @@
+package store
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Weight of a term depending on the field it comes from : a match in the name matters more than in a comment.
+var searchFieldWeights = map[string]float64{
+	"name":    3,
+	"city":    1.5,
+	"type":    1.5,
+	"address": 1,
+	"content": 1,
+}
+
+// Number of characters kept around the first match of a long text.
+const snippetContext = 60
+
+var searchStopWords = map[string]bool{
+	"a": true, "au": true, "aux": true, "avec": true, "ce": true, "ces": true, "d": true, "dans": true, "de": true, "des": true,
+	"du": true, "en": true, "est": true, "et": true, "il": true, "l": true, "la": true, "le": true, "les": true, "leur": true,
+	"mais": true, "n": true, "ne": true, "ou": true, "par": true, "pas": true, "pour": true, "qu": true, "que": true, "qui": true,
+	"s": true, "sa": true, "se": true, "ses": true, "son": true, "sur": true, "un": true, "une": true, "y": true,
+}
+
+type SearchResult struct {
+	PlaygroundID int     `json:"playground_id"`
+	CommentID    int     `json:"comment_id,omitempty"`
+	Name         string  `json:"name"`
+	Snippet      string  `json:"snippet"`
+	Score        float64 `json:"score"`
+}
+
+type SearchResults []SearchResult
+
+// searchDocument is a playground (CommentID 0) or one of its comments.
+type searchDocument struct {
+	PlaygroundID int
+	CommentID    int
+}
+
+type searchField struct {
+	name string
+	text string
+}
+
+// searchIndex is an inverted index from stemmed terms to the documents containing them.
+type searchIndex struct {
+	postings     map[string]map[searchDocument]float64
+	documents    map[searchDocument][]searchField
+	names        map[int]string
+	byPlayground map[int][]searchDocument
+	// terms is kept sorted by Add and Remove so that searching only reads the index.
+	terms []string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings:     make(map[string]map[searchDocument]float64),
+		documents:    make(map[searchDocument][]searchField),
+		names:        make(map[int]string),
+		byPlayground: make(map[int][]searchDocument),
+	}
+}
+
+// Search finds the playgrounds and comments containing every word of query, the last word being completed as it is typed.
+// It builds a temporary index, stores keep their own up to date.
+func (p Playgrounds) Search(query string, limit int) SearchResults {
+	index := newSearchIndex()
+	for _, playground := range p {
+		index.Add(playground)
+	}
+	return index.Search(query, limit)
+}
+
+// Add indexes the playground and its comments, replacing what was indexed for its ID.
+func (s *searchIndex) Add(playground Playground) {
+	s.Remove(playground.ID)
+	s.names[playground.ID] = playground.Name
+	s.addDocument(searchDocument{PlaygroundID: playground.ID}, []searchField{
+		{"name", playground.Name},
+		{"address", playground.Address},
+		{"city", playground.City},
+		{"type", playground.Type},
+	})
+	for _, comment := range playground.Comments {
+		if comment.Deleted || comment.Hidden || comment.Shadowed {
+			continue
+		}
+		s.addDocument(searchDocument{PlaygroundID: playground.ID, CommentID: comment.ID}, []searchField{
+			{"content", comment.Content},
+		})
+	}
+}
+
+func (s *searchIndex) Remove(playgroundID int) {
+	for _, document := range s.byPlayground[playgroundID] {
+		for _, field := range s.documents[document] {
+			for _, term := range analyze(field.text) {
+				delete(s.postings[term], document)
+				if len(s.postings[term]) == 0 {
+					delete(s.postings, term)
+					s.removeTerm(term)
+				}
+			}
+		}
+		delete(s.documents, document)
+	}
+	delete(s.byPlayground, playgroundID)
+	delete(s.names, playgroundID)
+}
+
+func (s *searchIndex) addDocument(document searchDocument, fields []searchField) {
+	s.documents[document] = fields
+	s.byPlayground[document.PlaygroundID] = append(s.byPlayground[document.PlaygroundID], document)
+	for _, field := range fields {
+		for _, term := range analyze(field.text) {
+			if _, ok := s.postings[term]; !ok {
+				s.postings[term] = make(map[searchDocument]float64)
+				s.insertTerm(term)
+			}
+			s.postings[term][document] += searchFieldWeights[field.name]
+		}
+	}
+}
+
+// Search returns the best limit documents, limit 0 returns all of them.
+func (s *searchIndex) Search(query string, limit int) SearchResults {
+	queryTerms := analyze(query)
+	results := SearchResults{}
+	if len(queryTerms) == 0 {
+		return results
+	}
+	// The last word is matched as a prefix, unless the query ends with a space : the word is then complete.
+	lastTermIsPrefix := !strings.HasSuffix(query, " ")
+
+	var scores map[searchDocument]float64
+	matchedTerms := make([]string, 0, len(queryTerms))
+	for i, queryTerm := range queryTerms {
+		terms := []string{queryTerm}
+		if lastTermIsPrefix && i == len(queryTerms)-1 {
+			terms = s.completions(queryTerm)
+		}
+		matchedTerms = append(matchedTerms, terms...)
+		termScores := make(map[searchDocument]float64)
+		for _, term := range terms {
+			postings := s.postings[term]
+			idf := math.Log(1 + float64(len(s.documents))/float64(len(postings)))
+			for document, weight := range postings {
+				termScores[document] = math.Max(termScores[document], weight*idf)
+			}
+		}
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for document, score := range scores {
+			if termScore, ok := termScores[document]; ok {
+				scores[document] = score + termScore
+			} else {
+				delete(scores, document)
+			}
+		}
+	}
+
+	for document, score := range scores {
+		results = append(results, SearchResult{
+			PlaygroundID: document.PlaygroundID,
+			CommentID:    document.CommentID,
+			Name:         s.names[document.PlaygroundID],
+			Snippet:      snippet(s.documents[document], matchedTerms),
+			Score:        math.Round(score*1000) / 1000,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].PlaygroundID != results[j].PlaygroundID {
+			return results[i].PlaygroundID < results[j].PlaygroundID
+		}
+		return results[i].CommentID < results[j].CommentID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func (s *searchIndex) insertTerm(term string) {
+	i := sort.SearchStrings(s.terms, term)
+	s.terms = append(s.terms, "")
+	copy(s.terms[i+1:], s.terms[i:])
+	s.terms[i] = term
+}
+
+func (s *searchIndex) removeTerm(term string) {
+	i := sort.SearchStrings(s.terms, term)
+	if i < len(s.terms) && s.terms[i] == term {
+		s.terms = append(s.terms[:i], s.terms[i+1:]...)
+	}
+}
+
+func (s *searchIndex) completions(prefix string) []string {
+	completions := []string{}
+	for i := sort.SearchStrings(s.terms, prefix); i < len(s.terms) && strings.HasPrefix(s.terms[i], prefix); i++ {
+		completions = append(completions, s.terms[i])
+	}
+	return completions
+}
+
+// snippet returns the first field containing a matched term, HTML escaped, with the matching words in <mark>.
+func snippet(fields []searchField, matchedTerms []string) string {
+	matched := make(map[string]bool, len(matchedTerms))
+	for _, term := range matchedTerms {
+		matched[term] = true
+	}
+	for _, field := range fields {
+		words := splitWords(field.text)
+		var marks [][2]int
+		for _, word := range words {
+			if terms := analyze(field.text[word[0]:word[1]]); len(terms) == 1 && matched[terms[0]] {
+				marks = append(marks, word)
+			}
+		}
+		if len(marks) > 0 {
+			return highlight(field.text, marks)
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return html.EscapeString(fields[0].text)
+}
+
+func highlight(text string, marks [][2]int) string {
+	start, end := 0, len(text)
+	prefix, suffix := "", ""
+	if marks[0][0] > snippetContext {
+		start = wordBoundary(text, marks[0][0]-snippetContext)
+		prefix = "…"
+	}
+	if end-marks[0][1] > 2*snippetContext {
+		end = wordBoundary(text, marks[0][1]+2*snippetContext)
+		suffix = "…"
+	}
+
+	var builder strings.Builder
+	builder.WriteString(prefix)
+	position := start
+	for _, mark := range marks {
+		if mark[0] < start || mark[1] > end {
+			continue
+		}
+		builder.WriteString(html.EscapeString(text[position:mark[0]]))
+		builder.WriteString("<mark>" + html.EscapeString(text[mark[0]:mark[1]]) + "</mark>")
+		position = mark[1]
+	}
+	builder.WriteString(html.EscapeString(text[position:end]))
+	builder.WriteString(suffix)
+	return strings.TrimSpace(builder.String())
+}
+
+// wordBoundary moves index forward to the start of the next word.
+func wordBoundary(text string, index int) int {
+	for index < len(text) && (text[index]&0xC0 == 0x80 || !unicode.IsSpace(rune(text[index]))) {
+		index++
+	}
+	return index
+}
+
+// splitWords returns the byte offsets of the words of text.
+func splitWords(text string) [][2]int {
+	words := [][2]int{}
+	start := -1
+	for i, r := range text {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWordRune && start < 0 {
+			start = i
+		}
+		if !isWordRune && start >= 0 {
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(text)})
+	}
+	return words
+}
+
+// analyze turns text into search terms : lower case words without accents, stop words nor French inflections.
+func analyze(text string) []string {
+	text = normalizeText(text)
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if searchStopWords[word] {
+			continue
+		}
+		terms = append(terms, stem(word))
+	}
+	return terms
+}
+
+// stem is a light French stemmer removing plurals and feminine endings, so that "terrains couverts" matches "terrain couvert".
+func stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "aux"):
+		word = strings.TrimSuffix(word, "aux") + "al"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"):
+		word = word[:len(word)-1]
+	}
+	if len(word) > 4 && strings.HasSuffix(word, "e") {
+		word = word[:len(word)-1]
+		if strings.HasSuffix(word, "e") {
+			word = word[:len(word)-1]
+		}
+	}
+	return word
+}
@@ -0,0 +1,121 @@
+package store_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestSearch(t *testing.T) {
+	file, removeFile := createTempFile(t, `[
+		{"Name": "Square des Épinettes", "Address": "Rue Maria Deraismes", "PostalCode": "75017", "City": "Paris", "Type": "Terrain de basket",
+			"Comments": [{"ID": 1, "Content": "Les paniers sont neufs et le terrain est couvert"}]},
+		{"Name": "Jardin d'Éole", "Address": "Rue d'Aubervilliers", "PostalCode": "75018", "City": "Paris", "Type": "Terrain de football"},
+		{"Name": "Stade Lenglen", "Address": "Rue Louis Armand", "PostalCode": "75015", "City": "Paris", "Type": "Terrains couverts"}]`)
+	defer removeFile()
+	str, _ := store.New(file)
+
+	assertResults := func(t *testing.T, got store.SearchResults, want [][2]int) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		for i, result := range got {
+			if result.PlaygroundID != want[i][0] || result.CommentID != want[i][1] {
+				t.Errorf("result %d : got playground %d comment %d, want %v", i, result.PlaygroundID, result.CommentID, want[i])
+			}
+		}
+	}
+
+	t.Run("Ignores case and accents", func(t *testing.T) {
+		got := str.Search("EPINETTES ", 0)
+
+		assertResults(t, got, [][2]int{{2, 0}})
+		if got[0].Name != "Square des Épinettes" || got[0].Snippet != "Square des <mark>Épinettes</mark>" {
+			t.Errorf("got %v", got[0])
+		}
+	})
+	t.Run("Matches plural and feminine forms", func(t *testing.T) {
+		got := str.Search("terrain couvert ", 0)
+
+		assertResults(t, got, [][2]int{{3, 0}, {2, 1}})
+		if got[1].Snippet != "Les paniers sont neufs et le <mark>terrain</mark> est <mark>couvert</mark>" {
+			t.Errorf("got snippet %q", got[1].Snippet)
+		}
+	})
+	t.Run("Completes the last word", func(t *testing.T) {
+		got := str.Search("jardin eo", 0)
+
+		assertResults(t, got, [][2]int{{1, 0}})
+	})
+	t.Run("Doesn't complete a word followed by a space", func(t *testing.T) {
+		got := str.Search("foot ", 0)
+
+		assertResults(t, got, [][2]int{})
+	})
+	t.Run("Ranks name matches first and applies the limit", func(t *testing.T) {
+		got := str.Search("stade", 0)
+		assertResults(t, got, [][2]int{{3, 0}})
+
+		got = str.Search("paris", 2)
+		if len(got) != 2 {
+			t.Errorf("got %d results, want 2", len(got))
+		}
+	})
+	t.Run("Escapes HTML in snippets", func(t *testing.T) {
+		str.AddComment(1, store.Comment{Content: "<b>Super</b> toboggan", Author: "Youssef"})
+
+		got := str.Search("toboggan", 0)
+
+		assertResults(t, got, [][2]int{{1, 1}})
+		if got[0].Snippet != "&lt;b&gt;Super&lt;/b&gt; <mark>toboggan</mark>" {
+			t.Errorf("got snippet %q", got[0].Snippet)
+		}
+	})
+	t.Run("Follows comment updates and deletions", func(t *testing.T) {
+		str.UpdateComment(1, store.Comment{ID: 1, Content: "Balançoires cassées", Author: "Youssef"})
+		assertResults(t, str.Search("toboggan", 0), [][2]int{})
+		assertResults(t, str.Search("balancoire", 0), [][2]int{{1, 1}})
+
+		str.DeleteComment(1, 1, "Youssef")
+		assertResults(t, str.Search("balancoire", 0), [][2]int{})
+	})
+	t.Run("Follows playground additions, updates and deletions", func(t *testing.T) {
+		str.NewPlayground(store.Playground{Name: "Parc Monceau", City: "Paris"})
+		assertResults(t, str.Search("monceau", 0), [][2]int{{4, 0}})
+
+		playground, _ := str.Playground(4)
+		playground.Name = "Parc des Buttes-Chaumont"
+		str.UpdatePlayground(playground)
+		assertResults(t, str.Search("monceau", 0), [][2]int{})
+		assertResults(t, str.Search("chaumont", 0), [][2]int{{4, 0}})
+
+		str.DeletePlayground(4)
+		assertResults(t, str.Search("chaumont", 0), [][2]int{})
+	})
+	t.Run("Can be searched concurrently", func(t *testing.T) {
+		str.NewPlayground(store.Playground{Name: "Parc Monceau", City: "Paris"})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if got := str.Search("monc", 0); len(got) != 1 {
+					t.Errorf("got %v", got)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+	t.Run("Crops long texts around the first match", func(t *testing.T) {
+		playgrounds := store.Playgrounds{{ID: 1, Comments: store.Comments{{ID: 1, Content: "Un très long commentaire qui parle de beaucoup de choses avant d'arriver enfin au sujet : le toboggan est très glissant et les enfants adorent y passer des heures entières, même quand il pleut à verse sur tout le quartier, ce qui arrive souvent en automne à Paris."}}}}
+
+		got := playgrounds.Search("toboggan", 0)
+
+		want := "… de beaucoup de choses avant d&#39;arriver enfin au sujet : le <mark>toboggan</mark> est très glissant et les enfants adorent y passer des heures entières, même quand il pleut à verse sur tout le quartier,…"
+		if len(got) != 1 || got[0].Snippet != want {
+			t.Errorf("got %v, want snippet %q", got, want)
+		}
+	})
+}
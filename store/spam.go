@@ -0,0 +1,129 @@
+package store
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrorTooManyLinks     = errors.New("Content has too many links")
+	ErrorDuplicateContent = errors.New("Content was already posted")
+	ErrorBannedWord       = errors.New("Content contains a banned word")
+)
+
+const (
+	MaxLinks = 2
+	// A user can't post the same content twice within DuplicateContentWindow.
+	DuplicateContentWindow = 24 * time.Hour
+	maxRecentContents      = 20
+)
+
+var linkRegexp = regexp.MustCompile(`(?i)(https?://|www\.)`)
+
+type postedContent struct {
+	content string
+	time    time.Time
+}
+
+// SpamFilter checks the content posted by users against a few heuristics.
+type SpamFilter struct {
+	mutex       sync.Mutex
+	bannedWords []string
+	recent      map[string][]postedContent
+}
+
+// NewSpamFilter matches banned words regardless of case and accents, a banned word can be several words long.
+func NewSpamFilter(bannedWords []string) *SpamFilter {
+	filter := &SpamFilter{recent: make(map[string][]postedContent)}
+	for _, word := range bannedWords {
+		if word = words(word); word != "" {
+			filter.bannedWords = append(filter.bannedWords, word)
+		}
+	}
+	return filter
+}
+
+// LoadBannedWords reads one banned word or expression per line, empty lines and lines starting with # are ignored.
+func LoadBannedWords(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't open banned words file, %s", err)
+	}
+	defer file.Close()
+
+	bannedWords := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		bannedWords = append(bannedWords, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Couldn't read banned words file, %s", err)
+	}
+	return bannedWords, nil
+}
+
+// words keeps the letters and digits of text, lowercased and without accents, separated by single spaces.
+func words(text string) string {
+	return strings.Join(strings.FieldsFunc(normalizeText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// CheckContent looks for links and banned words.
+func (s *SpamFilter) CheckContent(content string) error {
+	if len(linkRegexp.FindAllStringIndex(content, -1)) > MaxLinks {
+		return ErrorTooManyLinks
+	}
+	padded := " " + words(content) + " "
+	for _, word := range s.bannedWords {
+		if strings.Contains(padded, " "+word+" ") {
+			return ErrorBannedWord
+		}
+	}
+	return nil
+}
+
+// Check also rejects the content author already posted recently, see Record.
+func (s *SpamFilter) Check(author, content string, now time.Time) error {
+	err := s.CheckContent(content)
+	if err != nil {
+		return err
+	}
+	normalized := normalizeText(content)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, posted := range s.recent[author] {
+		if posted.content == normalized && now.Sub(posted.time) < DuplicateContentWindow {
+			return ErrorDuplicateContent
+		}
+	}
+	return nil
+}
+
+// Record remembers the content posted by author, only the last ones are kept.
+func (s *SpamFilter) Record(author, content string, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	recent := []postedContent{}
+	for _, posted := range s.recent[author] {
+		if now.Sub(posted.time) < DuplicateContentWindow {
+			recent = append(recent, posted)
+		}
+	}
+	recent = append(recent, postedContent{content: normalizeText(content), time: now})
+	if len(recent) > maxRecentContents {
+		recent = recent[len(recent)-maxRecentContents:]
+	}
+	s.recent[author] = recent
+}
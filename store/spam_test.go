@@ -0,0 +1,73 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestSpamFilter(t *testing.T) {
+	now := time.Now()
+	filter := store.NewSpamFilter([]string{"viagra", "Casino en ligne"})
+
+	t.Run("Accepts normal content", func(t *testing.T) {
+		cases := []string{
+			"Super terrain, venez jouer le dimanche !",
+			"Le casino d'en face est fermé",
+			"Plus d'infos sur https://paris.fr et www.paris.fr",
+		}
+		for _, content := range cases {
+			assertError(t, filter.Check("Youssef", content, now), nil)
+		}
+	})
+	t.Run("Rejects too many links", func(t *testing.T) {
+		err := filter.Check("Youssef", "http://a.fr https://b.fr WWW.c.fr", now)
+		assertError(t, err, store.ErrorTooManyLinks)
+	})
+	t.Run("Rejects banned words regardless of case and accents", func(t *testing.T) {
+		cases := []string{
+			"VIAGRA pas cher",
+			"Le meilleur CASINO-en-ligne !",
+			"casino  en  lígne",
+		}
+		for _, content := range cases {
+			assertError(t, filter.CheckContent(content), store.ErrorBannedWord)
+		}
+		assertError(t, filter.CheckContent("viagrafree"), nil)
+	})
+	t.Run("Rejects the same content posted again by the same user", func(t *testing.T) {
+		filter.Record("Youssef", "Qui vient jouer ce soir ?", now)
+
+		assertError(t, filter.Check("Youssef", "qui vient  jouer ce soir ?", now.Add(time.Hour)), store.ErrorDuplicateContent)
+		assertError(t, filter.Check("Clélia", "Qui vient jouer ce soir ?", now.Add(time.Hour)), nil)
+		assertError(t, filter.Check("Youssef", "Qui vient jouer ce soir ?", now.Add(store.DuplicateContentWindow)), nil)
+	})
+}
+
+func TestLoadBannedWords(t *testing.T) {
+	file, err := ioutil.TempFile("", "bannedWords")
+	if err != nil {
+		t.Fatalf("Couldn't create temp file, %s", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("# Publicités\nviagra\n\n  casino en ligne  \n")
+	file.Close()
+
+	got, err := store.LoadBannedWords(file.Name())
+	if err != nil {
+		t.Fatalf("Couldn't load banned words, %s", err)
+	}
+	want := []string{"viagra", "casino en ligne"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	_, err = store.LoadBannedWords(file.Name() + "missing")
+	if err == nil {
+		t.Error("Should return an error for a missing file")
+	}
+}
@@ -0,0 +1,250 @@
+package store
+
+import (
+	"math"
+	"sort"
+)
+
+// Size of a grid cell in degrees, roughly 1.1km x 0.7km around Paris.
+const gridCellSize = 0.01
+
+type BoundingBox struct {
+	MinLong float64 `json:"min_long"`
+	MinLat  float64 `json:"min_lat"`
+	MaxLong float64 `json:"max_long"`
+	MaxLat  float64 `json:"max_lat"`
+}
+
+func (b BoundingBox) Contains(long, lat float64) bool {
+	return long >= b.MinLong && long <= b.MaxLong && lat >= b.MinLat && lat <= b.MaxLat
+}
+
+type gridCell struct {
+	x, y int
+}
+
+type indexedPoint struct {
+	ID   int
+	Long float64
+	Lat  float64
+}
+
+type pointDistance struct {
+	ID       int
+	Distance float64
+}
+
+// spatialIndex is a uniform grid over longitude and latitude.
+// Nearest neighbours are found by visiting rings of cells around the searched point until no unvisited cell can hold a closer point.
+// Longitudes are not wrapped around the antimeridian.
+type spatialIndex struct {
+	cells  map[gridCell][]indexedPoint
+	size   int
+	bounds struct {
+		minX, minY, maxX, maxY int
+	}
+}
+
+func newSpatialIndex() *spatialIndex {
+	return &spatialIndex{cells: make(map[gridCell][]indexedPoint)}
+}
+
+func cellOf(long, lat float64) gridCell {
+	return gridCell{
+		x: int(math.Floor(long / gridCellSize)),
+		y: int(math.Floor(lat / gridCellSize)),
+	}
+}
+
+func (s *spatialIndex) Insert(ID int, long, lat float64) {
+	cell := cellOf(long, lat)
+	s.cells[cell] = append(s.cells[cell], indexedPoint{ID: ID, Long: long, Lat: lat})
+	if s.size == 0 {
+		s.bounds.minX, s.bounds.maxX, s.bounds.minY, s.bounds.maxY = cell.x, cell.x, cell.y, cell.y
+	} else {
+		s.bounds.minX = minInt(s.bounds.minX, cell.x)
+		s.bounds.maxX = maxInt(s.bounds.maxX, cell.x)
+		s.bounds.minY = minInt(s.bounds.minY, cell.y)
+		s.bounds.maxY = maxInt(s.bounds.maxY, cell.y)
+	}
+	s.size++
+}
+
+func (s *spatialIndex) Delete(ID int, long, lat float64) {
+	cell := cellOf(long, lat)
+	points := s.cells[cell]
+	for i, point := range points {
+		if point.ID == ID {
+			points = append(points[:i], points[i+1:]...)
+			s.size--
+			break
+		}
+	}
+	if len(points) == 0 {
+		delete(s.cells, cell)
+		return
+	}
+	s.cells[cell] = points
+}
+
+// Nearest returns the IDs and distances (in metres) of the limit nearest points within radius, nearest first.
+// A zero radius or limit disables the corresponding bound, a nil keep keeps every point.
+func (s *spatialIndex) Nearest(long, lat, radius float64, limit int, keep func(ID int) bool) []pointDistance {
+	if s.size == 0 {
+		return []pointDistance{}
+	}
+	if limit <= 0 && radius <= 0 {
+		return s.scan(long, lat, radius, limit, keep)
+	}
+
+	candidates := []pointDistance{}
+	center := cellOf(long, lat)
+	visitedCells := 0
+	for ring := 0; ; ring++ {
+		// The bounds never shrink, so on a sparse and wide index the rings can hold far more cells than there are points.
+		visitedCells += maxInt(8*ring, 1)
+		if visitedCells > s.size {
+			return s.scan(long, lat, radius, limit, keep)
+		}
+		s.visitRing(center, ring, func(point indexedPoint) {
+			distance := haversine(long, lat, point.Long, point.Lat)
+			if radius > 0 && distance > radius || keep != nil && !keep(point.ID) {
+				return
+			}
+			candidates = append(candidates, pointDistance{ID: point.ID, Distance: distance})
+		})
+
+		if s.ringCoversBounds(center, ring) {
+			break
+		}
+		unvisitedDistance := distanceOutsideRing(long, lat, center, ring)
+		if radius > 0 && unvisitedDistance > radius {
+			break
+		}
+		if limit > 0 && len(candidates) >= limit {
+			sortByDistance(candidates)
+			if candidates[limit-1].Distance <= unvisitedDistance {
+				break
+			}
+		}
+	}
+
+	sortByDistance(candidates)
+	return truncate(candidates, limit)
+}
+
+// scan computes the distance to every point, without looking up any cell.
+func (s *spatialIndex) scan(long, lat, radius float64, limit int, keep func(ID int) bool) []pointDistance {
+	candidates := make([]pointDistance, 0, s.size)
+	for _, points := range s.cells {
+		for _, point := range points {
+			distance := haversine(long, lat, point.Long, point.Lat)
+			if radius > 0 && distance > radius || keep != nil && !keep(point.ID) {
+				continue
+			}
+			candidates = append(candidates, pointDistance{ID: point.ID, Distance: distance})
+		}
+	}
+	sortByDistance(candidates)
+	return truncate(candidates, limit)
+}
+
+// Within returns the IDs of the points inside the bounding box.
+func (s *spatialIndex) Within(box BoundingBox) []int {
+	IDs := []int{}
+	minCell := cellOf(box.MinLong, box.MinLat)
+	maxCell := cellOf(box.MaxLong, box.MaxLat)
+	collect := func(points []indexedPoint) {
+		for _, point := range points {
+			if box.Contains(point.Long, point.Lat) {
+				IDs = append(IDs, point.ID)
+			}
+		}
+	}
+
+	cellsInBox := float64(maxCell.x-minCell.x+1) * float64(maxCell.y-minCell.y+1)
+	if cellsInBox > float64(len(s.cells)) {
+		for cell, points := range s.cells {
+			if cell.x >= minCell.x && cell.x <= maxCell.x && cell.y >= minCell.y && cell.y <= maxCell.y {
+				collect(points)
+			}
+		}
+		return IDs
+	}
+	for x := minCell.x; x <= maxCell.x; x++ {
+		for y := minCell.y; y <= maxCell.y; y++ {
+			collect(s.cells[gridCell{x, y}])
+		}
+	}
+	return IDs
+}
+
+func (s *spatialIndex) visitRing(center gridCell, ring int, visit func(indexedPoint)) {
+	visitCell := func(x, y int) {
+		for _, point := range s.cells[gridCell{x, y}] {
+			visit(point)
+		}
+	}
+	if ring == 0 {
+		visitCell(center.x, center.y)
+		return
+	}
+	for x := center.x - ring; x <= center.x+ring; x++ {
+		visitCell(x, center.y-ring)
+		visitCell(x, center.y+ring)
+	}
+	for y := center.y - ring + 1; y <= center.y+ring-1; y++ {
+		visitCell(center.x-ring, y)
+		visitCell(center.x+ring, y)
+	}
+}
+
+func (s *spatialIndex) ringCoversBounds(center gridCell, ring int) bool {
+	return center.x-ring <= s.bounds.minX && center.x+ring >= s.bounds.maxX &&
+		center.y-ring <= s.bounds.minY && center.y+ring >= s.bounds.maxY
+}
+
+// distanceOutsideRing is a lower bound of the distance between the point and any point outside the cells visited so far.
+func distanceOutsideRing(long, lat float64, center gridCell, ring int) float64 {
+	west := float64(center.x-ring) * gridCellSize
+	east := float64(center.x+ring+1) * gridCellSize
+	south := float64(center.y-ring) * gridCellSize
+	north := float64(center.y+ring+1) * gridCellSize
+
+	toRadians := math.Pi / 180
+	latitudeDistance := math.Min(lat-south, north-lat) * toRadians * earthRadius
+	longitudeDelta := math.Min(math.Min(long-west, east-long)*toRadians, math.Pi/2)
+	longitudeDistance := earthRadius * math.Asin(math.Cos(lat*toRadians)*math.Sin(longitudeDelta))
+	return math.Min(latitudeDistance, longitudeDistance)
+}
+
+// sortByDistance breaks ties by ID since the cells are not visited in a fixed order.
+func sortByDistance(points []pointDistance) {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Distance != points[j].Distance {
+			return points[i].Distance < points[j].Distance
+		}
+		return points[i].ID < points[j].ID
+	})
+}
+
+func truncate(points []pointDistance, limit int) []pointDistance {
+	if limit > 0 && len(points) > limit {
+		return points[:limit]
+	}
+	return points
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,226 @@
+package store_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/yousseffarkhani/playground/backend2/store"
+)
+
+func TestSpatialIndex(t *testing.T) {
+	playgrounds := randomPlaygrounds(2000)
+	str, removeFile := newStoreFromPlaygrounds(t, playgrounds)
+	defer removeFile()
+	allPlaygrounds := str.AllPlaygrounds()
+
+	t.Run("NearestPlaygrounds returns the same playgrounds as a full sort", func(t *testing.T) {
+		cases := []struct {
+			radius float64
+			limit  int
+		}{
+			{0, 1},
+			{0, 10},
+			{0, 100},
+			{500, 10},
+			{5000, 0},
+			{0, 0},
+		}
+		random := rand.New(rand.NewSource(2))
+		for _, c := range cases {
+			for i := 0; i < 20; i++ {
+				long, lat := 2.0+random.Float64()*0.8, 48.6+random.Float64()*0.5
+
+				got := str.NearestPlaygrounds(long, lat, c.radius, c.limit)
+				want := allPlaygrounds.Nearby(long, lat, c.radius, c.limit)
+
+				assertSameDistances(t, got, want)
+			}
+		}
+	})
+	t.Run("NearestPlaygrounds works far away from every playground", func(t *testing.T) {
+		got := str.NearestPlaygrounds(5.37, 43.29, 0, 3)
+		want := allPlaygrounds.Nearby(5.37, 43.29, 0, 3)
+
+		assertSameDistances(t, got, want)
+	})
+	t.Run("PlaygroundsWithin returns the playgrounds inside the bounding box", func(t *testing.T) {
+		cases := []store.BoundingBox{
+			{MinLong: 2.3, MinLat: 48.8, MaxLong: 2.4, MaxLat: 48.9},
+			{MinLong: 2.0, MinLat: 48.6, MaxLong: 2.8, MaxLat: 49.1},
+			{MinLong: -180, MinLat: -90, MaxLong: 180, MaxLat: 90},
+			{MinLong: 3.0, MinLat: 45.0, MaxLong: 3.1, MaxLat: 45.1},
+		}
+		for _, box := range cases {
+			got := str.PlaygroundsWithin(box)
+			want := allPlaygrounds.Within(box)
+
+			if len(got) != len(want) {
+				t.Fatalf("got %d playgrounds, want %d", len(got), len(want))
+			}
+			for i := range got {
+				if got[i].ID != want[i].ID {
+					t.Errorf("got : %v, want : %v", got[i], want[i])
+				}
+			}
+		}
+	})
+	t.Run("Index is updated when a playground is added or deleted", func(t *testing.T) {
+		newPlayground := store.Playground{Name: "ZZZ", Long: 10.0, Lat: 10.0}
+		str.NewPlayground(newPlayground)
+
+		got := str.NearestPlaygrounds(10.0, 10.0, 0, 1)
+		if len(got) != 1 || got[0].Name != newPlayground.Name {
+			t.Fatalf("New playground should be the nearest, got %v", got)
+		}
+
+		newID := got[0].ID
+		str.DeletePlayground(newID)
+
+		got = str.NearestPlaygrounds(10.0, 10.0, 0, 1)
+		if len(got) != 1 || got[0].Name == newPlayground.Name {
+			t.Errorf("Deleted playground shouldn't be returned, got %v", got)
+		}
+		if _, err := str.Playground(newID); err == nil {
+			t.Errorf("Deleted playground shouldn't be found")
+		}
+	})
+	t.Run("NearestPlaygrounds returns the same playgrounds as a full sort on a sparse index", func(t *testing.T) {
+		str, removeFile := newStoreFromPlaygrounds(t, sparsePlaygrounds(50))
+		defer removeFile()
+		allPlaygrounds := str.AllPlaygrounds()
+
+		for _, limit := range []int{0, 1, 10} {
+			got := str.NearestPlaygrounds(55.45, -20.88, 0, limit)
+			want := allPlaygrounds.Nearby(55.45, -20.88, 0, limit)
+
+			assertSameDistances(t, got, want)
+		}
+	})
+	t.Run("NearestOpenPlaygrounds leaves the closed playgrounds out", func(t *testing.T) {
+		playgrounds := randomPlaygrounds(500)
+		for i := range playgrounds {
+			playgrounds[i].OpeningHours = "Mo-Su 20:00-08:00"
+			if i%3 == 0 {
+				playgrounds[i].OpeningHours = "Mo-Su 08:00-20:00"
+			}
+		}
+		str, removeFile := newStoreFromPlaygrounds(t, playgrounds)
+		defer removeFile()
+		noon := parisTime(t, "2024-03-04 12:00")
+
+		for _, limit := range []int{0, 1, 10} {
+			got := str.NearestOpenPlaygrounds(2.35, 48.85, 0, limit, noon)
+			want := str.AllPlaygrounds().Nearby(2.35, 48.85, 0, 0).OpenAt(noon, limit)
+
+			assertSameDistances(t, got, want)
+			for _, playground := range got {
+				if open, _ := playground.IsOpenAt(noon); !open {
+					t.Errorf("%s should be open", playground.Name)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkNearestPlaygrounds(b *testing.B) {
+	for _, size := range []int{600, 100000} {
+		str, removeFile := newStoreFromPlaygrounds(b, randomPlaygrounds(size))
+		playgrounds := str.AllPlaygrounds()
+
+		b.Run("spatial index/"+strconv.Itoa(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				str.NearestPlaygrounds(2.372452, 48.886835, 0, 10)
+			}
+		})
+		b.Run("full sort/"+strconv.Itoa(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				playgrounds.FindNearestPlaygroundsFrom(2.372452, 48.886835)
+			}
+		})
+		b.Run("bounding box/"+strconv.Itoa(size), func(b *testing.B) {
+			box := store.BoundingBox{MinLong: 2.33, MinLat: 48.85, MaxLong: 2.37, MaxLat: 48.87}
+			for i := 0; i < b.N; i++ {
+				str.PlaygroundsWithin(box)
+			}
+		})
+		removeFile()
+	}
+}
+
+func BenchmarkNearestPlaygroundsSparse(b *testing.B) {
+	str, removeFile := newStoreFromPlaygrounds(b, sparsePlaygrounds(50))
+	defer removeFile()
+
+	for _, limit := range []int{0, 10} {
+		b.Run("limit "+strconv.Itoa(limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				str.NearestPlaygrounds(55.45, -20.88, 0, limit)
+			}
+		})
+	}
+}
+
+func assertSameDistances(t *testing.T, got, want store.NearbyPlaygrounds) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d playgrounds, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].Distance != want[i].Distance {
+			t.Errorf("got : %v at position %d, want : %v", got[i].Distance, i, want[i].Distance)
+		}
+	}
+}
+
+// randomPlaygrounds spreads playgrounds over Île-de-France.
+func randomPlaygrounds(size int) store.Playgrounds {
+	random := rand.New(rand.NewSource(1))
+	playgrounds := make(store.Playgrounds, size)
+	for i := range playgrounds {
+		playgrounds[i] = store.Playground{
+			Name: "playground " + strconv.Itoa(i),
+			Long: 1.5 + random.Float64()*1.6,
+			Lat:  48.1 + random.Float64()*1.1,
+		}
+	}
+	return playgrounds
+}
+
+// sparsePlaygrounds spreads playgrounds over metropolitan France with a single one in La Réunion, so that the index bounds span thousands of cells.
+func sparsePlaygrounds(size int) store.Playgrounds {
+	random := rand.New(rand.NewSource(1))
+	playgrounds := make(store.Playgrounds, size)
+	for i := range playgrounds {
+		playgrounds[i] = store.Playground{
+			Name: "playground " + strconv.Itoa(i),
+			Long: -4.5 + random.Float64()*12.5,
+			Lat:  42.5 + random.Float64()*8.5,
+		}
+	}
+	playgrounds[0].Long, playgrounds[0].Lat = 55.45, -20.88
+	return playgrounds
+}
+
+func newStoreFromPlaygrounds(tb testing.TB, playgrounds store.Playgrounds) (*store.MainPlaygroundStore, func()) {
+	tb.Helper()
+	file, err := ioutil.TempFile("", "testdb")
+	if err != nil {
+		tb.Fatalf("Could't create temp file, %s", err)
+	}
+	removeFile := func() {
+		os.Remove(file.Name())
+	}
+	err = json.NewEncoder(file).Encode(playgrounds)
+	if err != nil {
+		tb.Fatalf("Couldn't write playgrounds, %s", err)
+	}
+	str, err := store.New(file)
+	if err != nil {
+		tb.Fatalf("Couldn't create store, %s", err)
+	}
+	return str, removeFile
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -18,18 +20,37 @@ type PlaygroundStore interface {
 	Playground(ID int) (Playground, error)
 	NewPlayground(newPlayground Playground)
 	DeletePlayground(ID int)
+	UpdatePlayground(updatedPlayground Playground) error
+	NearestPlaygrounds(long, lat, radius float64, limit int) NearbyPlaygrounds
+	NearestOpenPlaygrounds(long, lat, radius float64, limit int, t time.Time) NearbyPlaygrounds
+	PlaygroundsWithin(box BoundingBox) Playgrounds
 	AddComment(playgroundID int, newComment Comment) error
 	DeleteComment(playgroundID, commentID int, username string) error
+	// RemoveComment and HideComment are moderation actions, they don't check the author.
+	RemoveComment(playgroundID, commentID int) error
+	HideComment(playgroundID, commentID int, hidden bool) error
 	UpdateComment(playgroundID int, newComment Comment) error
+	VoteComment(playgroundID, commentID int, username string, vote int) (Comment, error)
+	RatePlayground(playgroundID int, username string, rating int) (Playground, error)
+	AddConditionReport(playgroundID int, report ConditionReport) error
+	AddPhoto(playgroundID int, photo Photo) (Photo, error)
+	SetPhotoStatus(playgroundID, photoID int, status string) (Photo, error)
+	Search(query string, limit int) SearchResults
 }
 
 type PlaygroundDatabase struct {
 	MainPlaygroundStore      PlaygroundStore
 	SubmittedPlaygroundStore PlaygroundStore
+	Corrections              *CorrectionStore
+	Moderation               *ModerationStore
 }
 
 type MainPlaygroundStore struct {
 	playgrounds Playgrounds
+	positions   map[int]int
+	index       *spatialIndex
+	search      *searchIndex
+	lastID      int
 }
 
 type SubmittedPlaygroundStore struct {
@@ -67,7 +88,47 @@ func New(file *os.File) (*MainPlaygroundStore, error) {
 	for i, _ := range playgrounds {
 		playgrounds[i].ID = i + 1
 	}
-	return &MainPlaygroundStore{playgrounds: playgrounds}, nil
+	mainPlaygroundStore := &MainPlaygroundStore{playgrounds: playgrounds, lastID: len(playgrounds)}
+	mainPlaygroundStore.buildIndex()
+	return mainPlaygroundStore, nil
+}
+
+func (m *MainPlaygroundStore) buildIndex() {
+	m.index = newSpatialIndex()
+	for _, playground := range m.playgrounds {
+		m.index.Insert(playground.ID, playground.Long, playground.Lat)
+	}
+	m.buildSearchIndex()
+	m.updatePositions()
+}
+
+func (m *MainPlaygroundStore) buildSearchIndex() {
+	m.search = newSearchIndex()
+	for _, playground := range m.playgrounds {
+		m.search.Add(playground)
+	}
+}
+
+// reindex has to be called whenever a playground or its comments are modified.
+func (m *MainPlaygroundStore) reindex(playground Playground) {
+	if m.search == nil {
+		m.buildSearchIndex()
+		return
+	}
+	m.search.Add(playground)
+}
+
+// updatePositions has to be called whenever playgrounds are moved in the slice.
+func (m *MainPlaygroundStore) updatePositions() {
+	m.positions = make(map[int]int, len(m.playgrounds))
+	m.updatePositionsFrom(0)
+}
+
+// updatePositionsFrom is enough when only the playgrounds from start have moved.
+func (m *MainPlaygroundStore) updatePositionsFrom(start int) {
+	for position := start; position < len(m.playgrounds); position++ {
+		m.positions[m.playgrounds[position].ID] = position
+	}
 }
 
 func (m *MainPlaygroundStore) AddComment(playgroundID int, newComment Comment) error {
@@ -79,6 +140,7 @@ func (m *MainPlaygroundStore) AddComment(playgroundID int, newComment Comment) e
 	if err != nil {
 		return err
 	}
+	m.reindex(m.playgrounds[index])
 	return nil
 }
 
@@ -103,6 +165,46 @@ func (m *MainPlaygroundStore) DeleteComment(playgroundID, commentID int, usernam
 	if err != nil {
 		return err
 	}
+	m.reindex(m.playgrounds[index])
+	return nil
+}
+
+func (m *MainPlaygroundStore) RemoveComment(playgroundID, commentID int) error {
+	position, ok := m.positions[playgroundID]
+	if !ok {
+		return ErrorNotFoundPlayground
+	}
+	if _, err := m.playgrounds[position].FindComment(commentID); err != nil {
+		return err
+	}
+	err := m.playgrounds[position].DeleteComment(commentID)
+	if err != nil {
+		return err
+	}
+	m.reindex(m.playgrounds[position])
+	return nil
+}
+
+func (m *MainPlaygroundStore) HideComment(playgroundID, commentID int, hidden bool) error {
+	position, ok := m.positions[playgroundID]
+	if !ok {
+		return ErrorNotFoundPlayground
+	}
+	err := m.playgrounds[position].HideComment(commentID, hidden)
+	if err != nil {
+		return err
+	}
+	m.reindex(m.playgrounds[position])
+	return nil
+}
+
+func (s *SubmittedPlaygroundStore) RemoveComment(playgroundID, commentID int) error {
+	// TODO refaire proprement
+	return nil
+}
+
+func (s *SubmittedPlaygroundStore) HideComment(playgroundID, commentID int, hidden bool) error {
+	// TODO refaire proprement
 	return nil
 }
 
@@ -125,11 +227,100 @@ func (m *MainPlaygroundStore) UpdateComment(playgroundID int, updatedComment Com
 	if err != nil {
 		return err
 	}
+	m.reindex(m.playgrounds[index])
 	return nil
 }
 
+// RatePlayground returns the playground with its updated average.
+func (m *MainPlaygroundStore) RatePlayground(playgroundID int, username string, rating int) (Playground, error) {
+	position, ok := m.positions[playgroundID]
+	if !ok {
+		return Playground{}, ErrorNotFoundPlayground
+	}
+	err := m.playgrounds[position].Rate(username, rating)
+	if err != nil {
+		return Playground{}, err
+	}
+	return m.playgrounds[position], nil
+}
+
+func (s *SubmittedPlaygroundStore) RatePlayground(playgroundID int, username string, rating int) (Playground, error) {
+	_, index, err := s.playgrounds.Find(playgroundID)
+	if err != nil {
+		return Playground{}, err
+	}
+	err = s.playgrounds[index].Rate(username, rating)
+	if err != nil {
+		return Playground{}, err
+	}
+	return s.playgrounds[index], nil
+}
+
+func (m *MainPlaygroundStore) AddConditionReport(playgroundID int, report ConditionReport) error {
+	position, ok := m.positions[playgroundID]
+	if !ok {
+		return ErrorNotFoundPlayground
+	}
+	return m.playgrounds[position].AddConditionReport(report)
+}
+
+func (s *SubmittedPlaygroundStore) AddConditionReport(playgroundID int, report ConditionReport) error {
+	_, index, err := s.playgrounds.Find(playgroundID)
+	if err != nil {
+		return err
+	}
+	return s.playgrounds[index].AddConditionReport(report)
+}
+
+func (m *MainPlaygroundStore) VoteComment(playgroundID, commentID int, username string, vote int) (Comment, error) {
+	position, ok := m.positions[playgroundID]
+	if !ok {
+		return Comment{}, ErrorNotFoundPlayground
+	}
+	return m.playgrounds[position].Vote(commentID, username, vote)
+}
+
+func (s *SubmittedPlaygroundStore) VoteComment(playgroundID, commentID int, username string, vote int) (Comment, error) {
+	_, index, err := s.playgrounds.Find(playgroundID)
+	if err != nil {
+		return Comment{}, err
+	}
+	return s.playgrounds[index].Vote(commentID, username, vote)
+}
+
+func (m *MainPlaygroundStore) AddPhoto(playgroundID int, photo Photo) (Photo, error) {
+	position, ok := m.positions[playgroundID]
+	if !ok {
+		return Photo{}, ErrorNotFoundPlayground
+	}
+	return m.playgrounds[position].AddPhoto(photo)
+}
+
+func (s *SubmittedPlaygroundStore) AddPhoto(playgroundID int, photo Photo) (Photo, error) {
+	_, index, err := s.playgrounds.Find(playgroundID)
+	if err != nil {
+		return Photo{}, err
+	}
+	return s.playgrounds[index].AddPhoto(photo)
+}
+
+func (m *MainPlaygroundStore) SetPhotoStatus(playgroundID, photoID int, status string) (Photo, error) {
+	position, ok := m.positions[playgroundID]
+	if !ok {
+		return Photo{}, ErrorNotFoundPlayground
+	}
+	return m.playgrounds[position].SetPhotoStatus(photoID, status)
+}
+
+func (s *SubmittedPlaygroundStore) SetPhotoStatus(playgroundID, photoID int, status string) (Photo, error) {
+	_, index, err := s.playgrounds.Find(playgroundID)
+	if err != nil {
+		return Photo{}, err
+	}
+	return s.playgrounds[index].SetPhotoStatus(photoID, status)
+}
+
 func (m *MainPlaygroundStore) AllPlaygrounds() Playgrounds {
-	m.playgrounds.sortByName()
 	return m.playgrounds
 }
 
@@ -139,11 +330,11 @@ func (s *SubmittedPlaygroundStore) AllPlaygrounds() Playgrounds {
 }
 
 func (m *MainPlaygroundStore) Playground(ID int) (Playground, error) {
-	playground, _, err := m.playgrounds.Find(ID)
-	if err != nil {
+	position, ok := m.positions[ID]
+	if !ok {
 		return Playground{}, ErrorNotFoundPlayground
 	}
-	return playground, nil
+	return m.playgrounds[position], nil
 }
 
 func (s *SubmittedPlaygroundStore) Playground(ID int) (Playground, error) {
@@ -155,17 +346,138 @@ func (s *SubmittedPlaygroundStore) Playground(ID int) (Playground, error) {
 }
 
 func (m *MainPlaygroundStore) NewPlayground(newPlayground Playground) {
-	newPlayground.ID = len(m.playgrounds) + 1
-	m.playgrounds = append(m.playgrounds, newPlayground)
+	if m.index == nil {
+		m.buildIndex()
+	}
+	m.lastID++
+	newPlayground.ID = m.lastID
+	newPlayground.parseOpeningHours()
+	// The playgrounds are kept sorted by name, the new one is inserted after those with the same name.
+	position := sort.Search(len(m.playgrounds), func(i int) bool {
+		return strings.ToLower(m.playgrounds[i].Name) > strings.ToLower(newPlayground.Name)
+	})
+	m.playgrounds = append(m.playgrounds, Playground{})
+	copy(m.playgrounds[position+1:], m.playgrounds[position:])
+	m.playgrounds[position] = newPlayground
+	m.index.Insert(newPlayground.ID, newPlayground.Long, newPlayground.Lat)
+	m.search.Add(newPlayground)
+	m.updatePositionsFrom(position)
 }
 
 func (s *SubmittedPlaygroundStore) NewPlayground(newPlayground Playground) {
 	newPlayground.ID = len(s.playgrounds) + 1
+	newPlayground.parseOpeningHours()
 	s.playgrounds = append(s.playgrounds, newPlayground)
 }
 
 func (m *MainPlaygroundStore) DeletePlayground(ID int) {
-	// TODO
+	position, ok := m.positions[ID]
+	if !ok {
+		return
+	}
+	playground := m.playgrounds[position]
+	m.playgrounds = append(m.playgrounds[:position], m.playgrounds[position+1:]...)
+	m.index.Delete(playground.ID, playground.Long, playground.Lat)
+	m.search.Remove(playground.ID)
+	delete(m.positions, ID)
+	m.updatePositionsFrom(position)
+}
+
+func (m *MainPlaygroundStore) UpdatePlayground(updatedPlayground Playground) error {
+	position, ok := m.positions[updatedPlayground.ID]
+	if !ok {
+		return ErrorNotFoundPlayground
+	}
+	if m.index == nil {
+		m.buildIndex()
+	}
+	playground := m.playgrounds[position]
+	m.index.Delete(playground.ID, playground.Long, playground.Lat)
+	updatedPlayground.parseOpeningHours()
+	m.playgrounds[position] = updatedPlayground
+	m.playgrounds.sortByName()
+	m.index.Insert(updatedPlayground.ID, updatedPlayground.Long, updatedPlayground.Lat)
+	m.search.Add(updatedPlayground)
+	m.updatePositions()
+	return nil
+}
+
+func (s *SubmittedPlaygroundStore) UpdatePlayground(updatedPlayground Playground) error {
+	_, index, err := s.playgrounds.Find(updatedPlayground.ID)
+	if err != nil {
+		return err
+	}
+	updatedPlayground.parseOpeningHours()
+	s.playgrounds[index] = updatedPlayground
+	return nil
+}
+
+func (m *MainPlaygroundStore) NearestPlaygrounds(long, lat, radius float64, limit int) NearbyPlaygrounds {
+	if m.index == nil {
+		m.buildIndex()
+	}
+	return m.nearbyPlaygrounds(m.index.Nearest(long, lat, radius, limit, nil))
+}
+
+func (s *SubmittedPlaygroundStore) NearestPlaygrounds(long, lat, radius float64, limit int) NearbyPlaygrounds {
+	return s.playgrounds.Nearby(long, lat, radius, limit)
+}
+
+// NearestOpenPlaygrounds leaves the closed playgrounds out while searching, so that the search stops once limit open ones are found.
+func (m *MainPlaygroundStore) NearestOpenPlaygrounds(long, lat, radius float64, limit int, t time.Time) NearbyPlaygrounds {
+	if m.index == nil {
+		m.buildIndex()
+	}
+	isOpen := func(ID int) bool {
+		open, _ := m.playgrounds[m.positions[ID]].IsOpenAt(t)
+		return open
+	}
+	return m.nearbyPlaygrounds(m.index.Nearest(long, lat, radius, limit, isOpen))
+}
+
+func (s *SubmittedPlaygroundStore) NearestOpenPlaygrounds(long, lat, radius float64, limit int, t time.Time) NearbyPlaygrounds {
+	return s.playgrounds.Nearby(long, lat, radius, 0).OpenAt(t, limit)
+}
+
+func (m *MainPlaygroundStore) nearbyPlaygrounds(points []pointDistance) NearbyPlaygrounds {
+	nearbyPlaygrounds := make(NearbyPlaygrounds, len(points))
+	for i, point := range points {
+		nearbyPlaygrounds[i] = NearbyPlayground{Playground: m.playgrounds[m.positions[point.ID]], Distance: point.Distance}
+	}
+	return nearbyPlaygrounds
+}
+
+// PlaygroundsWithin returns the playgrounds inside the bounding box sorted by name.
+func (m *MainPlaygroundStore) PlaygroundsWithin(box BoundingBox) Playgrounds {
+	if m.index == nil {
+		m.buildIndex()
+	}
+	IDs := m.index.Within(box)
+	positions := make([]int, len(IDs))
+	for i, ID := range IDs {
+		positions[i] = m.positions[ID]
+	}
+	sort.Ints(positions)
+	playgrounds := make(Playgrounds, len(positions))
+	for i, position := range positions {
+		playgrounds[i] = m.playgrounds[position]
+	}
+	return playgrounds
+}
+
+func (s *SubmittedPlaygroundStore) PlaygroundsWithin(box BoundingBox) Playgrounds {
+	return s.AllPlaygrounds().Within(box)
+}
+
+func (m *MainPlaygroundStore) Search(query string, limit int) SearchResults {
+	if m.search == nil {
+		m.buildSearchIndex()
+	}
+	return m.search.Search(query, limit)
+}
+
+func (s *SubmittedPlaygroundStore) Search(query string, limit int) SearchResults {
+	return s.playgrounds.Search(query, limit)
 }
 
 func (s *SubmittedPlaygroundStore) DeletePlayground(ID int) {
@@ -243,6 +555,15 @@ func isAlreadyExisting(newPlayground Playground, playgrounds Playgrounds) bool {
 
 var ErrEmptyField = errors.New("Empty field")
 
+var optionalFields = map[string]bool{
+	"Coating":         true,
+	"Open":            true,
+	"OpeningHours":    true,
+	"Type":            true,
+	"GeocodedAddress": true,
+	"GeocodingSource": true,
+}
+
 func verifyCorrectPlaygroundInput(newPlayground Playground) map[string]error {
 	errorsMap := make(map[string]error)
 	value := reflect.ValueOf(newPlayground)
@@ -251,10 +572,16 @@ func verifyCorrectPlaygroundInput(newPlayground Playground) map[string]error {
 		for i := 0; i < value.NumField(); i++ {
 			fieldName := typeOfData.Field(i).Name
 			fieldValue := value.Field(i).String()
-			if fieldName != "Coating" && fieldName != "Open" && fieldName != "Type" && strings.TrimSpace(fieldValue) == "" {
+			if !optionalFields[fieldName] && strings.TrimSpace(fieldValue) == "" {
 				errorsMap[fieldName] = ErrEmptyField
 				continue
 			}
+			if fieldName == "OpeningHours" && strings.TrimSpace(fieldValue) != "" {
+				if _, err := ParseOpeningHours(fieldValue); err != nil {
+					errorsMap[fieldName] = err
+				}
+				continue
+			}
 			if fieldName == "PostalCode" {
 				if _, err := strconv.Atoi(fieldValue); err != nil {
 					errorsMap[fieldName] = errors.New("Postal Code should be a number")
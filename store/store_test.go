@@ -454,3 +454,26 @@ func createTempFile(t *testing.T, data string) (*os.File, func()) {
 	tempFile.Write([]byte(data))
 	return tempFile, removeTempFile
 }
+
+func TestNewPlaygroundKeepsNameOrder(t *testing.T) {
+	file, removeFile := createTempFile(t, `[{"Name": "bbbb"}, {"Name": "dddd"}]`)
+	defer removeFile()
+	str, _ := store.New(file)
+
+	for _, name := range []string{"Eeee", "aaaa", "Cccc", "bbbb"} {
+		str.NewPlayground(store.Playground{Name: name})
+	}
+
+	got := []string{}
+	for _, playground := range str.AllPlaygrounds() {
+		got = append(got, playground.Name)
+		found, err := str.Playground(playground.ID)
+		if err != nil || found.Name != playground.Name {
+			t.Errorf("Playground %d : got %v, %v", playground.ID, found, err)
+		}
+	}
+	want := []string{"aaaa", "bbbb", "bbbb", "Cccc", "dddd", "Eeee"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,26 @@
+package store
+
+import "strings"
+
+var accentReplacer = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "á", "a",
+	"ç", "c",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "í", "i",
+	"ô", "o", "ö", "o", "ó", "o",
+	"ù", "u", "û", "u", "ü", "u", "ú", "u",
+	"ÿ", "y",
+	"œ", "oe", "æ", "ae",
+	"À", "A", "Â", "A", "Ç", "C", "É", "E", "È", "E", "Ê", "E", "Î", "I", "Ô", "O", "Ù", "U", "Û", "U",
+	"Œ", "OE", "Æ", "AE",
+)
+
+// FoldAccents replaces the accented letters used in French by their unaccented form.
+func FoldAccents(text string) string {
+	return accentReplacer.Replace(text)
+}
+
+// normalizeText makes text comparable regardless of case, accents and extra spaces.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(FoldAccents(text))), " ")
+}
@@ -32,6 +32,8 @@ func Initialize() map[string]server.View {
 	views["submitPlayground"] = newView("main", templateDir+"/submitPlayground.html")
 	views["submittedPlaygrounds"] = newView("main", templateDir+"/submittedPlaygrounds.html")
 	views["submittedPlayground"] = newView("main", templateDir+"/submittedPlayground.html")
+	views["geocodingCorrections"] = newView("main", templateDir+"/geocodingCorrections.html")
+	views["moderation"] = newView("main", templateDir+"/moderation.html")
 
 	return views
 }